@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+// appAuth is the GitHub App installation set up by loadAuth, shared by every client so they all use the
+// same installation token
+var appAuth *gh.App
+
+// loadAuth sets up GitHub App authentication when --app-id is set, which is then used instead of --token.
+// Installation tokens are minted with ctx so --timeout and interrupts stop them.
+func loadAuth(ctx context.Context) error {
+	id := viper.GetInt64("app-id")
+	if id == 0 {
+		return nil
+	}
+
+	key := viper.GetString("app-private-key")
+	if key == "" {
+		return errors.New("--app-id requires --app-private-key")
+	}
+
+	// the key itself can be passed in GITHUB_APP_PRIVATE_KEY instead of a path to it
+	pemData := []byte(key)
+	if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+		var err error
+		if pemData, err = os.ReadFile(key); err != nil {
+			return fmt.Errorf("reading github app private key: %w", err)
+		}
+	}
+
+	app, err := gh.NewApp(ctx, id, viper.GetInt64("app-installation-id"), pemData)
+	if err != nil {
+		return err
+	}
+	appAuth = app
+
+	return nil
+}
+
+// authToken returns the credentials for the GitHub APIs, the app installation when configured otherwise
+// the personal access token, pooled with any extra tokens to spread the rate limit load
+func authToken(token string, extra []string) gh.Token {
+	var names []string
+	var sources []oauth2.TokenSource
+	if appAuth != nil {
+		names = append(names, fmt.Sprintf("app %d", appAuth.ID))
+		sources = append(sources, appAuth.TokenSource())
+	} else if token != "" {
+		names = append(names, "token 1")
+		sources = append(sources, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+	for _, t := range extra {
+		if t = strings.TrimSpace(t); t != "" {
+			names = append(names, fmt.Sprintf("token %d", len(names)+1))
+			sources = append(sources, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: t}))
+		}
+	}
+
+	switch len(sources) {
+	case 0:
+		return gh.Token{}
+	case 1:
+		return gh.Token{Source: sources[0]}
+	}
+
+	return gh.Token{Pool: gh.NewTokenPool(names, sources)}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/chttp"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/spf13/viper"
+)
+
+// loadCache sets up the REST response cache in --cache-dir, the user's cache dir by default, pruning it to
+// --cache-max-age and --cache-max-size
+func loadCache() error {
+	// recorded responses must be the full ones github sent, and replaying can't revalidate the cache
+	if viper.GetBool("no-cache") || viper.GetString("record") != "" || viper.GetString("replay") != "" {
+		return nil
+	}
+
+	dir := viper.GetString("cache-dir")
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			// nowhere to put it, carry on without caching
+			return nil
+		}
+		dir = filepath.Join(userDir, "ghp-sync")
+	}
+
+	// kept separate per host as the same urls on different hosts are different resources
+	maxSize := int64(viper.GetInt("cache-max-size")) << 20
+	cache, err := chttp.NewCache(filepath.Join(dir, gh.Host()), viper.GetDuration("cache-max-age"), maxSize)
+	if err != nil {
+		return err
+	}
+	gh.SetCache(cache)
+
+	return nil
+}
+
+// printCacheStats prints how many requests were answered from the response cache
+func printCacheStats() {
+	s, ok := gh.CacheStats()
+	if !ok || s.Requests == 0 {
+		return
+	}
+
+	c.Printf("Cache: <green>%d</> of <yellow>%d</> requests not modified (%.1f%%, <cyan>%s</> not downloaded), <magenta>%d</> responses cached\n",
+		s.Hits, s.Requests, float64(s.Hits)*100/float64(s.Requests), formatBytes(s.BytesSaved), s.Stored)
+	if s.Pruned > 0 {
+		c.Printf("  <gray>%d</> unused responses pruned\n", s.Pruned)
+	}
+}
+
+// formatBytes formats a byte count as B, KiB or MiB
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+
+	return fmt.Sprintf("%dB", n)
+}
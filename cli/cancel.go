@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// stopTimeout releases the --timeout timer once a command has run
+var stopTimeout context.CancelFunc = func() {}
+
+// applyTimeout cancels the command's context after --timeout, signals are handled by main
+func applyTimeout(cmd *cobra.Command) {
+	timeout := viper.GetDuration("timeout")
+	if timeout <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	stopTimeout = cancel
+	cmd.SetContext(ctx)
+}
+
+// interrupted returns an error reporting how far a run got once it has been stopped by a signal or
+// --timeout, nil while it can carry on
+func interrupted(ctx context.Context, format string, args ...any) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+
+	reason := "interrupted"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = fmt.Sprintf("timed out after %s", viper.GetDuration("timeout"))
+	}
+
+	return fmt.Errorf("%s with %s: %w", reason, fmt.Sprintf(format, args...), ctx.Err())
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	c "github.com/gookit/color"
+)
+
+// checkpointSaveEvery is how many items are marked done between writes of the checkpoint file
+const checkpointSaveEvery = 20
+
+// DefaultCheckpointFile is the checkpoint file used when --checkpoint-file isn't set, relative to the current
+// directory so a run is only resumed from the directory it was started in
+const DefaultCheckpointFile = ".ghp-sync-checkpoint.json"
+
+// checkpointJob is the progress of a single job
+type checkpointJob struct {
+	Done      map[string]bool `json:"done,omitempty"` // node ids of the processed items
+	Line      int             `json:"line,omitempty"` // csv lines up to and including this one were processed
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Checkpoint records the items processed by a job so a --resume run can skip them. Jobs are keyed by the
+// command and its project, repos and search so several can share a checkpoint file, and a job is removed
+// once it completes without any failed items. The file is only written when --checkpoint-file or --resume
+// is set or once an item fails. A nil Checkpoint (dry runs) records nothing.
+type Checkpoint struct {
+	path   string
+	job    string
+	resume bool
+	record bool // write the file as items are processed, rather than only on failure
+
+	jobs    map[string]*checkpointJob
+	unsaved int
+	failed  int
+	broken  bool // a csv line failed, so later lines no longer extend Line
+}
+
+// OpenCheckpoint loads the checkpoint file for a job, starting the job over unless --resume is set.
+func OpenCheckpoint(f FlagData, job string) (*Checkpoint, error) {
+	if f.DryRun {
+		return nil, nil
+	}
+
+	cp := &Checkpoint{
+		path:   f.CheckpointFile,
+		job:    job,
+		resume: f.Resume,
+		record: f.CheckpointFile != "" || f.Resume,
+		jobs:   map[string]*checkpointJob{},
+	}
+	if cp.path == "" {
+		cp.path = DefaultCheckpointFile
+	}
+
+	b, err := os.ReadFile(cp.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading checkpoint %s: %w", cp.path, err)
+	default:
+		if err := json.Unmarshal(b, &cp.jobs); err != nil {
+			return nil, fmt.Errorf("parsing checkpoint %s: %w", cp.path, err)
+		}
+	}
+
+	j, ok := cp.jobs[job]
+	if !ok || !f.Resume {
+		j = &checkpointJob{}
+		cp.jobs[job] = j
+	}
+	if j.Done == nil {
+		j.Done = map[string]bool{}
+	}
+
+	if f.Resume {
+		if ok {
+			c.Printf("Resuming from <cyan>%s</>: <yellow>%d</> items", cp.path, len(j.Done))
+			if j.Line > 0 {
+				c.Printf(", csv line <yellow>%d</>", j.Line)
+			}
+			c.Printf(" already processed\n")
+		} else {
+			c.Printf("<yellow>Nothing to resume in %s, starting from the beginning</>\n", cp.path)
+		}
+	}
+
+	return cp, nil
+}
+
+// Done returns true if an item was processed by the run being resumed
+func (cp *Checkpoint) Done(id string) bool {
+	if cp == nil || !cp.resume {
+		return false
+	}
+
+	return cp.jobs[cp.job].Done[id]
+}
+
+// MarkDone records an item as processed
+func (cp *Checkpoint) MarkDone(id string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.jobs[cp.job].Done[id] = true
+	return cp.changed()
+}
+
+// MarkFailed records that an item failed, so the job is kept for --resume when the run completes. The
+// checkpoint is written straight away as this may be the first time it is.
+func (cp *Checkpoint) MarkFailed() error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.failed++
+	return cp.Save()
+}
+
+// LineDone returns true if a csv line was processed by the run being resumed
+func (cp *Checkpoint) LineDone(line int) bool {
+	if cp == nil || !cp.resume {
+		return false
+	}
+
+	return line <= cp.jobs[cp.job].Line
+}
+
+// MarkLine records the result of a csv line, Line only advances while every line has succeeded so a
+// resumed run starts at the first failed line
+func (cp *Checkpoint) MarkLine(line int, ok bool) error {
+	if cp == nil {
+		return nil
+	}
+
+	if !ok {
+		cp.broken = true
+		return cp.MarkFailed()
+	}
+	if cp.broken || line <= cp.jobs[cp.job].Line {
+		return nil
+	}
+
+	cp.jobs[cp.job].Line = line
+	return cp.changed()
+}
+
+func (cp *Checkpoint) changed() error {
+	cp.unsaved++
+	if (!cp.record && cp.failed == 0) || cp.unsaved < checkpointSaveEvery {
+		return nil
+	}
+
+	return cp.Save()
+}
+
+// Save writes the checkpoint file
+func (cp *Checkpoint) Save() error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.jobs[cp.job].UpdatedAt = time.Now()
+	cp.unsaved = 0
+
+	return cp.write()
+}
+
+func (cp *Checkpoint) write() error {
+	b, err := json.MarshalIndent(cp.jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	if err := os.WriteFile(cp.path, b, 0o600); err != nil {
+		return fmt.Errorf("writing checkpoint %s: %w", cp.path, err)
+	}
+
+	return nil
+}
+
+// Complete removes the finished job from the checkpoint file, deleting it when no jobs are left
+func (cp *Checkpoint) Complete() error {
+	if cp == nil {
+		return nil
+	}
+
+	delete(cp.jobs, cp.job)
+	if len(cp.jobs) > 0 {
+		return cp.write()
+	}
+
+	if err := os.Remove(cp.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing checkpoint %s: %w", cp.path, err)
+	}
+
+	return nil
+}
+
+// finish completes the job if the run succeeded without any failed items, otherwise saves the progress for
+// --resume
+func (cp *Checkpoint) finish(err error) error {
+	if cp == nil {
+		return err
+	}
+
+	if err == nil && cp.failed > 0 {
+		if err := cp.Save(); err != nil {
+			return err
+		}
+		c.Printf("<yellow>%d items failed, progress saved to %s, rerun with --resume to retry them</>\n", cp.failed, cp.path)
+		return nil
+	}
+
+	if err != nil {
+		if saveErr := cp.Save(); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		c.Printf("<yellow>Progress saved to %s, rerun with --resume to continue</>\n", cp.path)
+		return err
+	}
+
+	return cp.Complete()
+}
+
+// checkpointJobKey identifies a sync job by its command, project and source
+func checkpointJobKey(cmd string, f FlagData) string {
+	return fmt.Sprintf("%s %s/%d repos=%v search=%q", cmd, f.ProjectOwner, f.ProjectNumber, f.Repos, f.Search)
+}
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const (
+	testJob  = "prs owner/1 repos=[owner/a] search=\"\""
+	otherJob = "prs owner/1 repos=[owner/b] search=\"\""
+)
+
+func openTestCheckpoint(t *testing.T, path, job string, resume bool) *Checkpoint {
+	t.Helper()
+
+	cp, err := OpenCheckpoint(FlagData{CheckpointFile: path, Resume: resume}, job)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+
+	return cp
+}
+
+// readCheckpoint returns the jobs in a checkpoint file, nil when there isn't one
+func readCheckpoint(t *testing.T, path string) map[string]*checkpointJob {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("reading checkpoint: %v", err)
+	}
+
+	var jobs map[string]*checkpointJob
+	if err := json.Unmarshal(b, &jobs); err != nil {
+		t.Fatalf("parsing checkpoint: %v", err)
+	}
+
+	return jobs
+}
+
+func TestCheckpointMarkLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		lines   []bool // the result of each line from 1
+		want    int    // Line once marked
+		failed  bool
+		resumed []int // lines skipped by a resume
+	}{
+		{
+			name:    "all succeed",
+			lines:   []bool{true, true, true},
+			want:    3,
+			resumed: []int{1, 2, 3},
+		},
+		{
+			name:    "stops at the first failed line",
+			lines:   []bool{true, true, false, true, true},
+			want:    2,
+			failed:  true,
+			resumed: []int{1, 2},
+		},
+		{
+			name:   "first line failed",
+			lines:  []bool{false, true},
+			failed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+			cp := openTestCheckpoint(t, path, testJob, false)
+			for i, ok := range tt.lines {
+				if err := cp.MarkLine(i+1, ok); err != nil {
+					t.Fatalf("MarkLine(%d) error = %v", i+1, err)
+				}
+			}
+			if got := cp.jobs[testJob].Line; got != tt.want {
+				t.Errorf("Line = %d, want %d", got, tt.want)
+			}
+
+			if err := cp.finish(nil); err != nil {
+				t.Fatalf("finish() error = %v", err)
+			}
+
+			jobs := readCheckpoint(t, path)
+			if !tt.failed {
+				if jobs != nil {
+					t.Errorf("checkpoint kept after every line succeeded: %v", jobs)
+				}
+				return
+			}
+			if jobs[testJob] == nil || jobs[testJob].Line != tt.want {
+				t.Fatalf("checkpoint after a failed line = %v, want Line %d", jobs, tt.want)
+			}
+
+			resumed := openTestCheckpoint(t, path, testJob, true)
+			for line := 1; line <= len(tt.lines); line++ {
+				want := line <= len(tt.resumed)
+				if got := resumed.LineDone(line); got != want {
+					t.Errorf("LineDone(%d) on resume = %t, want %t", line, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointFinish(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		other    bool // another job is in the file
+		failItem bool
+		runErr   error
+		wantJob  bool // the job is in the file afterwards
+		wantFile bool
+	}{
+		{
+			name: "success removes the file",
+		},
+		{
+			name:     "success keeps other jobs",
+			other:    true,
+			wantFile: true,
+		},
+		{
+			name:     "failed item keeps the job",
+			failItem: true,
+			wantJob:  true,
+			wantFile: true,
+		},
+		{
+			name:     "interrupted keeps the job",
+			runErr:   errors.New("interrupted with 2 of 3 prs synced"),
+			wantJob:  true,
+			wantFile: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+			if tt.other {
+				other := openTestCheckpoint(t, path, otherJob, false)
+				if err := other.MarkFailed(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cp := openTestCheckpoint(t, path, testJob, false)
+			if err := cp.MarkDone("PR_1"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.failItem {
+				if err := cp.MarkFailed(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := cp.MarkDone("PR_3"); err != nil {
+				t.Fatal(err)
+			}
+			if err := cp.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cp.finish(tt.runErr); !errors.Is(err, tt.runErr) {
+				t.Fatalf("finish() error = %v, want %v", err, tt.runErr)
+			}
+
+			jobs := readCheckpoint(t, path)
+			if (jobs != nil) != tt.wantFile {
+				t.Fatalf("checkpoint file kept = %t, want %t", jobs != nil, tt.wantFile)
+			}
+			if (jobs[testJob] != nil) != tt.wantJob {
+				t.Errorf("job kept = %t, want %t", jobs[testJob] != nil, tt.wantJob)
+			}
+			if tt.other && jobs[otherJob] == nil {
+				t.Errorf("other job removed from the checkpoint")
+			}
+			if tt.wantJob && (!jobs[testJob].Done["PR_1"] || !jobs[testJob].Done["PR_3"]) {
+				t.Errorf("done items = %v, want PR_1 and PR_3", jobs[testJob].Done)
+			}
+		})
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cp := openTestCheckpoint(t, path, testJob, false)
+	for _, id := range []string{"PR_1", "PR_2"} {
+		if err := cp.MarkDone(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cp.MarkFailed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.finish(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// only --resume skips what was done, and only for the same job
+	if cp := openTestCheckpoint(t, path, testJob, false); cp.Done("PR_1") {
+		t.Errorf("Done() = true without --resume")
+	}
+	resumed := openTestCheckpoint(t, path, testJob, true)
+	if !resumed.Done("PR_1") || !resumed.Done("PR_2") || resumed.Done("PR_3") {
+		t.Errorf("Done() on resume = %v, want PR_1 and PR_2", resumed.jobs[testJob].Done)
+	}
+	other := openTestCheckpoint(t, path, otherJob, true)
+	if other.Done("PR_1") {
+		t.Errorf("Done() = true resuming another job")
+	}
+
+	// completing the other job leaves this one to resume
+	if err := other.finish(nil); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := readCheckpoint(t, path); jobs[testJob] == nil || jobs[otherJob] != nil {
+		t.Errorf("checkpoint after completing another job = %v, want only %s", jobs, testJob)
+	}
+}
+
+func TestCheckpointOnlyWrittenOnFailure(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cp, err := OpenCheckpoint(FlagData{CheckpointFile: path}, testJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.record = false // as when --checkpoint-file isn't set
+
+	for i := range checkpointSaveEvery * 2 {
+		if err := cp.MarkDone("PR_" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if jobs := readCheckpoint(t, path); jobs != nil {
+		t.Fatalf("checkpoint written before any item failed")
+	}
+
+	if err := cp.MarkFailed(); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := readCheckpoint(t, path); jobs[testJob] == nil || len(jobs[testJob].Done) != checkpointSaveEvery*2 {
+		t.Errorf("checkpoint after a failure = %v, want the %d done items", jobs, checkpointSaveEvery*2)
+	}
+}
+
+func TestCheckpointDryRun(t *testing.T) {
+	t.Parallel()
+
+	cp, err := OpenCheckpoint(FlagData{DryRun: true}, testJob)
+	if err != nil || cp != nil {
+		t.Fatalf("OpenCheckpoint() for a dry run = %v, %v, want nil", cp, err)
+	}
+
+	// a nil checkpoint records nothing
+	if err := cp.MarkDone("PR_1"); err != nil {
+		t.Errorf("MarkDone() error = %v", err)
+	}
+	if err := cp.MarkLine(1, false); err != nil {
+		t.Errorf("MarkLine() error = %v", err)
+	}
+	if cp.Done("PR_1") || cp.LineDone(1) {
+		t.Errorf("nil checkpoint reports items done")
+	}
+}
+
+func TestCheckpointJobKey(t *testing.T) {
+	t.Parallel()
+
+	base := FlagData{ProjectOwner: "owner", ProjectNumber: 1, Repos: []string{"owner/a"}}
+	key := checkpointJobKey("prs", base)
+
+	for name, f := range map[string]FlagData{
+		"project": {ProjectOwner: "owner", ProjectNumber: 2, Repos: []string{"owner/a"}},
+		"repos":   {ProjectOwner: "owner", ProjectNumber: 1, Repos: []string{"owner/a", "owner/b"}},
+		"search":  {ProjectOwner: "owner", ProjectNumber: 1, Repos: []string{"owner/a"}, Search: "is:open"},
+	} {
+		if checkpointJobKey("prs", f) == key {
+			t.Errorf("job key doesn't change with the %s", name)
+		}
+	}
+	if checkpointJobKey("issues", base) == key {
+		t.Errorf("job key doesn't change with the command")
+	}
+	if checkpointJobKey("prs", base) != key {
+		t.Errorf("job key isn't stable")
+	}
+}
@@ -4,17 +4,28 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/katbyte/ghp-sync/lib/gh"
 	"github.com/katbyte/ghp-sync/lib/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// ValidateParams returns an error for any empty param, `a|b` requires at least one of a or b.
 func ValidateParams(params []string) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		for _, p := range params {
-			if viper.GetString(p) == "" {
-				return errors.New(p + " parameter can't be empty")
+			set := false
+			for _, name := range strings.Split(p, "|") {
+				if isParamSet(name) {
+					set = true
+					break
+				}
+			}
+
+			if !set {
+				return errors.New(strings.ReplaceAll(p, "|", " or ") + " parameter can't be empty")
 			}
 		}
 
@@ -22,13 +33,56 @@ func ValidateParams(params []string) func(cmd *cobra.Command, args []string) err
 	}
 }
 
+// isParamSet handles string slice flags, which viper.GetString returns as empty
+func isParamSet(name string) bool {
+	if viper.GetString(name) != "" {
+		return true
+	}
+
+	for _, s := range GetStringSliceFixed(name) {
+		if s != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func Make(cmdName string) (*cobra.Command, error) {
 	root := &cobra.Command{
 		Use:           cmdName + " [command]",
 		Short:         cmdName + " is a small utility to sync GitHub issues and PRs to a project",
 		Long:          `Sync GitHub issues and PRs to a GitHub Project`,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "repos", "project-owner", "project-number"}),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(); err != nil {
+				return err
+			}
+			if err := loadLogging(cmd); err != nil {
+				return err
+			}
+			applyTimeout(cmd)
+			if err := gh.SetHost(viper.GetString("github-host")); err != nil {
+				return err
+			}
+			if err := loadFixtures(); err != nil {
+				return err
+			}
+			if err := loadCache(); err != nil {
+				return err
+			}
+
+			policy := gh.DefaultRetryPolicy
+			policy.MaxTotalWait = viper.GetDuration("max-retry-wait")
+			gh.SetRetryPolicy(policy)
+
+			return loadAuth(cmd.Context())
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			stopTimeout()
+			printCacheStats()
+		},
+		PreRunE: ValidateParams([]string{"token|tokens|app-id", "repos", "project-owner", "project-number"}),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Println("USAGE: ghp-syc [issues|prs] katbyte/ghp-sync project")
 
@@ -51,7 +105,7 @@ func Make(cmdName string) (*cobra.Command, error) {
 		Short:         "Sync issues from a repo to a project",
 		Args:          cobra.NoArgs,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "repos", "project-owner", "project-number"}),
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "repos|search", "project-owner", "project-number"}),
 		RunE:          CmdIssues,
 	})
 
@@ -60,7 +114,7 @@ func Make(cmdName string) (*cobra.Command, error) {
 		Short:         "Sync PRs from a repo to a project",
 		Args:          cobra.NoArgs,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "repos", "project-owner", "project-number"}),
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "repos|search", "project-owner", "project-number"}),
 		RunE:          CmdPRs,
 	})
 
@@ -77,7 +131,7 @@ mapped by option name).
   rjg list | ghp-sync add -o katbyte -p 42 --set "Status=Backlog [PRs]"
   rjg list | ghp-sync add "JIRA" "JIRA URL" -o katbyte -p 42   # headerless input`,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "project-owner", "project-number"}),
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "project-owner", "project-number"}),
 		RunE:          CmdAdd,
 	}
 	addCmd.Flags().StringSlice("set", []string{}, "set a fixed field value on every added item, e.g. 'Status=Backlog [PRs]' (repeatable)")
@@ -88,17 +142,46 @@ mapped by option name).
 		Short:         "Sync issues and PRs between two projects",
 		Args:          cobra.ExactArgs(2),
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "project-owner", "project-number"}),
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "project-owner", "project-number"}),
 		RunE:          CmdSync,
 	})
 
+	root.AddCommand(&cobra.Command{
+		Use:   "push",
+		Short: "Push project field values back to the labels, milestone and assignees of issues and PRs",
+		Long: `Push project field values back to the labels, milestone and assignees of the issues and PRs
+in a project using --push-mappings. The values at the last push are recorded in --push-state, items
+where both the project field and github have changed since then are reported as conflicts and skipped.
+
+  ghp-sync push -o katbyte -p 42 --push-mappings 'Priority=label:priority/' --push-mappings 'Status:Blocked=milestone:Blocked'`,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "project-owner", "project-number", "push-mappings"}),
+		RunE:          CmdPush,
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "apply",
+		Short: "Apply a plan written by a prs or issues dry run with --plan-file",
+		Long: `Apply a plan written by a prs or issues dry run with --plan-file, adding the planned items to the
+project and setting exactly the planned field values. Items whose fields changed since the plan was made
+are skipped.
+
+  ghp-sync prs -o katbyte -p 42 -r katbyte/ghp-sync --plan-file plan.json
+  ghp-sync apply --plan-file plan.json`,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id", "plan-file"}),
+		RunE:          CmdApply,
+	})
+
 	// command to get and print gh rate limits
 	root.AddCommand(&cobra.Command{
 		Use:           "rate-limits",
 		Short:         "get and print github api rate limits",
 		Args:          cobra.NoArgs,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token"}),
+		PreRunE:       ValidateParams([]string{"token|tokens|app-id"}),
 		RunE:          CmdRateLimit,
 	})
 
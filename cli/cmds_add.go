@@ -94,7 +94,8 @@ func resolveField(p gh.Project, alias, fieldName, value string, typeOverride *gh
 	return gh.ProjectItemField{Name: alias, FieldID: fieldID, Type: t, Value: v}, nil
 }
 
-func CmdAdd(cmd *cobra.Command, args []string) error {
+func CmdAdd(cmd *cobra.Command, args []string) (err error) {
+	ctx := cmd.Context()
 	f := GetFlags()
 
 	r := csv.NewReader(os.Stdin)
@@ -117,9 +118,9 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 		line++
 	}
 
-	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Token)
+	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Auth)
 	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", f.ProjectOwner, f.ProjectNumber)
-	if err := p.LoadDetails(); err != nil {
+	if err := p.LoadDetails(ctx); err != nil {
 		return fmt.Errorf("loading project details: %w", err)
 	}
 	c.Printf("  ID: <magenta>%s</>\n", p.ID)
@@ -188,10 +189,52 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 		explicit[name] = true
 	}
 
+	// csv input can't be identified, so resuming assumes the same input as the failed run
+	cp, err := OpenCheckpoint(f, fmt.Sprintf("add %s/%d", f.ProjectOwner, f.ProjectNumber))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = cp.finish(err)
+	}()
+
 	repos := map[string]*gh.Repo{}
 	added, updated, failed := 0, 0, 0
+	skipped := 0
+
+	w := newItemWriter(ctx, f, &p)
+	defer func() {
+		err = w.Close(err)
+	}()
+
+	// a written line is marked in the checkpoint once its batch is written, any other once the next is
+	// read. A failed line ends the run of lines a resume skips.
+	pending, failedBefore := 0, 0
+	markPending := func() error {
+		if pending == 0 {
+			return nil
+		}
+
+		ok := failed == failedBefore
+		if !ok {
+			// the lines still queued come first
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		return cp.MarkLine(pending, ok)
+	}
 
 	for ; ; line++ {
+		if err := markPending(); err != nil {
+			return err
+		}
+		pending, failedBefore = 0, failed
+
+		if err := interrupted(ctx, "%d added, %d updated and %d failed", added, updated, failed); err != nil {
+			return err
+		}
+
 		record, err := r.Read()
 		if errors.Is(err, io.EOF) {
 			break
@@ -201,9 +244,13 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 		}
 
 		url := strings.TrimSpace(record[0])
-		if url == "" {
+		if url == "" || cp.LineDone(line) {
+			if url != "" {
+				skipped++
+			}
 			continue
 		}
+		pending = line
 
 		c.Printf("<white>processing line</> <lightWhite>%d</><white>:</> <gray>%s</>\n", line, strings.Join(record, ","))
 
@@ -215,7 +262,7 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 		repoKey := owner + "/" + name
 		repo, ok := repos[repoKey]
 		if !ok {
-			repo, err = gh.NewRepo(repoKey, f.Token)
+			repo, err = gh.NewRepo(repoKey, f.Auth)
 			if err != nil {
 				return fmt.Errorf("creating repo %s: %w", repoKey, err)
 			}
@@ -226,7 +273,7 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 		var nodeID, author string
 		isPR := typ == "pull"
 		if isPR {
-			pr, prErr := repo.GetPullRequest(number)
+			pr, prErr := repo.GetPullRequest(ctx, number)
 			if prErr != nil {
 				c.Printf("  <red>ERROR!!</> %s\n", prErr)
 				failed++
@@ -236,7 +283,7 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 			nodeID = pr.GetNodeID()
 			author = pr.User.GetLogin()
 		} else {
-			issue, issueErr := repo.GetIssue(number)
+			issue, issueErr := repo.GetIssue(ctx, number)
 			if issueErr != nil {
 				c.Printf("  <red>ERROR!!</> %s\n", issueErr)
 				failed++
@@ -247,7 +294,7 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 			author = issue.User.GetLogin()
 		}
 
-		itemID, err := p.HasItem(nodeID)
+		itemID, err := p.HasItem(ctx, nodeID)
 		if err != nil {
 			c.Printf("  <red>ERROR!!</> checking if item is in project: %s\n\n", err)
 			failed++
@@ -313,30 +360,43 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		if itemID == nil {
-			itemID, err = p.AddItem(nodeID)
-			if err != nil {
-				c.Printf("    <red>ERROR!!</> %s\n\n", err)
-				failed++
-
-				continue
-			}
+		// added and updated in batches, the line is marked in the checkpoint once written
+		ctx := itemContext(ctx, repoKey, number, nodeID)
+		iw := itemWrite{NodeID: nodeID, Fields: fields}
+		if existed {
+			iw.ItemID = *itemID
 		}
+		lineNumber := line
+		iw.Done = func(id string, err error) error {
+			if id == "" {
+				logItem(ctx, "add", nil, err)
+			} else {
+				logItem(ctx, "update", &id, err)
+			}
 
-		if len(fields) > 0 {
-			if err = p.UpdateItem(*itemID, fields); err != nil {
-				c.Printf("    <red>ERROR!!</> %s\n\n", err)
+			if err != nil {
+				c.Printf("  <red>ERROR!!</> line %d %s: %s\n", lineNumber, url, err)
 				failed++
-
-				continue
+			} else {
+				c.Printf("  <green>✓</> line %d <magenta>%s</> <gray>- %d field(s) set</>\n", lineNumber, id, len(fields))
+				if existed {
+					updated++
+				} else {
+					added++
+				}
 			}
+
+			return cp.MarkLine(lineNumber, err == nil)
 		}
-		c.Printf("    <green>✓</> <magenta>%s</> <gray>- %d field(s) set</>\n\n", *itemID, len(fields))
-		if existed {
-			updated++
-		} else {
-			added++
+		if err := w.Write(iw); err != nil {
+			return err
 		}
+		pending = 0
+		c.Printf("\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
 	}
 
 	c.Printf("Added <green>%d</> item(s)", added)
@@ -346,6 +406,9 @@ func CmdAdd(cmd *cobra.Command, args []string) error {
 	if failed > 0 {
 		c.Printf(", <red>%d</> failed", failed)
 	}
+	if skipped > 0 {
+		c.Printf(", skipped <gray>%d</> done in the previous run", skipped)
+	}
 	fmt.Println()
 
 	if failed > 0 {
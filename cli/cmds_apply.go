@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func CmdApply(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	f := GetFlags()
+
+	b, err := os.ReadFile(f.PlanFile)
+	if err != nil {
+		return fmt.Errorf("reading plan %s: %w", f.PlanFile, err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return fmt.Errorf("parsing plan %s: %w", f.PlanFile, err)
+	}
+
+	p := gh.NewProject(plan.ProjectOwner, plan.ProjectNumber, f.Auth)
+	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", p.Owner, p.Number)
+	if err := p.LoadDetails(ctx); err != nil {
+		return fmt.Errorf("loading project details: %w", err)
+	}
+	c.Printf("  ID: <magenta>%s</>\n", p.ID)
+
+	// the project is read again so items changed since the plan was made are not overwritten
+	c.Printf("Reading current project state...")
+	current, err := loadProjectState(ctx, p)
+	if err != nil {
+		return err
+	}
+	c.Printf(" <yellow>%d</> items\n\n", len(current))
+
+	// check every item is still as planned, then add and update the rest in batches
+	pending, removes, itemIDs, stale := checkPlan(plan, current)
+
+	batcher := p.NewItemBatcher(f.BatchSize, f.BatchMutations)
+	failed := map[string]error{} // by node id
+
+	var adds []string
+	for _, item := range pending {
+		if _, ok := itemIDs[item.NodeID]; !ok {
+			adds = append(adds, item.NodeID)
+		}
+	}
+	if len(adds) > 0 {
+		c.Printf("\nAdding <yellow>%d</> item(s) to the project...\n", len(adds))
+		added, errs := batcher.AddItems(ctx, adds)
+		for nodeID, id := range added {
+			itemIDs[nodeID] = id
+		}
+		for nodeID, err := range errs {
+			failed[nodeID] = err
+		}
+	}
+
+	var updates []gh.ItemUpdate
+	nodeIDs := map[string]string{} // project item id -> node id
+	for _, item := range pending {
+		itemID, ok := itemIDs[item.NodeID]
+		if !ok || len(item.Changes) == 0 {
+			continue
+		}
+		nodeIDs[itemID] = item.NodeID
+
+		fields := make([]gh.ProjectItemField, 0, len(item.Changes))
+		for i, ch := range item.Changes {
+			fields = append(fields, gh.ProjectItemField{
+				Name:    fmt.Sprintf("f%d", i),
+				FieldID: ch.FieldID,
+				Type:    ch.Type,
+				Value:   normaliseValue(ch.Type, ch.Value), // numbers are float64 once read back from json
+			})
+		}
+		updates = append(updates, gh.ItemUpdate{ItemID: itemID, Fields: fields})
+	}
+	if len(updates) > 0 {
+		c.Printf("Updating the fields of <yellow>%d</> item(s)...\n", len(updates))
+		for itemID, err := range batcher.UpdateItems(ctx, updates) {
+			failed[nodeIDs[itemID]] = err
+		}
+	}
+
+	if len(removes) > 0 {
+		c.Printf("Removing <yellow>%d</> item(s) from the project...\n", len(removes))
+		for _, item := range removes {
+			if err := p.DeleteItem(ctx, itemIDs[item.NodeID]); err != nil {
+				failed[item.NodeID] = err
+			}
+		}
+		pending = append(pending, removes...)
+	}
+
+	for _, item := range pending {
+		var itemID *string
+		if id, ok := itemIDs[item.NodeID]; ok {
+			itemID = &id
+		}
+		err, ok := failed[item.NodeID]
+		logItem(clog.WithFields(ctx, logrus.Fields{"node_id": item.NodeID}), item.Action, itemID, err)
+		if ok {
+			c.Printf("  <red>ERROR!!</> %s: %s\n", item.Label, err)
+		}
+	}
+
+	applied := len(pending) - len(failed)
+	c.Printf("\napplied <green>%d</>, stale <yellow>%d</>, failed <red>%d</>\n", applied, stale, len(failed))
+	return interrupted(ctx, "%d of %d items applied", applied, len(pending))
+}
+
+// checkPlan compares a plan's items with the current project state, returning the adds and updates still
+// as planned, the removes of items still in the project, the project item ids of both by node id and how
+// many items changed since the plan was made
+func checkPlan(plan Plan, current map[string]gh.ProjectItemValues) ([]PlanItem, []PlanItem, map[string]string, int) {
+	var pending, removes []PlanItem
+	itemIDs := map[string]string{} // node id -> project item id
+	stale := 0
+	for _, item := range plan.Items {
+		if item.Action == PlanActionNoOp {
+			continue
+		}
+		printPlanItem(item)
+
+		existing, inProject := current[item.NodeID]
+
+		if item.Action == PlanActionRemove {
+			if !inProject {
+				c.Printf("    <gray>already removed</>\n")
+				continue
+			}
+			itemIDs[item.NodeID] = existing.ID
+			removes = append(removes, item)
+			continue
+		}
+
+		var changed []string
+		for _, ch := range item.Changes {
+			now := ""
+			if v, ok := existing.Values[ch.Field]; ok {
+				now = normaliseValue(v.Type, v.Value)
+			}
+			if !sameValue(ch.Type, now, ch.OldValue) {
+				changed = append(changed, ch.Field)
+			}
+		}
+		if len(changed) > 0 {
+			c.Printf("    <red>STALE</> %v changed since the plan was made, skipping\n", changed)
+			stale++
+			continue
+		}
+
+		if inProject {
+			itemIDs[item.NodeID] = existing.ID
+		}
+		pending = append(pending, item)
+	}
+
+	return pending, removes, itemIDs, stale
+}
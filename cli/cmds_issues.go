@@ -1,24 +1,43 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/go-github/v89/github"
 	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/filter"
 	"github.com/katbyte/ghp-sync/lib/gh"
 	"github.com/spf13/cobra"
 )
 
-func CmdIssues(_ *cobra.Command, _ []string) error {
+func CmdIssues(cmd *cobra.Command, _ []string) (err error) {
+	ctx := cmd.Context()
+
 	// For each repo get all issues and add to project only bugs
 	// Can't add all issues with current limit on number of issues on a project
 	f := GetFlags()
-	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Token)
+	if f.PlanFile != "" {
+		f.DryRun = true
+	}
+	if err := f.ExpandTeams(ctx); err != nil {
+		return err
+	}
+	if err := f.ResolveRepos(ctx); err != nil {
+		return fmt.Errorf("resolving repos: %w", err)
+	}
+	overrides, err := GetRepoOverrides()
+	if err != nil {
+		return err
+	}
+	f.RepoOverrides = overrides
+	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Auth)
 
 	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", f.ProjectOwner, f.ProjectNumber)
-	err := p.LoadDetails()
+	err = p.LoadDetails(ctx)
 	if err != nil {
 		return fmt.Errorf("loading project details: %w", err)
 	}
@@ -36,8 +55,65 @@ func CmdIssues(_ *cobra.Command, _ []string) error {
 	}
 	fmt.Println()
 
+	expr, err := f.GetIssueFilter()
+	if err != nil {
+		return err
+	}
+	if expr != nil {
+		c.Printf("  filter: <yellow>%s</>\n", expr)
+	}
+
+	inProject, err := projectContent(ctx, p, expr)
+	if err != nil {
+		return err
+	}
+
+	pf, err := f.GetProjectFilter(ctx, p)
+	if err != nil {
+		return fmt.Errorf("building project filter: %w", err)
+	}
+
+	// issues have only ever been synced when they match a filter as every issue in a repo is too many, a
+	// search already picks the issues
+	if expr == nil && pf == nil && f.Search == "" {
+		c.Printf("<yellow>no filters set</>, only issues matching --authors, --assignees, --labels-or, --labels-and, --filter or the project filters are synced\n")
+		return nil
+	}
+
+	// dry runs read the project and print the plan of what would change
+	var pl *Planner
+	if f.DryRun {
+		pl = NewPlanner(p)
+	}
+
+	cp, err := OpenCheckpoint(f, checkpointJobKey("issues", f))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = cp.finish(err)
+	}()
+
+	// sync all issues matching the search query across any number of repos
+	if f.Search != "" {
+		c.Printf("Searching for issues matching <white>%s</>...", f.Search)
+		issues, err := p.SearchIssuesGQL(ctx, f.Search, f.ItemLimit, nil)
+		if err != nil {
+			return fmt.Errorf("searching for issues: %w", err)
+		}
+		c.Printf(" found <yellow>%d</>\n", len(*issues))
+
+		if err = syncIssues(ctx, f, p, expr, inProject, pf, pl, cp, issues); err != nil {
+			return err
+		}
+		if err = addSubIssuesIf(ctx, f, p, pl); err != nil {
+			return err
+		}
+		return finishPlan(f, pl)
+	}
+
 	for _, repo := range f.Repos {
-		r, err := gh.NewRepo(repo, f.Token)
+		r, err := gh.NewRepo(repo, f.Auth)
 		if err != nil {
 			return fmt.Errorf("creating repo %s: %w", repo, err)
 		}
@@ -52,96 +128,168 @@ func CmdIssues(_ *cobra.Command, _ []string) error {
 			}
 		}
 		c.Printf("Retrieving all issues for <white>%s</>/<cyan>%s</>...", r.Owner, r.Name)
-		issues, err := r.GetAllIssues(state)
+		issues, err := r.GetAllIssues(ctx, state)
 		if err != nil {
 			return fmt.Errorf("getting issues for %s/%s: %w", r.Owner, r.Name, err)
 		}
 		c.Printf(" found <yellow>%d</>\n", len(*issues))
 
-		filters := f.GetFilters()
+		if err = syncIssues(ctx, f, p, expr, inProject, pf, pl, cp, issues); err != nil {
+			return err
+		}
+	}
+	if err = addSubIssuesIf(ctx, f, p, pl); err != nil {
+		return err
+	}
+	return finishPlan(f, pl)
+}
 
-		// Currently not interested in the username of the author for issues, so I removed the code for now
+// addSubIssuesIf adds the sub-issues and tracked issues of the issues in the project when --add-sub-issues is set
+func addSubIssuesIf(ctx context.Context, f FlagData, p gh.Project, pl *Planner) error {
+	if !f.AddSubIssues {
+		return nil
+	}
 
-		var totalIssues, daysSinceCreation, collectiveDaysSinceCreation int
-		for _, issue := range *issues {
-			issueNode := *issue.NodeID
+	return addSubIssues(ctx, f, p, pl)
+}
 
-			if issue.GetState() == "open" {
-				c.Printf("#<lightCyan>%d</> (<cyan>%s</>) - %s \n", issue.GetNumber(), issue.User.GetLogin(), issue.GetTitle())
-			} else {
-				c.Printf("#<LightBlue>%d</> (<cyan>%s</>) - %s \n", issue.GetNumber(), issue.User.GetLogin(), issue.GetTitle())
-			}
+// syncIssues adds each issue matching the filters to the project and updates its fields, inProject is
+// the content of the project when the filter needs it
+func syncIssues(ctx context.Context, f FlagData, p gh.Project, expr filter.Expr, inProject map[string]bool, pf *ProjectFilter, pl *Planner, cp *Checkpoint, issues *[]github.Issue) (err error) {
+	// Currently not interested in the username of the author for issues, so I removed the code for now
 
-			// only put issues labelled whatever flag is passed (bug, etc) into the project, therefore graphyQL is inside this loop
-			sync := false
-			for _, filter := range filters {
-				match, err := filter.Issue(issue)
-				if err != nil {
-					return fmt.Errorf("ERROR: running filter %s: %w", filter.Name, err)
-				}
-				if match {
-					sync = true
-					break
-				}
-			}
+	var totalIssues, daysSinceCreation, collectiveDaysSinceCreation int
+	var unmatched []unmatchedItem
+	settings := map[string]*repoSettings{}
 
-			if !sync {
-				continue
-			}
+	labelFields, err := f.GetLabelFields(p)
+	if err != nil {
+		return err
+	}
 
-			totalIssues++
-			daysSinceCreation = int(time.Since(issue.GetCreatedAt().Time) / (time.Hour * 24))
-			collectiveDaysSinceCreation += daysSinceCreation
+	tracked, err := trackedIssuesFor(ctx, p, *issues)
+	if err != nil {
+		return err
+	}
 
-			// statuses and waiting days code removed
+	w := newItemWriter(ctx, f, &p)
+	defer func() {
+		err = w.Close(err)
+	}()
 
-			c.Printf("  open %d days\n", daysSinceCreation)
+	for i, issue := range *issues {
+		if err := interrupted(ctx, "%d of %d issues synced", i, len(*issues)); err != nil {
+			return err
+		}
+		issueNode := *issue.NodeID
+		ctx := itemContext(ctx, issueRepo(issue), issue.GetNumber(), issueNode)
 
-			c.Printf("  syncing (<cyan>%s</>) to project.. ", issueNode)
-			iid, err := p.AddItem(issueNode)
-			if err != nil {
-				c.Printf("\n\n <red>ERROR!!</> %s", err)
-				continue
-			}
-			c.Printf("<magenta>%s</>", *iid)
-
-			fields := []gh.ProjectItemField{
-				{
-					Name:    "issue_number",
-					FieldID: p.FieldIDs["Issue#"],
-					Type:    gh.ItemValueTypeText,
-					Value:   strconv.Itoa(*issue.Number),
-				},
-				{
-					Name:    "user",
-					FieldID: p.FieldIDs["User"],
-					Type:    gh.ItemValueTypeText,
-					Value:   issue.User.GetLogin(),
-				},
-				{
-					Name:    "age",
-					FieldID: p.FieldIDs["Age"],
-					Type:    gh.ItemValueTypeNumber,
-					Value:   daysSinceCreation,
-				},
-			}
+		if issue.GetState() == "open" {
+			c.Printf("#<lightCyan>%d</> (<cyan>%s</>) - %s \n", issue.GetNumber(), issue.User.GetLogin(), issue.GetTitle())
+		} else {
+			c.Printf("#<LightBlue>%d</> (<cyan>%s</>) - %s \n", issue.GetNumber(), issue.User.GetLogin(), issue.GetTitle())
+		}
 
-			err = p.UpdateItem(*iid, fields)
+		if cp.Done(issueNode) {
+			c.Printf("  <gray>skipping, done in the previous run</>\n")
+			continue
+		}
+
+		// only put issues matching the filters (labelled bug, etc) into the project, therefore graphyQL is inside this loop
+		if expr != nil {
+			var projects []string
+			if inProject[issueNode] {
+				projects = []string{strconv.Itoa(p.Number)}
+			}
+			match, err := expr.Eval(IssueFilterFields(issue, projects))
 			if err != nil {
-				c.Printf("<red>ERROR!!</> %s\n", err)
+				return fmt.Errorf("filtering issue %d: %w", issue.GetNumber(), err)
+			}
+			if !match {
+				c.Printf("  <gray>skipping, doesn't match filter</>\n")
+				unmatched = append(unmatched, unmatchedItem{issueNode, fmt.Sprintf("%s#%d %s", issueRepo(issue), issue.GetNumber(), issue.GetTitle())})
 				continue
 			}
+		}
 
-			c.Printf("\n")
+		if pf != nil && !pf.Match(issueNode) {
+			c.Printf("  <gray>skipping, doesn't match project filters</>\n")
+			continue
 		}
 
-		// output
-		// totalDaysOpen is for ALL bugs, so this will not match the metrics that only track last 365 days.
-		if totalIssues > 0 {
-			c.Printf("Total of %d bugs open for an average of %d days\n", totalIssues, collectiveDaysSinceCreation/totalIssues)
-		} else {
-			c.Printf("Total of 0 issues\n")
+		totalIssues++
+		daysSinceCreation = int(time.Since(issue.GetCreatedAt().Time) / (time.Hour * 24))
+		collectiveDaysSinceCreation += daysSinceCreation
+
+		// statuses and waiting days code removed
+
+		c.Printf("  open %d days\n", daysSinceCreation)
+
+		rs, err := f.settingsForRepo(p, settings, issueRepo(issue))
+		if err != nil {
+			return err
+		}
+
+		c.Printf("  syncing (<cyan>%s</>) to project.. ", issueNode)
+		if f.DryRun {
+			c.Printf("<yellow>[dry-run]</>")
+		}
+		c.Printf("\n")
+
+		fields := []gh.ProjectItemField{
+			{
+				Name:    "issue_number",
+				FieldID: p.FieldIDs["Issue#"],
+				Type:    gh.ItemValueTypeText,
+				Value:   strconv.Itoa(*issue.Number),
+			},
+			{
+				Name:    "user",
+				FieldID: p.FieldIDs["User"],
+				Type:    gh.ItemValueTypeText,
+				Value:   issue.User.GetLogin(),
+			},
+			{
+				Name:    "age",
+				FieldID: p.FieldIDs["Age"],
+				Type:    gh.ItemValueTypeNumber,
+				Value:   daysSinceCreation,
+			},
+		}
+
+		fields = append(fields, issueItemFields(ctx, f, p, issue, tracked[issueNode])...)
+
+		labels := make([]string, 0, len(issue.Labels))
+		for _, l := range issue.Labels {
+			labels = append(labels, l.GetName())
+		}
+		fields = withFields(fields, labelItemFields(ctx, f, p, labelFields, labels))
+
+		// added and updated in batches, the issue is marked done in the checkpoint once written
+		label := fmt.Sprintf("%s#%d %s", issueRepo(issue), issue.GetNumber(), issue.GetTitle())
+		if f.DryRun {
+			if err = pl.Item(ctx, issueNode, label, withFields(fields, rs.Set)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = w.Write(itemWrite{NodeID: issueNode, Fields: withFields(fields, rs.Set), Done: syncDone(ctx, cp, label, issueNode)}); err != nil {
+			return err
 		}
 	}
-	return nil
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	// output
+	// totalDaysOpen is for ALL bugs, so this will not match the metrics that only track last 365 days.
+	if totalIssues > 0 {
+		c.Printf("Total of %d bugs open for an average of %d days\n", totalIssues, collectiveDaysSinceCreation/totalIssues)
+	} else {
+		c.Printf("Total of 0 issues\n")
+	}
+
+	return removeUnmatched(ctx, f, p, pl, unmatched)
 }
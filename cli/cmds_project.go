@@ -9,7 +9,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func CmdSync(_ *cobra.Command, args []string) error {
+// syncNewItemStatus is the status of items sync adds to the destination project
+const syncNewItemStatus = "Backlog [PRs]"
+
+func CmdSync(cmd *cobra.Command, args []string) (err error) {
+	ctx := cmd.Context()
 	f := GetFlags()
 
 	sourceProjectOwner := args[0]
@@ -18,11 +22,11 @@ func CmdSync(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid project number %q: %w", args[1], err)
 	}
 
-	source := gh.NewProject(sourceProjectOwner, sourceProjectNumber, f.Token)
-	destination := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Token)
+	source := gh.NewProject(sourceProjectOwner, sourceProjectNumber, f.Auth)
+	destination := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Auth)
 
 	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", f.ProjectOwner, f.ProjectNumber)
-	err = destination.LoadDetails()
+	err = destination.LoadDetails(ctx)
 	if err != nil {
 		return fmt.Errorf("loading destination project details: %w", err)
 	}
@@ -33,25 +37,45 @@ func CmdSync(_ *cobra.Command, args []string) error {
 		c.Printf("    <lightBlue>%s</> <> <lightCyan>%s</>\n", field.Name, field.ID)
 	}
 	c.Printf(" getting existing items.. ")
-	dstItems, err := destination.GetItems()
+	dstItems, err := destination.GetItems(ctx)
 	if err != nil {
 		return fmt.Errorf("getting destination items: %w", err)
 	}
 	c.Printf("  <yellow>%d</>\n\n\n", len(dstItems))
 
+	if f.Filters.ProjectStatusIs != "" || len(f.Filters.ProjectFieldPopulated) > 0 {
+		c.Printf("Looking up project details for source <green>%s</>/<lightGreen>%d</>...\n", source.Owner, source.Number)
+		if err = source.LoadDetails(ctx); err != nil {
+			return fmt.Errorf("loading source project details: %w", err)
+		}
+	}
+
 	dstItemNodeIDMap := map[string]gh.ProjectItem{}
 	for _, item := range dstItems {
 		dstItemNodeIDMap[item.NodeID] = item
 	}
 
+	// the project filters apply to the items' values in the source project
+	pf, err := f.GetProjectFilter(ctx, source)
+	if err != nil {
+		return fmt.Errorf("building project filter: %w", err)
+	}
+
 	c.Printf("Getting items from source <green>%s</>/<lightGreen>%d</>...", source.Owner, source.Number)
-	srcItems, err := source.GetItems()
+	srcItems, err := source.GetItems(ctx)
 	if err != nil {
 		return fmt.Errorf("getting source items: %w", err)
 	}
 	c.Printf("  <white>%d</>\n", len(srcItems))
 
-	for _, srcItem := range srcItems {
+	w := newItemWriter(ctx, f, &destination)
+	defer func() {
+		err = w.Close(err)
+	}()
+	for i, srcItem := range srcItems {
+		if err := interrupted(ctx, "%d of %d items synced", i, len(srcItems)); err != nil {
+			return err
+		}
 		c.Printf("  Item: <magenta>%s</> <lightMagenta>(%s)</> ", srcItem.ID, srcItem.NodeID)
 
 		// TODO filters, for now we just want to add all PRs with a due date
@@ -60,7 +84,10 @@ func CmdSync(_ *cobra.Command, args []string) error {
 			continue
 		}
 
-		// TODO filters - now we only want ones with status X
+		if pf != nil && !pf.Match(srcItem.NodeID) {
+			c.Printf(" skipping, doesn't match project filters\n")
+			continue
+		}
 
 		// parse the url (todo handle issues?)
 		owner, name, _, number, err := gh.ParseGitHubURL(srcItem.URL)
@@ -69,46 +96,18 @@ func CmdSync(_ *cobra.Command, args []string) error {
 		}
 
 		// get the pr via rest
-		r, err := gh.NewRepo(owner+"/"+name, f.Token)
+		r, err := gh.NewRepo(owner+"/"+name, f.Auth)
 		if err != nil {
 			return fmt.Errorf("creating repo %s/%s: %w", owner, name, err)
 		}
 
-		pr, err := r.GetPullRequest(number)
+		pr, err := r.GetPullRequest(ctx, number)
 		if err != nil {
 			return fmt.Errorf("getting PR %d: %w", number, err)
 		}
 
 		nodeID := *pr.NodeID
-		var dstItemID string
 		c.Printf("<blue>%s</>/<lightBlue>%s</>#<lightCyan>%d</> \n", owner, name, pr.GetNumber())
-		if di, ok := dstItemNodeIDMap[nodeID]; ok {
-			c.Printf("  already exists, ")
-			dstItemID = di.ID
-		} else {
-			c.Printf("  <green>adding</> ")
-
-			iid, addErr := destination.AddItem(nodeID)
-			if addErr != nil {
-				c.Printf("\n\n <red>ERROR!!</> %s", addErr)
-				continue
-			}
-			c.Printf("(<magenta>%s</>), setting status.. ", *iid)
-			dstItemID = *iid
-
-			if statusErr := destination.SetItemStatus(dstItemID, "Backlog [PRs]"); statusErr != nil {
-				c.Printf("\n\n <red>ERROR!!</> %s", statusErr)
-				continue
-			}
-		}
-
-		if dstItemID == "" {
-			c.Printf("\n\n <red>ERROR!!</> no item ID found for %s, skipping\n", srcItem.NodeID)
-			continue
-		}
-
-		// update the other fields
-		c.Printf("<blue>updating</>...")
 
 		fields := []gh.ProjectItemField{
 			{Name: "number", FieldID: destination.FieldIDs["#"], Type: gh.ItemValueTypeNumber, Value: *pr.Number},
@@ -117,14 +116,32 @@ func CmdSync(_ *cobra.Command, args []string) error {
 			{Name: "duedate", FieldID: destination.FieldIDs["Due Date"], Type: gh.ItemValueTypeDate, Value: srcItem.DueDate},
 		}
 
-		err = destination.UpdateItem(dstItemID, fields)
-		if err != nil {
-			c.Printf("\n\n <red>ERROR!!</> %s\n", err)
-			continue
+		// added and updated in batches
+		iw := itemWrite{NodeID: nodeID}
+		if di, ok := dstItemNodeIDMap[nodeID]; ok {
+			c.Printf("  already exists, <blue>updating</>\n")
+			iw.ItemID = di.ID
+		} else {
+			c.Printf("  <green>adding</> with status <white>%s</>\n", syncNewItemStatus)
+			fields = append([]gh.ProjectItemField{
+				{Name: "status", FieldID: destination.FieldIDs["Status"], Type: gh.ItemValueTypeSingleSelect, Value: syncNewItemStatus},
+			}, fields...)
 		}
+		iw.Fields = fields
 
-		fmt.Println()
+		label := fmt.Sprintf("%s/%s#%d", owner, name, pr.GetNumber())
+		iw.Done = func(itemID string, err error) error {
+			if err != nil {
+				c.Printf("<red>ERROR!!</> %s: %s\n", label, err)
+				return nil
+			}
+			c.Printf("  %s <gray>-></> <magenta>%s</>\n", label, itemID)
+			return nil
+		}
+		if err := w.Write(iw); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return w.Flush()
 }
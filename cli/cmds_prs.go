@@ -1,23 +1,39 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/filter"
 	"github.com/katbyte/ghp-sync/lib/gh"
 	"github.com/spf13/cobra"
 )
 
-func CmdPRs(_ *cobra.Command, _ []string) error {
+func CmdPRs(cmd *cobra.Command, _ []string) (err error) {
+	ctx := cmd.Context()
 	f := GetFlags()
-	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Token)
+	if f.PlanFile != "" {
+		f.DryRun = true
+	}
+	if err := f.ExpandTeams(ctx); err != nil {
+		return err
+	}
+	if err := f.ResolveRepos(ctx); err != nil {
+		return fmt.Errorf("resolving repos: %w", err)
+	}
+	overrides, err := GetRepoOverrides()
+	if err != nil {
+		return err
+	}
+	f.RepoOverrides = overrides
+	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Auth)
 
 	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", f.ProjectOwner, f.ProjectNumber)
-	err := p.LoadDetails()
+	err = p.LoadDetails(ctx)
 	if err != nil {
 		return fmt.Errorf("loading project details: %w", err)
 	}
@@ -35,6 +51,31 @@ func CmdPRs(_ *cobra.Command, _ []string) error {
 	}
 	fmt.Println()
 
+	expr, err := f.GetFilter(true)
+	if err != nil {
+		return err
+	}
+
+	// projects set up before an optional field was added to the defaults won't have it, so those are skipped
+	// unless explicitly requested. Any other field missing from the project is an error.
+	var prFields, missingFields []string
+	for _, name := range f.PRFields {
+		def, ok := PRFields[name]
+		if !ok {
+			return fmt.Errorf("unknown pr field %q", name)
+		}
+
+		switch _, inProject := p.FieldIDs[name]; {
+		case inProject:
+			prFields = append(prFields, name)
+		case def.Optional && len(f.PRPopulateFields) == 0:
+			missingFields = append(missingFields, name)
+		default:
+			return fmt.Errorf("pr field %q not found in project", name)
+		}
+	}
+	f.PRFields = prFields
+
 	// Print config summary
 	c.Printf("<white>Configuration:</>\n")
 	c.Printf("  <lightBlue>repos</>:        ")
@@ -45,6 +86,9 @@ func CmdPRs(_ *cobra.Command, _ []string) error {
 		c.Printf("<cyan>%s</>", repo)
 	}
 	c.Printf("\n")
+	if f.Search != "" {
+		c.Printf("  <lightBlue>search</>:       <cyan>%s</>\n", f.Search)
+	}
 	c.Printf("  <lightBlue>pr states</>:    <green>%s</>\n", strings.Join(f.Filters.States, ", "))
 	if len(f.Filters.Authors) > 0 {
 		c.Printf("  <lightBlue>authors</>:      <yellow>%s</>\n", strings.Join(f.Filters.Authors, ", "))
@@ -61,9 +105,49 @@ func CmdPRs(_ *cobra.Command, _ []string) error {
 	if len(f.Filters.LabelsAnd) > 0 {
 		c.Printf("  <lightBlue>labels (and)</>: <yellow>%s</>\n", strings.Join(f.Filters.LabelsAnd, ", "))
 	}
+	if f.Filters.ProjectStatusIs != "" {
+		c.Printf("  <lightBlue>status is</>:    <yellow>%s</>\n", f.Filters.ProjectStatusIs)
+	}
+	if len(f.Filters.ProjectFieldPopulated) > 0 {
+		c.Printf("  <lightBlue>populated</>:    <yellow>%s</>\n", strings.Join(f.Filters.ProjectFieldPopulated, ", "))
+	}
+	if expr != nil {
+		c.Printf("  <lightBlue>filter</>:       <yellow>%s</>\n", expr)
+	}
 	c.Printf("  <lightBlue>pr fields</>:    <lightGreen>%s</>\n", strings.Join(f.PRFields, ", "))
+	if len(missingFields) > 0 {
+		c.Printf("  <lightBlue>not in project</>: <gray>%s</>\n", strings.Join(missingFields, ", "))
+	}
+	c.Printf("  <lightBlue>waiting labels</>: <yellow>%s</>\n", strings.Join(f.WaitingLabels, ", "))
+	for _, rule := range f.StatusRules {
+		c.Printf("  <lightBlue>status rule</>:  <magenta>%s</>\n", rule)
+	}
+	for _, o := range f.RepoOverrides {
+		c.Printf("  <lightBlue>override</>:     <cyan>%s</>", o.Pattern)
+		if len(o.WaitingLabels) > 0 {
+			c.Printf(" <gray>waiting labels:</> <yellow>%s</>", strings.Join(o.WaitingLabels, ", "))
+		}
+		for _, rule := range o.StatusRules {
+			c.Printf(" <gray>rule:</> <magenta>%s</>", rule)
+		}
+		for _, set := range o.Set {
+			c.Printf(" <gray>set:</> <yellow>%s</>", set)
+		}
+		c.Printf("\n")
+	}
 	if len(f.SyncLinkedIssueFields) > 0 {
 		c.Printf("  <lightBlue>issue sync</>:   <magenta>%s</>\n", strings.Join(f.SyncLinkedIssueFields, ", "))
+	}
+	if len(f.SyncPRFieldsToIssues) > 0 {
+		c.Printf("  <lightBlue>to issues</>:    <magenta>%s</>\n", strings.Join(f.SyncPRFieldsToIssues, ", "))
+	}
+	if len(f.SyncLinkedIssueFields) > 0 || len(f.SyncPRFieldsToIssues) > 0 {
+		if len(f.LinkedIssueStrategies) > 0 {
+			c.Printf("  <lightBlue>strategies</>:   <magenta>%s</>\n", strings.Join(f.LinkedIssueStrategies, ", "))
+		}
+		if len(f.LinkedIssueSources) > 0 {
+			c.Printf("  <lightBlue>issue sources</>: <magenta>%s</>\n", strings.Join(f.LinkedIssueSources, ", "))
+		}
 	} else {
 		c.Printf("  <lightBlue>issue sync</>:   <gray>disabled</>\n")
 	}
@@ -75,309 +159,356 @@ func CmdPRs(_ *cobra.Command, _ []string) error {
 	}
 	fmt.Println()
 
+	pf, err := f.GetProjectFilter(ctx, p)
+	if err != nil {
+		return fmt.Errorf("building project filter: %w", err)
+	}
+
+	limitMsg := ""
+	if f.ItemLimit != 0 {
+		limitMsg = " limited to: <yellow>" + strconv.Itoa(f.ItemLimit) + "</> items"
+	}
+
+	// dry runs read the project and print the plan of what would change
+	var pl *Planner
+	if f.DryRun {
+		pl = NewPlanner(p)
+	}
+
+	cp, err := OpenCheckpoint(f, checkpointJobKey("prs", f))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = cp.finish(err)
+	}()
+
+	// sync all prs matching the search query across any number of repos
+	if f.Search != "" {
+		c.Printf("Searching for prs matching <white>%s</>%s. Loaded ", f.Search, limitMsg)
+		prs, err := p.SearchPullRequestsGQL(ctx, f.Search, f.Filters.States, f.Filters.Reviewers, f.ItemLimit, func(i int) {
+			fmt.Printf("%d ", i)
+		})
+		if err != nil {
+			return fmt.Errorf("searching for PRs: %w", err)
+		}
+		c.Printf("<yellow>%d</> items\n", len(*prs))
+
+		if err = syncPRs(ctx, f, p, expr, pf, pl, cp, prs); err != nil {
+			return err
+		}
+		return finishPlan(f, pl)
+	}
+
 	// for each repo, get all prs, and add to project
 	for _, repo := range f.Repos {
-		r, err := gh.NewRepo(repo, f.Token)
+		r, err := gh.NewRepo(repo, f.Auth)
 		if err != nil {
 			return fmt.Errorf("creating repo %s: %w", repo, err)
 		}
 
-		limitMsg := ""
-		if f.ItemLimit != 0 {
-			limitMsg = " limited to: <yellow>" + strconv.Itoa(f.ItemLimit) + "</> items"
-		}
-
 		// get all pull requests
 		c.Printf("Retrieving all prs for <white>%s</>/<cyan>%s</> with states <green>%s</>%s. Loaded ", r.Owner, r.Name, f.Filters.States, limitMsg)
-		prs, err := r.GetAllPullRequestsGQL(f.Filters.States, f.Filters.Reviewers, f.ItemLimit, func(i int) {
+		prs, err := r.GetAllPullRequestsGQL(ctx, f.Filters.States, f.Filters.Reviewers, f.ItemLimit, func(i int) {
 			fmt.Printf("%d ", i)
 		})
 		if err != nil {
 			return fmt.Errorf("getting PRs for %s/%s: %w", r.Owner, r.Name, err)
 		}
 		c.Printf("<yellow>%d</> items\n", len(*prs))
-		prs = FilterByFlags(f, prs)
-
-		byStatus := map[string][]int{}
 
-		for i, pr := range *prs {
-			prNode := pr.NodeID
+		if err = syncPRs(ctx, f, p, expr, pf, pl, cp, prs); err != nil {
+			return err
+		}
+	}
+	return finishPlan(f, pl)
+}
 
-			c.Printf("<white>%d</><gray>/%d</> Syncing pr <lightCyan>%d</> (<cyan>%s</>) to project.. ", i+1, len(*prs), pr.Number, prNode)
+// syncPRs filters the prs and then adds/updates each of them in the project
+func syncPRs(ctx context.Context, f FlagData, p gh.Project, expr filter.Expr, pf *ProjectFilter, pl *Planner, cp *Checkpoint, prs *[]gh.PullRequest) (err error) {
+	var unmatched []unmatchedItem
+	if expr != nil {
+		all := *prs
+		if prs, err = FilterPRs(expr, prs); err != nil {
+			return err
+		}
 
-			var iid *string
-			if !f.DryRun {
-				iid, err = p.AddItem(prNode)
-				if err != nil {
-					c.Printf("\n\n <red>ERROR!!</> %s", err)
-					continue
-				}
-				c.Printf("<magenta>%s</>", *iid)
-			} else {
-				c.Printf("<yellow>[dry-run]</>")
+		matched := map[string]bool{}
+		for _, pr := range *prs {
+			matched[pr.NodeID] = true
+		}
+		for _, pr := range all {
+			if !matched[pr.NodeID] {
+				unmatched = append(unmatched, unmatchedItem{pr.NodeID, fmt.Sprintf("%s#%d %s", pr.Repository, pr.Number, pr.Title)})
 			}
+		}
+	}
+	if pf != nil {
+		prs = FilterByProject(pf, prs)
+	}
 
-			daysOpen := int(time.Since(pr.CreatedAt) / (time.Hour * 24))
-			daysWaiting := 0
-
-			var statusText string
-			switch {
-			case strings.EqualFold(pr.State, "merged"):
-				statusText = "Merged"
-				daysOpen = int(pr.ClosedAt.Sub(pr.CreatedAt) / (time.Hour * 24))
-				c.Printf("  <green>Merged</>\n")
-			case pr.ReviewDecision == "APPROVED": // TODO if approved make sure it stays approved
-				statusText = "Approved"
-				c.Printf("  <blue>Approved</> <gray>(reviews)</>\n")
-			case strings.EqualFold(pr.State, "closed"): // We filter by open PRs so pr.State should never be `closed`?
-				statusText = "Closed"
-				daysOpen = int(pr.ClosedAt.Sub(pr.CreatedAt) / (time.Hour * 24))
-				c.Printf("  <darkred>Closed</> <gray>(state)</>\n")
-
-			case strings.EqualFold(pr.Milestone, "Blocked"):
-				statusText = "Blocked"
-				c.Printf("  <red>Blocked</> <gray>(milestone)</>\n")
-			case pr.Draft:
-				statusText = "In Progress"
-				c.Printf("  <yellow>In Progress</> <gray>(draft)</>\n")
-			case pr.State == "":
-				statusText = "In Progress"
-				c.Printf("  <yellow>In Progress</> <gray>(unknown state)</>\n")
-			case pr.AssociatedLabels["waiting-response"]:
-				statusText = "Waiting for Response"
-				c.Printf("  <lightGreen>Waiting for Response</> <gray>(label)</>\n")
-			default:
-				statusText = "Waiting"
-				c.Printf("  <green>Waiting for Review</> <gray>(default)</>")
-
-				// calculate days waiting
-				daysWaiting = daysOpen
-
-				events, eventsErr := r.GetAllIssueEvents(pr.Number)
-				if eventsErr != nil {
-					return fmt.Errorf("getting events for PR %d: %w", pr.Number, eventsErr)
-				}
-				c.Printf(" with <magenta>%d</> events\n", len(*events))
-
-				for _, t := range *events {
-					// check for waiting response label removed
-					if t.GetEvent() == "unlabeled" {
-						if t.Label.GetName() == "waiting-response" {
-							daysWaiting = int(time.Since(t.GetCreatedAt().Time) / (time.Hour * 24))
-							break
-						}
-					}
-
-					// check for blocked milestone removal
-					if t.GetEvent() == "demilestoned" {
-						if t.Milestone.GetTitle() == "Blocked" {
-							daysWaiting = int(time.Since(t.GetCreatedAt().Time) / (time.Hour * 24))
-							break
-						}
-					}
-				}
-			}
+	// global status rules are validated up front, repo overrides when a pr from the repo is first seen
+	if _, err = f.GetStatusRules(p); err != nil {
+		return err
+	}
+
+	labelFields, err := f.GetLabelFields(p)
+	if err != nil {
+		return err
+	}
 
-			byStatus[statusText] = append(byStatus[statusText], pr.Number)
+	ls, err := f.GetLinkedIssueSync(p)
+	if err != nil {
+		return err
+	}
 
-			c.Printf("  open %d days, waiting %d days\n", daysOpen, daysWaiting)
+	needTimeline := false
+	for _, name := range f.PRFields {
+		if PRFields[name].NeedsTimeline {
+			needTimeline = true
+		}
+	}
 
-			// Build field context for computing values
-			fieldCtx := PRFieldContext{
-				PR:          &pr,
-				Project:     p,
-				DaysOpen:    daysOpen,
-				DaysWaiting: daysWaiting,
-				Status:      statusText,
-			}
+	// repos are looked up per pr as search results can span many of them
+	repos := map[string]*gh.Repo{}
+	settings := map[string]*repoSettings{}
+	byStatus := map[string][]int{}
+	w := newItemWriter(ctx, f, &p)
+	defer func() {
+		err = w.Close(err)
+	}()
+
+	for i, pr := range *prs {
+		if err := interrupted(ctx, "%d of %d prs synced", i, len(*prs)); err != nil {
+			return err
+		}
+		prNode := pr.NodeID
+		ctx := itemContext(ctx, pr.Repository, pr.Number, prNode)
 
-			// Build fields dynamically from registry
-			var fields []gh.ProjectItemField
-			for _, fieldName := range f.PRFields {
-				fieldID, ok := p.FieldIDs[fieldName]
-				if !ok {
-					return fmt.Errorf("pr field %q not found in project", fieldName)
-				}
+		rs, err := f.settingsForRepo(p, settings, pr.Repository)
+		if err != nil {
+			return err
+		}
+		rf := rs.Flags
 
-				fieldDef := PRFields[fieldName]
-				value := fieldDef.ComputeFn(fieldCtx)
-				if value == nil {
-					continue // ComputeFn returned nil, skip this field
-				}
+		c.Printf("<white>%d</><gray>/%d</> Syncing pr <lightCyan>%d</> (<cyan>%s</>) to project.. ", i+1, len(*prs), pr.Number, prNode)
+		if cp.Done(prNode) {
+			c.Printf("<gray>skipping, done in the previous run</>\n")
+			continue
+		}
+
+		if f.DryRun {
+			c.Printf("<yellow>[dry-run]</>")
+		}
 
-				fields = append(fields, gh.ProjectItemField{
-					Name:    strings.ToLower(strings.NewReplacer(" ", "_", "#", "").Replace(fieldName)),
-					FieldID: fieldID,
-					Type:    fieldDef.Type,
-					Value:   value,
-				})
+		daysOpen := int(time.Since(pr.CreatedAt) / (time.Hour * 24))
+		if strings.EqualFold(pr.State, "merged") || strings.EqualFold(pr.State, "closed") {
+			daysOpen = int(pr.ClosedAt.Sub(pr.CreatedAt) / (time.Hour * 24))
+		}
+		daysWaiting := 0
+
+		// the timeline is only fetched when a field or status rule needs it, or for the waiting status
+		var timeline *gh.TimelineStats
+		if needTimeline || len(rs.Rules) > 0 {
+			if timeline, err = getPRTimeline(ctx, rf, repos, pr); err != nil {
+				return err
 			}
+		}
 
-			if !f.DryRun && iid != nil {
-				err = p.UpdateItem(*iid, fields)
-				if err != nil {
-					c.Printf("<red>ERROR!!</> %s\n\n", err)
-					continue
-				}
-			} else if f.DryRun {
-				c.Printf(" <yellow>[dry-run: would update %d fields]</>", len(fields))
+		var statusText string
+		for _, rule := range rs.Rules {
+			fields := PRFilterFields(pr)
+			addTimelineFilterFields(fields, timeline)
+
+			match, ruleErr := rule.Expr.Eval(fields)
+			if ruleErr != nil {
+				return fmt.Errorf("evaluating status rule %q for pr %d: %w", rule.Status, pr.Number, ruleErr)
+			}
+			if match {
+				statusText = rule.Status
+				break
 			}
+		}
 
-			// Sync fields from linked issues if configured
-			if len(f.SyncLinkedIssueFields) > 0 {
-				if len(pr.ClosingIssues) == 0 {
-					c.Printf("  <gray>🔗 linked issue sync: no closing issues referenced</>")
-				} else {
-					c.Printf("  <magenta>🔗</> linked issue sync (<cyan>%s</>)\n", strings.Join(f.SyncLinkedIssueFields, ", "))
-					c.Printf("    closing issue(s): ")
-					for i, ci := range pr.ClosingIssues {
-						if i > 0 {
-							c.Printf(", ")
-						}
-						c.Printf("<lightCyan>#%d</>", ci.Number)
-					}
-					c.Printf("\n")
-
-					// Find which linked issues are in the project
-					type foundIssue struct {
-						NodeID string
-						Number int
-						ItemID string
-					}
-					var inProject []foundIssue
-					for _, ci := range pr.ClosingIssues {
-						c.Printf("    checking <lightCyan>#%d</> (<gray>%s</>).. ", ci.Number, ci.NodeID)
-						itemID, lookupErr := p.HasItem(ci.NodeID)
-						if lookupErr != nil {
-							c.Printf("<red>ERROR!</> %s\n", lookupErr)
-							continue
-						}
-						if itemID != nil {
-							c.Printf("<green>✓ in project</> (<gray>%s</>)\n", *itemID)
-							inProject = append(inProject, foundIssue{NodeID: ci.NodeID, Number: ci.Number, ItemID: *itemID})
-						} else {
-							c.Printf("<yellow>✗ not in project</>\n")
-						}
-					}
-
-					switch {
-					case len(inProject) == 0:
-						c.Printf("    <yellow>⚠ no linked issues found in project, skipping field sync</>")
-					case len(inProject) > 1:
-						c.Printf("    <yellow>⚠ multiple linked issues in project (%d), skipping field sync</>", len(inProject))
-					default:
-						// Exactly one linked issue found — fetch its field values
-						c.Printf("    reading fields from issue <lightCyan>#%d</>...\n", inProject[0].Number)
-						issueFieldValues, lookupErr := p.GetItemFieldValuesByNodeID(inProject[0].NodeID, f.SyncLinkedIssueFields)
-						if lookupErr != nil {
-							c.Printf("    <red>ERROR!</> reading linked issue fields: %s", lookupErr)
-						} else {
-							var linkedFields []gh.ProjectItemField
-							for _, fieldName := range f.SyncLinkedIssueFields {
-								fv, ok := issueFieldValues[fieldName]
-								if !ok {
-									c.Printf("      <gray>%s: <empty></>\n", fieldName)
-									continue
-								}
-
-								fieldID, hasField := p.FieldIDs[fieldName]
-								if !hasField {
-									c.Printf("      <yellow>%s: field not found in project, skipping</>\n", fieldName)
-									continue
-								}
-
-								c.Printf("      <green>%s</>: <white>%v</> (<gray>%s</>)\n", fieldName, fv.Value, fv.Type)
-								linkedFields = append(linkedFields, gh.ProjectItemField{
-									Name:    "linked_" + strings.ToLower(strings.NewReplacer(" ", "_", "#", "").Replace(fieldName)),
-									FieldID: fieldID,
-									Type:    fv.Type,
-									Value:   fv.Value,
-								})
-							}
-
-							switch {
-							case len(linkedFields) == 0:
-								c.Printf("    <yellow>⚠ no field values to sync</>")
-							case !f.DryRun && iid != nil:
-								c.Printf("    syncing <lightGreen>%d</> field(s) to PR.. ", len(linkedFields))
-								syncErr := p.UpdateItem(*iid, linkedFields)
-								if syncErr != nil {
-									c.Printf("<red>ERROR!</> %s", syncErr)
-								} else {
-									c.Printf("<green>✓ done</>")
-								}
-							case f.DryRun:
-								c.Printf("    <yellow>[dry-run: would sync %d field(s)]</>", len(linkedFields))
-							}
-						}
-					}
+		switch {
+		case statusText != "":
+			c.Printf("  <magenta>%s</> <gray>(rule)</>\n", statusText)
+		case strings.EqualFold(pr.State, "merged"):
+			statusText = "Merged"
+			c.Printf("  <green>Merged</>\n")
+		case pr.ReviewDecision == "APPROVED": // TODO if approved make sure it stays approved
+			statusText = "Approved"
+			c.Printf("  <blue>Approved</> <gray>(reviews)</>\n")
+		case strings.EqualFold(pr.State, "closed"): // We filter by open PRs so pr.State should never be `closed`?
+			statusText = "Closed"
+			c.Printf("  <darkred>Closed</> <gray>(state)</>\n")
+
+		case strings.EqualFold(pr.Milestone, blockedMilestone):
+			statusText = "Blocked"
+			c.Printf("  <red>Blocked</> <gray>(milestone)</>\n")
+		case pr.Draft:
+			statusText = "In Progress"
+			c.Printf("  <yellow>In Progress</> <gray>(draft)</>\n")
+		case pr.State == "":
+			statusText = "In Progress"
+			c.Printf("  <yellow>In Progress</> <gray>(unknown state)</>\n")
+		case hasAnyLabel(pr, rf.WaitingLabels):
+			statusText = "Waiting for Response"
+			c.Printf("  <lightGreen>Waiting for Response</> <gray>(label)</>\n")
+		default:
+			statusText = "Waiting"
+			c.Printf("  <green>Waiting for Review</> <gray>(default)</>")
+
+			if timeline == nil {
+				if timeline, err = getPRTimeline(ctx, rf, repos, pr); err != nil {
+					return err
 				}
-				c.Printf("\n")
 			}
 
-			c.Printf("\n")
+			// days waiting on the maintainers since the ball was last put in their court
+			if timeline.BallInCourt == gh.CourtMaintainer {
+				daysWaiting = timeline.DaysInCourt()
+			}
+			c.Printf(" in the <magenta>%s</> court\n", timeline.BallInCourt)
+		}
+
+		byStatus[statusText] = append(byStatus[statusText], pr.Number)
+
+		c.Printf("  open %d days, waiting %d days\n", daysOpen, daysWaiting)
+
+		// Build field context for computing values
+		fieldCtx := PRFieldContext{
+			PR:          &pr,
+			Project:     p,
+			DaysOpen:    daysOpen,
+			DaysWaiting: daysWaiting,
+			Status:      statusText,
+			Timeline:    timeline,
+		}
+
+		// Build fields dynamically from registry
+		var fields []gh.ProjectItemField
+		for _, fieldName := range f.PRFields {
+			fieldDef := PRFields[fieldName]
+			value := fieldDef.ComputeFn(fieldCtx)
+			if value == nil {
+				continue // ComputeFn returned nil, skip this field
+			}
 
-			// TODO remove closed PRs? move them to closed status?
+			field, fieldErr := projectField(ctx, f, p, fieldName, fieldDef.Type, value)
+			if fieldErr != nil {
+				c.Printf("  <yellow>WARNING:</> %s\n", fieldErr)
+				continue
+			}
+			fields = append(fields, field)
+		}
+		labels := make([]string, 0, len(pr.AssociatedLabels))
+		for l := range pr.AssociatedLabels {
+			labels = append(labels, l)
+		}
+		fields = withFields(fields, labelItemFields(ctx, f, p, labelFields, labels))
+		fields = withFields(fields, rs.Set)
+
+		// added and updated in batches, the pr is marked done in the checkpoint once written
+		label := fmt.Sprintf("%s#%d %s", pr.Repository, pr.Number, pr.Title)
+		if f.DryRun {
+			if err = pl.Item(ctx, prNode, label, fields); err != nil {
+				return err
+			}
+		} else if err = w.Write(itemWrite{NodeID: prNode, Fields: fields, Done: syncDone(ctx, cp, label, prNode)}); err != nil {
+			return err
 		}
 
-		// output
-		for k := range byStatus { // todo sort? format as table? https://github.com/jedib0t/go-pretty
-			c.Printf("<cyan>%s</><gray>x%d -</> %s\n", k, len(byStatus[k]), strings.Trim(strings.ReplaceAll(fmt.Sprint(byStatus[k]), " ", ","), "[]"))
+		// Sync fields to and from linked issues if configured
+		if ls != nil {
+			if err = syncLinkedIssues(ctx, f, p, ls, pl, w, repos, pr, fields); err != nil {
+				return err
+			}
 		}
+
 		c.Printf("\n")
+
+		// TODO remove closed PRs? move them to closed status?
 	}
-	return nil
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	// output
+	for k := range byStatus { // todo sort? format as table? https://github.com/jedib0t/go-pretty
+		c.Printf("<cyan>%s</><gray>x%d -</> %s\n", k, len(byStatus[k]), strings.Trim(strings.ReplaceAll(fmt.Sprint(byStatus[k]), " ", ","), "[]"))
+	}
+	c.Printf("\n")
+
+	return removeUnmatched(ctx, f, p, pl, unmatched)
 }
 
-func FilterByFlags(f FlagData, prs *[]gh.PullRequest) *[]gh.PullRequest {
-	if len(f.Filters.Authors) == 0 && len(f.Filters.Assignees) == 0 {
-		return prs
+// cachedRepo returns the repo for owner/name, creating it the first time it is seen
+func cachedRepo(f FlagData, repos map[string]*gh.Repo, repo string) (*gh.Repo, error) {
+	if r, ok := repos[repo]; ok {
+		return r, nil
 	}
 
-	c.Printf(" filtering by authors: <yellow>%s:</>\n", f.Filters.Authors)
-	c.Printf(" filtering by assignees: <yellow>%s:</>\n", f.Filters.Assignees)
+	r, err := gh.NewRepo(repo, f.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("creating repo %s: %w", repo, err)
+	}
+	repos[repo] = r
+
+	return r, nil
+}
 
-	// map of users
-	authorMap := map[string]bool{}
-	for _, u := range f.Filters.Authors {
-		authorMap[u] = true
+// getPREvents fetches the timeline events of a pr
+func getPREvents(ctx context.Context, f FlagData, repos map[string]*gh.Repo, pr gh.PullRequest) ([]gh.TimelineEvent, error) {
+	r, err := cachedRepo(f, repos, pr.Repository)
+	if err != nil {
+		return nil, err
 	}
 
-	assigneeUserMap := map[string]bool{}
-	for _, u := range f.Filters.Assignees {
-		assigneeUserMap[u] = true
+	events, err := r.GetAllIssueEvents(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("getting events for PR %d: %w", pr.Number, err)
 	}
 
-	var filteredPRs []gh.PullRequest
-	for _, pr := range *prs {
-		add := false
+	return *events, nil
+}
 
-		if pr.AssociatedProjectNumbers[f.ProjectNumber] {
-			add = true
-		}
+// getPRTimeline fetches the timeline events of a pr and works out who it is waiting on
+func getPRTimeline(ctx context.Context, f FlagData, repos map[string]*gh.Repo, pr gh.PullRequest) (*gh.TimelineStats, error) {
+	events, err := getPREvents(ctx, f, repos, pr)
+	if err != nil {
+		return nil, err
+	}
 
-		if !add && authorMap[pr.Author] {
-			add = true
-		}
+	stats := gh.AnalyzeTimeline(pr.Author, pr.CreatedAt, pr.ClosedAt, events, gh.TimelineOptions{
+		WaitingLabels:    f.WaitingLabels,
+		BlockedMilestone: blockedMilestone,
+	})
 
-		if !add {
-			for _, a := range pr.Assignees {
-				if assigneeUserMap[a] {
-					add = true
-				}
-			}
+	return &stats, nil
+}
+
+func hasAnyLabel(pr gh.PullRequest, labels []string) bool {
+	for _, l := range labels {
+		if pr.AssociatedLabels[l] {
+			return true
 		}
+	}
+
+	return false
+}
 
-		if add {
+// FilterByProject only keeps PRs that are in the project and match the project status/populated field filters.
+func FilterByProject(pf *ProjectFilter, prs *[]gh.PullRequest) *[]gh.PullRequest {
+	var filteredPRs []gh.PullRequest
+	for _, pr := range *prs {
+		if pf.Match(pr.NodeID) {
 			filteredPRs = append(filteredPRs, pr)
 		}
 	}
 
-	sort.Slice(filteredPRs, func(i, j int) bool {
-		return filteredPRs[i].Number < filteredPRs[j].Number
-	})
-
-	c.Printf("  Found <lightBlue>%d</> filtered PRs: ", len(filteredPRs))
+	c.Printf("  Found <lightBlue>%d</> PRs matching project filters: ", len(filteredPRs))
 	for _, pr := range filteredPRs {
 		c.Printf("<white>%d</>,", pr.Number)
 	}
@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	c "github.com/gookit/color"
 	"github.com/katbyte/ghp-sync/lib/gh"
 )
 
@@ -14,13 +16,16 @@ type PRFieldContext struct {
 	Project     gh.Project
 	DaysOpen    int
 	DaysWaiting int
-	Status      string // The computed status text (e.g., "In Progress", "Approved")
+	Status      string            // The computed status text (e.g., "In Progress", "Approved")
+	Timeline    *gh.TimelineStats // nil unless a field, status rule or the waiting status needed it
 }
 
 // PRFieldDef defines a field that can be populated on a GitHub Project item
 type PRFieldDef struct {
-	Type      gh.ItemValueType // Field type for GraphQL mutation
-	ComputeFn func(ctx PRFieldContext) any
+	Type          gh.ItemValueType // Field type for GraphQL mutation, text fields can also be single selects
+	NeedsTimeline bool             // ComputeFn uses ctx.Timeline
+	Optional      bool             // added to the defaults later, skipped when missing from the project unless populated explicitly
+	ComputeFn     func(ctx PRFieldContext) any
 }
 
 // PRFields is the registry of all available PR fields, keyed by field name (matches GitHub Project field name)
@@ -111,4 +116,156 @@ var PRFields = map[string]PRFieldDef{
 			return ctx.PR.FilteredReviewCommentCount
 		},
 	},
+	"Repo": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return repoFieldValue(ctx.PR.Repository)
+		},
+	},
+	"Kind": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return KindPullRequest
+		},
+	},
+	"URL": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			if ctx.PR.URL == "" {
+				return nil
+			}
+			return ctx.PR.URL
+		},
+	},
+	"Author Type": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return authorType(ctx.PR.AuthorAssociation)
+		},
+	},
+	"Author Association": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			if ctx.PR.AuthorAssociation == "" {
+				return nil
+			}
+			return ctx.PR.AuthorAssociation
+		},
+	},
+	"First-time Contributor": {
+		Type:     gh.ItemValueTypeText,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			if gh.IsFirstTimeAssociation(ctx.PR.AuthorAssociation) {
+				return "Yes"
+			}
+			return "No"
+		},
+	},
+	"Maintainer Review Count": {
+		Type:     gh.ItemValueTypeNumber,
+		Optional: true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return ctx.PR.MaintainerReviewCount
+		},
+	},
+	"Maintainer Waiting Days": {
+		Type:          gh.ItemValueTypeNumber,
+		NeedsTimeline: true,
+		Optional:      true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return ctx.Timeline.MaintainerWaitingDays
+		},
+	},
+	"Author Waiting Days": {
+		Type:          gh.ItemValueTypeNumber,
+		NeedsTimeline: true,
+		Optional:      true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return ctx.Timeline.AuthorWaitingDays
+		},
+	},
+	"Ball In Court": {
+		Type:          gh.ItemValueTypeText,
+		NeedsTimeline: true,
+		Optional:      true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return ctx.Timeline.BallInCourt
+		},
+	},
+	"Days Since Maintainer Review": {
+		Type:          gh.ItemValueTypeNumber,
+		NeedsTimeline: true,
+		Optional:      true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			if ctx.Timeline.LastMaintainerReview.IsZero() {
+				return nil // never reviewed
+			}
+			return ctx.Timeline.DaysSinceMaintainerReview()
+		},
+	},
+	"Days Since Author Activity": {
+		Type:          gh.ItemValueTypeNumber,
+		NeedsTimeline: true,
+		Optional:      true,
+		ComputeFn: func(ctx PRFieldContext) any {
+			return ctx.Timeline.DaysSinceAuthorActivity()
+		},
+	},
+}
+
+// authorType classifies an author association as a Maintainer or Community author
+func authorType(association string) string {
+	if gh.IsMaintainerAssociation(association) {
+		return "Maintainer"
+	}
+
+	return "Community"
+}
+
+// projectField builds the update for a computed field value, text values for single select project
+// fields are mapped to the option with the same name which is created with --create-missing-options.
+func projectField(ctx context.Context, f FlagData, p gh.Project, fieldName string, t gh.ItemValueType, value any) (gh.ProjectItemField, error) {
+	if t == gh.ItemValueTypeText && p.FieldTypes[fieldName] == gh.ItemValueTypeSingleSelect {
+		name := fmt.Sprint(value)
+		optionID, ok := selectOptionID(p, fieldName, name)
+		if !ok && f.CreateMissingOptions && !f.DryRun {
+			c.Printf("  creating option <yellow>%s</> for <lightBlue>%s</>\n", name, fieldName)
+			if err := p.AddSingleSelectOptions(ctx, fieldName, []string{name}); err != nil {
+				return gh.ProjectItemField{}, err
+			}
+			optionID, ok = selectOptionID(p, fieldName, name)
+		}
+		if !ok {
+			return gh.ProjectItemField{}, fmt.Errorf("field %q has no option %q", fieldName, value)
+		}
+		t, value = gh.ItemValueTypeSingleSelect, optionID
+	}
+
+	return gh.ProjectItemField{
+		Name:    strings.ToLower(strings.NewReplacer(" ", "_", "#", "").Replace(fieldName)),
+		FieldID: p.FieldIDs[fieldName],
+		Type:    t,
+		Value:   value,
+	}, nil
+}
+
+// selectOptionID finds a single select option by name, ignoring case
+func selectOptionID(p gh.Project, fieldName, name string) (string, bool) {
+	if id, ok := p.SingleSelectOptionIDs[fieldName][name]; ok {
+		return id, true
+	}
+
+	for optionName, id := range p.SingleSelectOptionIDs[fieldName] {
+		if strings.EqualFold(optionName, name) {
+			return id, true
+		}
+	}
+
+	return "", false
 }
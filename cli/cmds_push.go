@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func CmdPush(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	f := GetFlags()
+	p := gh.NewProject(f.ProjectOwner, f.ProjectNumber, f.Auth)
+
+	c.Printf("Looking up project details for <green>%s</>/<lightGreen>%d</>...\n", f.ProjectOwner, f.ProjectNumber)
+	if err := p.LoadDetails(ctx); err != nil {
+		return fmt.Errorf("loading project details: %w", err)
+	}
+	c.Printf("  ID: <magenta>%s</>\n\n", p.ID)
+
+	mappings, err := f.GetPushMappings(p)
+	if err != nil {
+		return err
+	}
+
+	state, err := LoadPushState(f.PushStateFile)
+	if err != nil {
+		return err
+	}
+
+	c.Printf("<white>Configuration:</>\n")
+	for _, m := range mappings {
+		c.Printf("  <lightBlue>mapping</>: <magenta>%s</>\n", m)
+	}
+	c.Printf("  <lightBlue>state</>:   <cyan>%s</>\n", f.PushStateFile)
+	if f.DryRun {
+		c.Printf("  <lightBlue>dry run</>: <yellow>yes</>\n")
+	}
+	fmt.Println()
+
+	var fieldNames []string
+	seen := map[string]bool{}
+	for _, m := range mappings {
+		if !seen[m.Field] {
+			seen[m.Field] = true
+			fieldNames = append(fieldNames, m.Field)
+		}
+	}
+
+	c.Printf("Getting project items...")
+	items, err := p.GetItemsFieldValues(ctx, fieldNames)
+	if err != nil {
+		return fmt.Errorf("getting project items: %w", err)
+	}
+	c.Printf(" <yellow>%d</>\n\n", len(items))
+
+	repos := map[string]*gh.Repo{}
+	pushed, conflicts, failed := 0, 0, 0
+	pushedItems := 0 // issues and prs with a change pushed, for --item-limit
+
+	for _, item := range items {
+		if err := interrupted(ctx, "%d pushed, %d conflicts and %d failed", pushed, conflicts, failed); err != nil {
+			return err
+		}
+		if item.Type != "ISSUE" && item.Type != "PULL_REQUEST" {
+			continue
+		}
+		if f.ItemLimit > 0 && pushedItems >= f.ItemLimit {
+			break
+		}
+
+		// only look the issue/pr up when a mapped field changed since the last push
+		var changed []PushMapping
+		for _, m := range mappings {
+			last, ok := state.Get(item.NodeID, m)
+			if !ok || last.Field != fieldValueText(p, m.Field, item) {
+				changed = append(changed, m)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		owner, name, _, number, err := gh.ParseGitHubURL(item.URL)
+		if err != nil {
+			c.Printf("<red>ERROR!</> %s: %s\n", item.URL, err)
+			failed++
+			continue
+		}
+
+		repo := owner + "/" + name
+		r, ok := repos[repo]
+		if !ok {
+			if r, err = gh.NewRepo(repo, f.Auth); err != nil {
+				return fmt.Errorf("creating repo %s: %w", repo, err)
+			}
+			repos[repo] = r
+		}
+
+		issue, err := r.GetIssue(ctx, number)
+		if err != nil {
+			c.Printf("<red>ERROR!</> %s\n", err)
+			failed++
+			continue
+		}
+
+		ctx := itemContext(ctx, repo, number, item.NodeID)
+		c.Printf("<white>%s</>#<lightCyan>%d</> - %s\n", repo, number, issue.GetTitle())
+		itemPushed := false
+		for _, m := range changed {
+			value := fieldValueText(p, m.Field, item)
+			current := m.Current(issue)
+
+			desired, ok := m.Desired(value, current)
+			if !ok {
+				c.Printf("  <gray>%s: field is empty</>\n", m)
+				if !f.DryRun {
+					state.Set(item.NodeID, m, PushStateEntry{Field: value, GitHub: current})
+				}
+				continue
+			}
+
+			last, hasLast := state.Get(item.NodeID, m)
+			switch {
+			case samePushValue(current, desired):
+				c.Printf("  <gray>%s: in sync</>\n", m)
+			case hasLast && last.Conflicts(current):
+				c.Printf("  <red>CONFLICT</> %s: project changed <yellow>%s</> -> <yellow>%s</> and github changed <yellow>%s</> -> <yellow>%s</>, skipping\n", m, last.Field, value, pushValueText(last.GitHub), pushValueText(current))
+				conflicts++
+				continue
+			case f.DryRun:
+				c.Printf("  <yellow>[dry-run]</> %s: <yellow>%s</> -> <green>%s</>\n", m, pushValueText(current), pushValueText(desired))
+				itemPushed = true
+				continue
+			default:
+				c.Printf("  %s: <yellow>%s</> -> <green>%s</>.. ", m, pushValueText(current), pushValueText(desired))
+				err := m.Apply(ctx, r, number, current, desired)
+				logItem(clog.WithFields(ctx, logrus.Fields{"mapping": m.String()}), "push", &item.ID, err)
+				if err != nil {
+					c.Printf("<red>ERROR!</> %s\n", err)
+					failed++
+					continue
+				}
+				c.Printf("<green>✓</>\n")
+				pushed++
+				itemPushed = true
+			}
+
+			if !f.DryRun {
+				state.Set(item.NodeID, m, PushStateEntry{Field: value, GitHub: desired})
+			}
+		}
+		if itemPushed {
+			pushedItems++
+		}
+
+		// saved after each item so an interrupted or failed run doesn't push again or report its own
+		// changes as conflicts
+		if !f.DryRun {
+			if err := state.Save(f.PushStateFile); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Printf("\npushed <green>%d</>, conflicts <red>%d</>, failed <red>%d</>\n", pushed, conflicts, failed)
+	return nil
+}
+
+// fieldValueText returns a project item's field value as text, single selects as the option name
+func fieldValueText(p gh.Project, fieldName string, item gh.ProjectItemValues) string {
+	v, ok := item.Values[fieldName]
+	if !ok || v.Value == nil {
+		return ""
+	}
+
+	switch v.Type {
+	case gh.ItemValueTypeSingleSelect:
+		return p.SingleSelectOptionNames[fieldName][fmt.Sprint(v.Value)]
+	case gh.ItemValueTypeNumber:
+		if n, ok := v.Value.(float64); ok {
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	}
+
+	return fmt.Sprint(v.Value)
+}
@@ -19,15 +19,33 @@ func humanReset(reset int) string {
 	return d.Round(time.Second).String()
 }
 
-func CmdRateLimit(_ *cobra.Command, _ []string) error {
+func CmdRateLimit(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
 	f := GetFlags()
-	r, err := gh.GetRateLimit(context.Background(), f.Token)
+	c.Printf("GitHub rate limits (local now: <lightCyan>%s</>):\n", time.Now().Format(time.RFC3339))
+
+	if f.Auth.Pool == nil {
+		return printRateLimits(ctx, f.Auth)
+	}
+
+	// each token in the pool has its own limits
+	names, tokens := f.Auth.Pool.Tokens()
+	for i, t := range tokens {
+		c.Printf("<white>%s</>:\n", names[i])
+		if err := printRateLimits(ctx, t); err != nil {
+			return fmt.Errorf("%s: %w", names[i], err)
+		}
+	}
+
+	return nil
+}
+
+func printRateLimits(ctx context.Context, t gh.Token) error {
+	r, err := gh.GetRateLimit(ctx, t)
 	if err != nil {
 		return fmt.Errorf("unable to get rate limits: %w", err)
 	}
 
-	c.Printf("GitHub rate limits (local now: <lightCyan>%s</>):\n", time.Now().Format(time.RFC3339))
-
 	type named struct {
 		name string
 		rate gh.Rate
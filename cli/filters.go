@@ -1,141 +1,311 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v89/github"
 	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/filter"
+	"github.com/katbyte/ghp-sync/lib/gh"
 )
 
-type Filter struct {
-	Name  string
-	Issue func(options github.Issue) (bool, error)
-}
+// GetFilter combines the --filter expression with the shorthand filter flags, returns nil when there is
+// nothing to filter on. When keepProjectItems is set items already in the project pass the author and
+// assignee filters so they continue to be updated.
+func (f FlagData) GetFilter(keepProjectItems bool) (filter.Expr, error) {
+	var users []filter.Expr
+	if len(f.Filters.Authors) > 0 {
+		users = append(users, filter.Compare("author", filter.OpIn, f.Filters.Authors...))
+	}
+	if len(f.Filters.Assignees) > 0 {
+		users = append(users, filter.Compare("assignee", filter.OpIn, f.Filters.Assignees...))
+	}
+	if len(users) > 0 && keepProjectItems {
+		users = append(users, filter.Compare("project", filter.OpEq, strconv.Itoa(f.ProjectNumber)))
+	}
 
-func (f FlagData) GetFilters() []Filter {
-	var filters []Filter
+	return f.withFilterExpression(
+		filter.Or(users...),
+		GetFilterForLabels(f.Filters.LabelsOr, false),
+		GetFilterForLabels(f.Filters.LabelsAnd, true),
+	)
+}
 
-	// should these return errors
-	if f := GetFilterForAuthors(f.Filters.Authors); f != nil {
-		filters = append(filters, *f)
+// GetIssueFilter combines the --filter expression with the shorthand filter flags for issues, which have
+// always synced an issue when any of the shorthand flags match it. Returns nil when there is nothing to
+// filter on.
+func (f FlagData) GetIssueFilter() (filter.Expr, error) {
+	var shorthand []filter.Expr
+	if len(f.Filters.Authors) > 0 {
+		shorthand = append(shorthand, filter.Compare("author", filter.OpIn, f.Filters.Authors...))
+	}
+	if len(f.Filters.Assignees) > 0 {
+		shorthand = append(shorthand, filter.Compare("assignee", filter.OpIn, f.Filters.Assignees...))
 	}
+	shorthand = append(shorthand,
+		GetFilterForLabels(f.Filters.LabelsOr, false),
+		GetFilterForLabels(f.Filters.LabelsAnd, true),
+	)
+
+	return f.withFilterExpression(filter.Or(shorthand...))
+}
 
-	if f := GetFilterForLabelsOr(f.Filters.LabelsOr); f != nil {
-		filters = append(filters, *f)
+// withFilterExpression returns the --filter expression and exprs when all of them match
+func (f FlagData) withFilterExpression(exprs ...filter.Expr) (filter.Expr, error) {
+	if f.Filters.Expression != "" {
+		e, err := filter.Parse(f.Filters.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("parsing filter %q: %w", f.Filters.Expression, err)
+		}
+		exprs = append(exprs, e)
 	}
 
-	if f := GetFilterForLabelsAnd(f.Filters.LabelsAnd); f != nil {
-		filters = append(filters, *f)
+	return filter.And(exprs...), nil
+}
+
+// GetFilterForLabels matches any (or all when and is set) of the labels, labels prefixed with `-` must
+// not be present.
+func GetFilterForLabels(labels []string, and bool) filter.Expr {
+	exprs := make([]filter.Expr, 0, len(labels))
+	for _, l := range labels {
+		if name, negate := strings.CutPrefix(l, "-"); negate {
+			exprs = append(exprs, filter.Not(filter.Compare("label", filter.OpEq, name)))
+		} else {
+			exprs = append(exprs, filter.Compare("label", filter.OpEq, l))
+		}
 	}
 
-	fmt.Println()
+	if and {
+		return filter.And(exprs...)
+	}
 
-	return filters
+	return filter.Or(exprs...)
 }
 
-func GetFilterForLabelsOr(labels []string) *Filter {
-	return GetFilterForLabels(labels, false)
+// PRFilterFields returns the fields filter expressions can use for a PR.
+func PRFilterFields(pr gh.PullRequest) filter.Fields {
+	labels := make([]string, 0, len(pr.AssociatedLabels))
+	for l := range pr.AssociatedLabels {
+		labels = append(labels, l)
+	}
+
+	projects := make([]string, 0, len(pr.AssociatedProjectNumbers))
+	for n := range pr.AssociatedProjectNumbers {
+		projects = append(projects, strconv.Itoa(n))
+	}
+
+	return filter.Fields{
+		"kind":            "pr",
+		"number":          float64(pr.Number),
+		"title":           pr.Title,
+		"author":          pr.Author,
+		"assignee":        pr.Assignees,
+		"label":           labels,
+		"state":           strings.ToLower(pr.State),
+		"draft":           pr.Draft,
+		"milestone":       pr.Milestone,
+		"age":             daysSince(pr.CreatedAt),
+		"updated":         daysSince(pr.UpdatedAt),
+		"comments":        float64(pr.TotalCommentCount),
+		"reviews":         float64(pr.TotalReviewCount),
+		"review-decision": strings.ToLower(pr.ReviewDecision),
+		"project":         projects,
+
+		"author-association":     strings.ToLower(pr.AuthorAssociation),
+		"author-type":            strings.ToLower(authorType(pr.AuthorAssociation)),
+		"first-time-contributor": gh.IsFirstTimeAssociation(pr.AuthorAssociation),
+		"maintainer-reviews":     float64(pr.MaintainerReviewCount),
+	}
 }
 
-func GetFilterForLabelsAnd(labels []string) *Filter {
-	return GetFilterForLabels(labels, true)
+// IssueFilterFields returns the fields filter expressions can use for an issue. The REST API doesn't list
+// an issue's projects so project is only the numbers of those it is known to be in.
+func IssueFilterFields(issue github.Issue, projects []string) filter.Fields {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, l := range issue.Labels {
+		labels = append(labels, l.GetName())
+	}
+
+	assignees := make([]string, 0, len(issue.Assignees))
+	for _, a := range issue.Assignees {
+		assignees = append(assignees, a.GetLogin())
+	}
+
+	return filter.Fields{
+		"kind":            "issue",
+		"number":          float64(issue.GetNumber()),
+		"title":           issue.GetTitle(),
+		"author":          issue.User.GetLogin(),
+		"assignee":        assignees,
+		"label":           labels,
+		"state":           strings.ToLower(issue.GetState()),
+		"draft":           false,
+		"milestone":       issue.Milestone.GetTitle(),
+		"age":             daysSince(issue.GetCreatedAt().Time),
+		"updated":         daysSince(issue.GetUpdatedAt().Time),
+		"comments":        float64(issue.GetComments()),
+		"reviews":         float64(0),
+		"review-decision": "",
+		"project":         projects,
+
+		"author-association":     strings.ToLower(issue.GetAuthorAssociation()),
+		"author-type":            strings.ToLower(authorType(issue.GetAuthorAssociation())),
+		"first-time-contributor": gh.IsFirstTimeAssociation(issue.GetAuthorAssociation()),
+		"maintainer-reviews":     float64(0),
+	}
 }
 
-func GetFilterForLabels(labels []string, and bool) *Filter {
-	if len(labels) == 0 {
-		return nil
+// projectContent returns the node IDs of the issues and PRs in the project, only read when the filter
+// looks at project as it means paging through every item
+func projectContent(ctx context.Context, p gh.Project, expr filter.Expr) (map[string]bool, error) {
+	if expr == nil || !filter.Uses(expr, "project") {
+		return nil, nil
 	}
 
-	filterLabelMap := map[string]bool{}
-	for _, l := range labels {
-		filterLabelMap[strings.TrimPrefix(l, "-")] = strings.HasPrefix(l, "-")
+	content := map[string]bool{}
+	err := p.ListItemFieldValues(ctx, nil, func(item gh.ProjectItemValues) bool {
+		content[item.NodeID] = true
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing project items: %w", err)
 	}
 
-	action := "or"
-	actionAnd := false
-	if and {
-		action = "and"
-		actionAnd = true
-	}
-
-	c.Printf("  labels %s:  <blue>%s</>\n", action, strings.Join(labels, "</>,<blue>"))
-
-	return &Filter{
-		Name: "labels " + action,
-		Issue: func(issue github.Issue) (bool, error) {
-			labelMap := map[string]bool{}
-			for _, l := range issue.Labels {
-				// todo check for emvy label name?
-				labelMap[l.GetName()] = true // casing?
-			}
-
-			// and
-			// for each label,
-
-			if actionAnd {
-				c.Printf("    labels all: ")
-			} else {
-				c.Printf("    labels any: ")
-			}
-
-			andFail := false
-			orPass := false
-
-			// for each filter label see if it exists
-			for filterLabel, negate := range filterLabelMap {
-				_, found := labelMap[filterLabel]
-
-				//nolint:gocritic
-				if found && !negate {
-					orPass = true
-					c.Printf(" <green>%s</>", filterLabel)
-				} else if found && negate {
-					andFail = true
-					c.Printf(" <red>-%s</>", filterLabel)
-				} else if negate {
-					orPass = true
-					c.Printf(" <green>-%s</>", filterLabel)
-				} else {
-					andFail = true
-					c.Printf(" <red>%s</>", filterLabel)
-				}
-			}
-			fmt.Println()
-
-			if actionAnd {
-				return !andFail, nil
-			}
-
-			return orPass, nil
-		},
+	return content, nil
+}
+
+func daysSince(t time.Time) float64 {
+	return time.Since(t).Hours() / 24
+}
+
+// FilterPRs returns the PRs matching the filter expression sorted by number.
+func FilterPRs(expr filter.Expr, prs *[]gh.PullRequest) (*[]gh.PullRequest, error) {
+	var filteredPRs []gh.PullRequest
+	for _, pr := range *prs {
+		match, err := expr.Eval(PRFilterFields(pr))
+		if err != nil {
+			return nil, fmt.Errorf("filtering pr %d: %w", pr.Number, err)
+		}
+
+		if match {
+			filteredPRs = append(filteredPRs, pr)
+		}
 	}
+
+	sort.Slice(filteredPRs, func(i, j int) bool {
+		return filteredPRs[i].Number < filteredPRs[j].Number
+	})
+
+	c.Printf("  Found <lightBlue>%d</> filtered PRs: ", len(filteredPRs))
+	for _, pr := range filteredPRs {
+		c.Printf("<white>%d</>,", pr.Number)
+	}
+	c.Printf("\n\n")
+
+	return &filteredPRs, nil
+}
+
+// ProjectFilter matches issues and PRs by their current values in a project, so it requires the
+// items to already be on the board.
+type ProjectFilter struct {
+	StatusIs        string
+	StatusField     string
+	FieldsPopulated []string
+
+	statusNames map[string]string               // status option ID -> name
+	items       map[string]gh.ProjectItemValues // content node ID -> item
+}
+
+func (f FlagData) GetProjectFilter(ctx context.Context, p gh.Project) (*ProjectFilter, error) {
+	return GetProjectFilter(ctx, p, f.Filters.ProjectStatusField, f.Filters.ProjectStatusIs, f.Filters.ProjectFieldPopulated)
 }
 
-func GetFilterForAuthors(authors []string) *Filter {
-	if len(authors) == 0 {
-		return nil
+// GetProjectFilter reads the current status and populated fields of every item in the project,
+// returns nil if neither filter is set. The status is matched against the options of statusField
+// ignoring case, as it is by Match.
+func GetProjectFilter(ctx context.Context, p gh.Project, statusField, statusIs string, populated []string) (*ProjectFilter, error) {
+	if statusIs == "" && len(populated) == 0 {
+		return nil, nil
 	}
 
-	authorMap := map[string]bool{}
-	for _, a := range authors {
-		authorMap[a] = true
+	fieldNames := append([]string{}, populated...)
+	if statusIs != "" {
+		if statusField == "" {
+			statusField = "Status"
+		}
+		if _, ok := p.FieldIDs[statusField]; !ok {
+			return nil, fmt.Errorf("project field %q not found in project", statusField)
+		}
+		if p.FieldTypes[statusField] != gh.ItemValueTypeSingleSelect {
+			return nil, fmt.Errorf("project field %q isn't a single select", statusField)
+		}
+		optionID, ok := selectOptionID(p, statusField, statusIs)
+		if !ok {
+			return nil, fmt.Errorf("project status %q not found in %s", statusIs, statusField)
+		}
+		statusIs = p.SingleSelectOptionNames[statusField][optionID]
+		fieldNames = append(fieldNames, statusField)
+		c.Printf("  project %s is: <blue>%s</>\n", strings.ToLower(statusField), statusIs)
+	}
+	for _, name := range populated {
+		if _, ok := p.FieldIDs[name]; !ok {
+			return nil, fmt.Errorf("project field %q not found in project", name)
+		}
+	}
+	if len(populated) > 0 {
+		c.Printf("  project fields populated: <blue>%s</>\n", strings.Join(populated, "</>,<blue>"))
 	}
 
-	c.Printf("  authors: <magenta>%s</>\n", strings.Join(authors, "</>,<magenta>"))
+	items, err := p.GetItemsFieldValues(ctx, fieldNames)
+	if err != nil {
+		return nil, fmt.Errorf("reading project item values: %w", err)
+	}
 
-	return &Filter{
-		Name: "authors",
-		Issue: func(issue github.Issue) (bool, error) {
-			author := issue.User.GetLogin()
+	pf := ProjectFilter{
+		StatusIs:        statusIs,
+		StatusField:     statusField,
+		FieldsPopulated: populated,
+		statusNames:     p.SingleSelectOptionNames[statusField],
+		items:           map[string]gh.ProjectItemValues{},
+	}
+	for _, item := range items {
+		pf.items[item.NodeID] = item
+	}
 
-			if _, ok := authorMap[author]; ok {
-				c.Printf("    author: <green>%s</>\n", author)
-				return true, nil
-			}
-			c.Printf("    author: <red>%s</>\n", author)
+	return &pf, nil
+}
 
-			return false, nil
-		},
+// Match returns true if the issue/PR with the given node ID is in the project with the required
+// status and all required fields populated.
+func (pf ProjectFilter) Match(nodeID string) bool {
+	item, ok := pf.items[nodeID]
+	if !ok {
+		return false
 	}
+
+	if pf.StatusIs != "" {
+		status, ok := item.Values[pf.StatusField]
+		if !ok {
+			return false
+		}
+
+		optionID, _ := status.Value.(string)
+		if !strings.EqualFold(pf.statusNames[optionID], pf.StatusIs) {
+			return false
+		}
+	}
+
+	for _, name := range pf.FieldsPopulated {
+		v, ok := item.Values[name]
+		if !ok || v.Value == "" {
+			return false
+		}
+	}
+
+	return true
 }
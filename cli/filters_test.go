@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+func statusProject() gh.Project {
+	return gh.Project{ProjectDetails: &gh.ProjectDetails{
+		FieldIDs:   map[string]string{"Stage": "F_STAGE", "Notes": "F_NOTES"},
+		FieldTypes: map[string]gh.ItemValueType{"Stage": gh.ItemValueTypeSingleSelect},
+		SingleSelectOptionIDs: map[string]map[string]string{
+			"Stage": {"In Progress": "OPT_PROGRESS"},
+		},
+		SingleSelectOptionNames: map[string]map[string]string{
+			"Stage": {"OPT_PROGRESS": "In Progress"},
+		},
+	}}
+}
+
+func TestGetProjectFilterStatusErrors(t *testing.T) {
+	t.Parallel()
+
+	p := statusProject()
+	for name, tc := range map[string]struct{ field, status string }{
+		"missing field":     {"Status", "In Progress"},
+		"not single select": {"Notes", "In Progress"},
+		"missing option":    {"Stage", "Done"},
+	} {
+		if _, err := GetProjectFilter(t.Context(), p, tc.field, tc.status, nil); err == nil {
+			t.Errorf("%s: GetProjectFilter(%q, %q) didn't fail", name, tc.field, tc.status)
+		}
+	}
+}
+
+func TestProjectFilterMatchStatus(t *testing.T) {
+	t.Parallel()
+
+	pf := ProjectFilter{
+		StatusIs:    "in progress",
+		StatusField: "Stage",
+		statusNames: statusProject().SingleSelectOptionNames["Stage"],
+		items: map[string]gh.ProjectItemValues{
+			"PR_progress": {Values: map[string]gh.ProjectItemFieldValue{"Stage": {Type: gh.ItemValueTypeSingleSelect, Value: "OPT_PROGRESS"}}},
+			"PR_status":   {Values: map[string]gh.ProjectItemFieldValue{"Status": {Type: gh.ItemValueTypeSingleSelect, Value: "OPT_PROGRESS"}}},
+			"PR_none":     {Values: map[string]gh.ProjectItemFieldValue{}},
+		},
+	}
+
+	for nodeID, want := range map[string]bool{"PR_progress": true, "PR_status": false, "PR_none": false, "PR_missing": false} {
+		if got := pf.Match(nodeID); got != want {
+			t.Errorf("Match(%s) = %t, want %t", nodeID, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/katbyte/ghp-sync/lib/chttp"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/spf13/viper"
+)
+
+// loadFixtures records the run's requests to --record, or replays them from --replay
+func loadFixtures() error {
+	record, replay := viper.GetString("record"), viper.GetString("replay")
+	switch {
+	case record != "" && replay != "":
+		return errors.New("only one of --record and --replay can be used")
+	case record != "":
+		f, err := chttp.NewRecorder(record)
+		if err != nil {
+			return err
+		}
+		gh.SetFixtures(f)
+	case replay != "":
+		f, err := chttp.NewReplayer(replay, viper.GetBool("replay-repeat"))
+		if err != nil {
+			return err
+		}
+		gh.SetFixtures(f)
+
+		// nothing is sent so any token will do, recordings don't contain the one they were made with. An app
+		// would still exchange its key for an installation token so is replaced with one.
+		viper.Set("app-id", int64(0))
+		if viper.GetString("token") == "" && len(GetStringSliceFixed("tokens")) == 0 {
+			viper.Set("token", "replay")
+		}
+	}
+
+	return nil
+}
@@ -4,19 +4,62 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/katbyte/ghp-sync/lib/gh"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 type FlagData struct {
-	Token         string
-	Repos         []string
+	Config string
+
+	GitHubHost string // github enterprise server host, empty for github.com
+	Token      string
+	Tokens     []string // extra tokens pooled with Token to spread the rate limit load
+	CacheDir   string   // response cache, the user cache dir when empty
+	NoCache    bool
+
+	CacheMaxAge  time.Duration // cached responses not used for this long are pruned, 0 for no limit
+	CacheMaxSize int           // MiB, least recently used responses are pruned past this, 0 for no limit
+
+	MaxRetryWait time.Duration // retries give up rather than wait longer than this for a request
+	Timeout      time.Duration // the whole run is cancelled after this, 0 for no limit
+	LogFormat    string        // text or json
+	Record       string        // directory to record requests and responses to
+	Replay       string        // directory to replay recorded responses from instead of calling github
+	ReplayRepeat bool          // replay the last recorded response again once a request's run out
+
+	// github app auth, used instead of the token when AppID is set
+	AppID             int64
+	AppInstallationID int64
+	AppPrivateKey     string
+	Auth              gh.Token // credentials for the github apis from Token or the app
+
+	Repos  []string
+	Search string
+
+	ExcludeRepos    []string
+	IncludeArchived bool
+
 	ProjectOwner  string
 	ProjectNumber int
 	ItemLimit     int
 	DryRun        bool
-	Filters       Filters
+	PlanFile      string // dry run plan written by prs/issues and read by apply
+
+	// remove project items that no longer match the filters
+	RemoveUnmatched bool
+
+	// batched project mutations
+	BatchSize      int
+	BatchMutations int
+
+	// resuming failed runs
+	CheckpointFile string
+	Resume         bool
+
+	Filters Filters
 
 	// PR field population control
 	PRPopulateFields []string // Only populate these fields (empty = all)
@@ -25,6 +68,30 @@ type FlagData struct {
 
 	// Linked issue field syncing
 	SyncLinkedIssueFields []string // Copy these fields from linked issues
+	SyncPRFieldsToIssues  []string // Copy these fields from the pr to its linked issues
+	LinkedIssueStrategies []string // `strategy` or `Field=strategy` for prs with several linked issues
+	LinkedIssueSources    []string // closing, body and/or timeline
+
+	// PR status
+	WaitingLabels []string // labels that mean a PR is waiting on the author
+	StatusRules   []string // `Status=expression` rules checked before the built-in status logic
+
+	// project fields set from labels with a prefix, `prefix=Field`
+	LabelFields []string
+
+	// single select options for computed values are created when missing, ie a Repo option per repo
+	CreateMissingOptions bool
+
+	// add the sub-issues of issues in the project
+	AddSubIssues bool
+
+	// reverse sync of project fields to labels, milestones and assignees
+	PushMappings  []string
+	PushStateFile string
+
+	// per repo settings from the config file, see ForRepo
+	RepoOverrides []RepoOverride
+	Set           []string // fixed `Field=Value` fields from the repo overrides
 }
 
 type Filters struct {
@@ -35,7 +102,10 @@ type Filters struct {
 	LabelsAnd []string
 	States    []string
 
+	Expression string // filter expression combined with the above, see lib/filter
+
 	ProjectStatusIs       string
+	ProjectStatusField    string // the single select field --project-status-is matches
 	ProjectFieldPopulated []string
 }
 
@@ -43,20 +113,41 @@ func configureFlags(root *cobra.Command) error {
 	flags := FlagData{}
 	pflags := root.PersistentFlags()
 
+	pflags.StringVarP(&flags.Config, "config", "c", "", "yaml config file with flag values and per repo overrides (GHP_SYNC_CONFIG)")
 	pflags.StringVarP(&flags.Token, "token", "t", "", "github oauth token (GITHUB_TOKEN)")
-	pflags.StringSliceVarP(&flags.Repos, "repos", "r", []string{}, "github repo name (GITHUB_REPO) or a set of repos `owner1/repo1,owner2/repo2`")
+	pflags.StringVar(&flags.GitHubHost, "github-host", "", "github enterprise server hostname or url, ie 'github.example.com', defaults to github.com (GITHUB_HOST)")
+	pflags.StringVar(&flags.CacheDir, "cache-dir", "", "directory for the response cache that makes requests conditional so unchanged responses don't count against the rate limit, defaults to the user cache dir (GITHUB_CACHE_DIR)")
+	pflags.BoolVar(&flags.NoCache, "no-cache", false, "don't cache responses")
+	pflags.DurationVar(&flags.CacheMaxAge, "cache-max-age", 30*24*time.Hour, "prune cached responses not used for this long, 0 for no limit (GITHUB_CACHE_MAX_AGE)")
+	pflags.IntVar(&flags.CacheMaxSize, "cache-max-size", 512, "prune the least recently used cached responses when the cache is larger than this many MiB, 0 for no limit (GITHUB_CACHE_MAX_SIZE)")
+	pflags.StringVar(&flags.LogFormat, "log-format", "text", "log to stderr as text or json lines, json logs an event for each item synced and api call and defaults GHP_SYNC_LOG to INFO (GHP_SYNC_LOG_FORMAT)")
+	pflags.StringVar(&flags.Record, "record", "", "record sanitized requests and responses to this new directory so the run can be replayed offline (GHP_SYNC_RECORD)")
+	pflags.StringVar(&flags.Replay, "replay", "", "serve responses recorded with --record from this directory instead of calling github (GHP_SYNC_REPLAY)")
+	pflags.BoolVar(&flags.ReplayRepeat, "replay-repeat", false, "with --replay, serve the last recorded response again once a request has used all of its recordings instead of failing (GHP_SYNC_REPLAY_REPEAT)")
+	pflags.DurationVar(&flags.Timeout, "timeout", 0, "stop the run after this long, reporting how far it got (GHP_SYNC_TIMEOUT)")
+	pflags.DurationVar(&flags.MaxRetryWait, "max-retry-wait", gh.DefaultRetryPolicy.MaxTotalWait, "give up on a request rather than wait longer than this in total for retries and rate limit resets (GITHUB_MAX_RETRY_WAIT)")
+	pflags.StringSliceVar(&flags.Tokens, "tokens", []string{}, "extra github tokens, requests use whichever token has the most rate limit left and switch when one is rate limited (GITHUB_TOKENS)")
+	pflags.Int64Var(&flags.AppID, "app-id", 0, "authenticate as this github app instead of with a token (GITHUB_APP_ID)")
+	pflags.Int64Var(&flags.AppInstallationID, "app-installation-id", 0, "github app installation to authenticate as (GITHUB_APP_INSTALLATION_ID)")
+	pflags.StringVar(&flags.AppPrivateKey, "app-private-key", "", "path to the github app's PEM private key, or the key itself (GITHUB_APP_PRIVATE_KEY)")
+	pflags.StringSliceVarP(&flags.Repos, "repos", "r", []string{}, "github repo name (GITHUB_REPO) or a set of repos `owner1/repo1,owner2/repo2`, also accepts patterns 'owner/prefix-*' and topics 'owner/topic:name'")
+	pflags.StringSliceVar(&flags.ExcludeRepos, "exclude-repos", []string{}, "exclude repos matching these patterns from --repos. ie 'hashicorp/terraform-provider-scaffolding*'")
+	pflags.BoolVar(&flags.IncludeArchived, "include-archived", false, "include archived repos when expanding --repos patterns and topics")
+	pflags.StringVarP(&flags.Search, "search", "s", "", "sync the issues/prs matching a github search query instead of --repos, ie 'org:hashicorp is:pr is:open label:service/storage' (GITHUB_SEARCH)")
 	pflags.StringVarP(&flags.ProjectOwner, "project-owner", "o", "", "github project owner (GITHUB_PROJECT_OWNER)")
 	pflags.IntVarP(&flags.ProjectNumber, "project-number", "p", 0, "github project number (GITHUB_PROJECT_NUMBER)")
 	pflags.IntVarP(&flags.ItemLimit, "item-limit", "", 0, "limit the number of items to process (0 for no limit)")
 
-	pflags.StringSliceVarP(&flags.Filters.Authors, "authors", "a", []string{}, "only sync prs by these authors. ie 'katbyte,author2,author3'")
-	pflags.StringSliceVarP(&flags.Filters.Assignees, "assignees", "", []string{}, "sync prs assigned to these users. ie 'katbyte,assignee2,assignee3'")
-	pflags.StringSliceVar(&flags.Filters.Reviewers, "reviewers", []string{}, "retrieves number of reviews filtered by these users. ie 'katbyte,reviewer2,reviewer3'. Added as a separate field in addition to the number of total reviews.")
+	pflags.StringSliceVarP(&flags.Filters.Authors, "authors", "a", []string{}, "only sync prs by these authors or org teams. ie 'katbyte,author2,@org/team-slug'")
+	pflags.StringSliceVarP(&flags.Filters.Assignees, "assignees", "", []string{}, "sync prs assigned to these users or org teams. ie 'katbyte,assignee2,@org/team-slug'")
+	pflags.StringSliceVar(&flags.Filters.Reviewers, "reviewers", []string{}, "retrieves number of reviews filtered by these users or org teams. ie 'katbyte,reviewer2,@org/team-slug'. Added as a separate field in addition to the number of total reviews.")
 	pflags.StringSliceVarP(&flags.Filters.LabelsOr, "labels-or", "l", []string{}, "filter that match any label conditions. ie 'label1,label2,-not-this-label'")
 	pflags.StringSliceVarP(&flags.Filters.LabelsAnd, "labels-and", "", []string{}, "filter that match all label conditions. ie 'label1,label2,-not-this-label'")
 	pflags.StringSliceVarP(&flags.Filters.States, "pr-states", "", []string{"OPEN"}, "filter that match pr states. ie 'OPEN,MERGED,CLOSED'")
-	pflags.StringVarP(&flags.Filters.ProjectStatusIs, "project-status-is", "", "", "filter that match project status. ie 'In Progress'")
-	pflags.StringSliceVarP(&flags.Filters.ProjectFieldPopulated, "project-fields-populated", "", []string{}, "filter that match project fields populated. ie 'Due Date'")
+	pflags.StringVar(&flags.Filters.Expression, "filter", "", "filter expression, ie 'label:bug and not label:wontfix and author in (a,b) and age > 30d and draft = false'")
+	pflags.StringVarP(&flags.Filters.ProjectStatusIs, "project-status-is", "", "", "only sync items already in the project with this status. ie 'In Progress'")
+	pflags.StringVar(&flags.Filters.ProjectStatusField, "project-status-field", "Status", "the single select project field --project-status-is matches (GITHUB_PROJECT_STATUS_FIELD)")
+	pflags.StringSliceVarP(&flags.Filters.ProjectFieldPopulated, "project-fields-populated", "", []string{}, "only sync items already in the project with these fields populated. ie 'Due Date'")
 
 	// PR field population control
 	pflags.StringSliceVar(&flags.PRPopulateFields, "pr-populate-fields", []string{}, "only populate these PR fields (accepts field names or aliases, e.g. 'PR#,open-days')")
@@ -64,29 +155,86 @@ func configureFlags(root *cobra.Command) error {
 
 	// Linked issue field syncing
 	pflags.StringSliceVar(&flags.SyncLinkedIssueFields, "sync-linked-issue-fields", []string{}, "copy these field values from linked issues in the project (e.g. 'Status,Due Date,Priority')")
+	pflags.StringSliceVar(&flags.SyncPRFieldsToIssues, "sync-pr-fields-to-issues", []string{}, "copy these field values from the pr to its linked issues in the project (e.g. 'Status')")
+	pflags.StringSliceVar(&flags.LinkedIssueStrategies, "linked-issue-strategy", []string{}, "how fields are picked when several linked issues are in the project: skip (default), first, earliest, priority or concat, for all fields or per field ie 'first,Due Date=earliest'")
+	pflags.StringSliceVar(&flags.LinkedIssueSources, "linked-issue-sources", []string{}, "where linked issues are found: closing (default), body and/or timeline (issues cross referencing the pr)")
+
+	// PR status
+	pflags.StringSliceVar(&flags.WaitingLabels, "waiting-labels", []string{"waiting-response"}, "labels that mean a pr is waiting on the author")
+	pflags.StringArrayVar(&flags.StatusRules, "status-rules", []string{}, "set the status of prs matching a filter expression before the built-in rules, ie 'Stale=days-since-author-activity > 30d' (repeatable, ';' separated in GITHUB_STATUS_RULES)")
+
+	pflags.StringSliceVar(&flags.LabelFields, "label-fields", []string{}, "set project fields from labels with a prefix, ie 'service/=Service,size/=Size'. When several labels match the first single select option wins")
+	pflags.BoolVar(&flags.CreateMissingOptions, "create-missing-options", false, "create missing single select options for computed field values, ie a Repo option for each repo")
+
+	pflags.BoolVar(&flags.AddSubIssues, "add-sub-issues", false, "add the sub-issues and tracked issues of issues in the project that aren't already in it (issues command)")
+	pflags.StringArrayVar(&flags.PushMappings, "push-mappings", []string{}, "push project fields back to issues/prs with the push command, ie 'Priority=label:priority/', 'Status:Blocked=milestone:Blocked' (repeatable, ';' separated in GITHUB_PUSH_MAPPINGS)")
+	pflags.StringVar(&flags.PushStateFile, "push-state", ".ghp-sync-push.json", "file recording the values at the last push, used to detect conflicting changes (GITHUB_PUSH_STATE)")
 
-	pflags.BoolVarP(&flags.DryRun, "dry-run", "d", false, "dry run, don't actually add issues/prs to project")
+	pflags.BoolVarP(&flags.DryRun, "dry-run", "d", false, "dry run, print the plan of what would be added/updated/removed in the project without changing it")
+	pflags.StringVar(&flags.CheckpointFile, "checkpoint-file", "", "file recording the progress of prs, issues and add runs, otherwise "+DefaultCheckpointFile+" is only written once an item fails, removed when a run completes without failures (GITHUB_CHECKPOINT_FILE)")
+	pflags.BoolVar(&flags.Resume, "resume", false, "skip the items (or csv lines for add) processed by the previous failed run recorded in --checkpoint-file (default "+DefaultCheckpointFile+" in the current directory)")
+	pflags.BoolVar(&flags.RemoveUnmatched, "remove-unmatched", false, "remove items in the project that no longer match the filters, dry runs plan their removal (GITHUB_REMOVE_UNMATCHED)")
+	pflags.StringVar(&flags.PlanFile, "plan-file", "", "write the dry run plan to this file for apply to execute, implies --dry-run (GITHUB_PLAN_FILE)")
+	pflags.IntVar(&flags.BatchSize, "batch-size", gh.DefaultBatchSize, "most items added or updated in a single mutation (GITHUB_BATCH_SIZE)")
+	pflags.IntVar(&flags.BatchMutations, "batch-mutations", gh.DefaultBatchMutations, "most field updates and adds in a single mutation, limiting its complexity (GITHUB_BATCH_MUTATIONS)")
 
 	// binding map for viper/pflag -> env
 	// this is too large now, we need to make a config file
 	m := map[string]string{ //nolint:gosec // false positive for mapping flag names to env vars
+		"config":                   "GHP_SYNC_CONFIG",
 		"token":                    "GITHUB_TOKEN",
+		"tokens":                   "GITHUB_TOKENS",
+		"github-host":              "GITHUB_HOST",
+		"cache-dir":                "GITHUB_CACHE_DIR",
+		"no-cache":                 "",
+		"cache-max-age":            "GITHUB_CACHE_MAX_AGE",
+		"cache-max-size":           "GITHUB_CACHE_MAX_SIZE",
+		"max-retry-wait":           "GITHUB_MAX_RETRY_WAIT",
+		"timeout":                  "GHP_SYNC_TIMEOUT",
+		"log-format":               "GHP_SYNC_LOG_FORMAT",
+		"record":                   "GHP_SYNC_RECORD",
+		"replay":                   "GHP_SYNC_REPLAY",
+		"replay-repeat":            "GHP_SYNC_REPLAY_REPEAT",
+		"app-id":                   "GITHUB_APP_ID",
+		"app-installation-id":      "GITHUB_APP_INSTALLATION_ID",
+		"app-private-key":          "GITHUB_APP_PRIVATE_KEY",
 		"repos":                    "GITHUB_REPOS",
+		"search":                   "GITHUB_SEARCH",
+		"exclude-repos":            "GITHUB_EXCLUDE_REPOS",
+		"include-archived":         "",
 		"project-owner":            "GITHUB_PROJECT_OWNER",
 		"project-number":           "GITHUB_PROJECT_NUMBER",
 		"item-limit":               "ITEM_LIMIT",
 		"pr-states":                "GITHUB_PR_STATES",
 		"project-status-is":        "GITHUB_PROJECT_STATUS_IS",
+		"project-status-field":     "GITHUB_PROJECT_STATUS_FIELD",
 		"project-fields-populated": "GITHUB_PROJECT_FIELDS_POPULATED",
 		"authors":                  "GITHUB_AUTHORS",
 		"assignees":                "GITHUB_ASSIGNEES",
 		"reviewers":                "GITHUB_REVIEWERS",
 		"labels-or":                "GITHUB_LABELS_OR",
 		"labels-and":               "GITHUB_LABELS_AND",
+		"filter":                   "GITHUB_FILTER",
 		"pr-populate-fields":       "GITHUB_PR_POPULATE_FIELDS",
 		"pr-skip-fields":           "GITHUB_PR_SKIP_FIELDS",
 		"sync-linked-issue-fields": "GITHUB_SYNC_LINKED_ISSUE_FIELDS",
+		"sync-pr-fields-to-issues": "GITHUB_SYNC_PR_FIELDS_TO_ISSUES",
+		"linked-issue-strategy":    "GITHUB_LINKED_ISSUE_STRATEGY",
+		"linked-issue-sources":     "GITHUB_LINKED_ISSUE_SOURCES",
+		"waiting-labels":           "GITHUB_WAITING_LABELS",
+		"status-rules":             "GITHUB_STATUS_RULES",
+		"label-fields":             "GITHUB_LABEL_FIELDS",
+		"create-missing-options":   "GITHUB_CREATE_MISSING_OPTIONS",
+		"add-sub-issues":           "GITHUB_ADD_SUB_ISSUES",
+		"push-mappings":            "GITHUB_PUSH_MAPPINGS",
+		"push-state":               "GITHUB_PUSH_STATE",
 		"dry-run":                  "",
+		"plan-file":                "GITHUB_PLAN_FILE",
+		"remove-unmatched":         "GITHUB_REMOVE_UNMATCHED",
+		"batch-size":               "GITHUB_BATCH_SIZE",
+		"batch-mutations":          "GITHUB_BATCH_MUTATIONS",
+		"checkpoint-file":          "GITHUB_CHECKPOINT_FILE",
+		"resume":                   "",
 	}
 
 	for name, env := range m {
@@ -102,6 +250,7 @@ func configureFlags(root *cobra.Command) error {
 	}
 
 	root.MarkFlagsMutuallyExclusive("pr-populate-fields", "pr-skip-fields")
+	root.MarkFlagsMutuallyExclusive("record", "replay")
 
 	return nil
 }
@@ -122,17 +271,65 @@ func GetStringSliceFixed(key string) []string {
 	return strings.Split(s[0], ",")
 }
 
+// GetStringSliceFixedSep is GetStringSliceFixed for values that can contain commas, such as filter
+// expressions, splitting env vars on sep instead.
+func GetStringSliceFixedSep(key, sep string) []string {
+	s := viper.GetStringSlice(key)
+
+	if len(s) != 1 || s[0] == "" {
+		return s
+	}
+
+	return strings.Split(s[0], sep)
+}
+
 func GetFlags() FlagData {
 	// there has to be an easier way....
 	f := FlagData{
-		Token:         viper.GetString("token"),
-		Repos:         GetStringSliceFixed("repos"),
+		Config: viper.GetString("config"),
+
+		GitHubHost: viper.GetString("github-host"),
+		Token:      viper.GetString("token"),
+		Tokens:     GetStringSliceFixed("tokens"),
+		CacheDir:   viper.GetString("cache-dir"),
+		NoCache:    viper.GetBool("no-cache"),
+
+		CacheMaxAge:  viper.GetDuration("cache-max-age"),
+		CacheMaxSize: viper.GetInt("cache-max-size"),
+
+		MaxRetryWait: viper.GetDuration("max-retry-wait"),
+		Timeout:      viper.GetDuration("timeout"),
+		LogFormat:    viper.GetString("log-format"),
+		Record:       viper.GetString("record"),
+		Replay:       viper.GetString("replay"),
+		ReplayRepeat: viper.GetBool("replay-repeat"),
+
+		AppID:             viper.GetInt64("app-id"),
+		AppInstallationID: viper.GetInt64("app-installation-id"),
+		AppPrivateKey:     viper.GetString("app-private-key"),
+		Auth:              authToken(viper.GetString("token"), GetStringSliceFixed("tokens")),
+
+		Repos:  GetStringSliceFixed("repos"),
+		Search: viper.GetString("search"),
+
+		ExcludeRepos:    GetStringSliceFixed("exclude-repos"),
+		IncludeArchived: viper.GetBool("include-archived"),
+
 		ProjectNumber: viper.GetInt("project-number"),
 		ProjectOwner:  viper.GetString("project-owner"),
 
 		ItemLimit: viper.GetInt("item-limit"),
 
-		DryRun: viper.GetBool("dry-run"),
+		DryRun:   viper.GetBool("dry-run"),
+		PlanFile: viper.GetString("plan-file"),
+
+		RemoveUnmatched: viper.GetBool("remove-unmatched"),
+
+		BatchSize:      viper.GetInt("batch-size"),
+		BatchMutations: viper.GetInt("batch-mutations"),
+
+		CheckpointFile: viper.GetString("checkpoint-file"),
+		Resume:         viper.GetBool("resume"),
 
 		Filters: Filters{
 			Authors:               GetStringSliceFixed("authors"),
@@ -141,7 +338,9 @@ func GetFlags() FlagData {
 			LabelsOr:              GetStringSliceFixed("labels-or"),
 			LabelsAnd:             GetStringSliceFixed("labels-and"),
 			States:                GetStringSliceFixed("pr-states"),
+			Expression:            viper.GetString("filter"),
 			ProjectStatusIs:       viper.GetString("project-status-is"),
+			ProjectStatusField:    viper.GetString("project-status-field"),
 			ProjectFieldPopulated: GetStringSliceFixed("project-fields-populated"),
 		},
 
@@ -149,6 +348,20 @@ func GetFlags() FlagData {
 		PRSkipFields:     GetStringSliceFixed("pr-skip-fields"),
 
 		SyncLinkedIssueFields: GetStringSliceFixed("sync-linked-issue-fields"),
+		SyncPRFieldsToIssues:  GetStringSliceFixed("sync-pr-fields-to-issues"),
+		LinkedIssueStrategies: GetStringSliceFixed("linked-issue-strategy"),
+		LinkedIssueSources:    GetStringSliceFixed("linked-issue-sources"),
+
+		WaitingLabels: GetStringSliceFixed("waiting-labels"),
+		StatusRules:   GetStringSliceFixedSep("status-rules", ";"),
+
+		LabelFields:          GetStringSliceFixed("label-fields"),
+		CreateMissingOptions: viper.GetBool("create-missing-options"),
+
+		AddSubIssues: viper.GetBool("add-sub-issues"),
+
+		PushMappings:  GetStringSliceFixedSep("push-mappings", ";"),
+		PushStateFile: viper.GetString("push-state"),
 	}
 
 	// Resolve which PR field names to populate
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// values of the built-in Kind field
+const (
+	KindPullRequest = "Pull Request"
+	KindIssue       = "Issue"
+)
+
+// repoFieldValue is the value of the built-in Repo field, the repo name without its owner. It isn't named
+// Repository as that is the name of the project's own repository field.
+func repoFieldValue(repo string) any {
+	if repo == "" {
+		return nil
+	}
+
+	_, name, found := strings.Cut(repo, "/")
+	if !found {
+		return repo
+	}
+
+	return name
+}
+
+// issueItemFields builds the built-in Repo, Kind, URL, Parent and Sub-issue Progress fields for an
+// issue, tracked is its task list relationships used when it has no sub-issue parent or sub-issues.
+// Fields not in the project are skipped.
+func issueItemFields(ctx context.Context, f FlagData, p gh.Project, issue github.Issue, tracked gh.TrackedIssues) []gh.ProjectItemField {
+	values := []struct {
+		name  string
+		value any
+	}{
+		{"Repo", repoFieldValue(issueRepo(issue))},
+		{"Kind", KindIssue},
+		{"URL", issue.GetHTMLURL()},
+		{"Parent", parentFieldValue(issue, tracked)},
+		{"Sub-issue Progress", subIssueProgress(issue, tracked)},
+	}
+
+	var fields []gh.ProjectItemField
+	for _, v := range values {
+		if _, ok := p.FieldIDs[v.name]; !ok || v.value == nil || v.value == "" {
+			continue
+		}
+
+		field, err := projectField(ctx, f, p, v.name, gh.ItemValueTypeText, v.value)
+		if err != nil {
+			c.Printf("  <yellow>WARNING:</> %s\n", err)
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	return fields
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// itemWrite is an add and field update of one project item queued on an itemWriter
+type itemWrite struct {
+	NodeID string // content added to the project when ItemID is empty
+	ItemID string // item already in the project
+	Fields []gh.ProjectItemField
+
+	// Done is called once the item is written with its item id, empty when adding it failed
+	Done func(itemID string, err error) error
+}
+
+// itemWriterCloseTimeout bounds writing the queued items of an interrupted run
+const itemWriterCloseTimeout = 30 * time.Second
+
+// itemWriter queues item adds and field updates and writes them with an ItemBatcher once a batch is full
+// or it is flushed. Writes to the same item are merged, later fields replacing earlier ones.
+type itemWriter struct {
+	ctx   context.Context // the run's, a batch isn't logged with the fields of the item that filled it
+	b     *gh.ItemBatcher
+	queue []itemWrite
+}
+
+func newItemWriter(ctx context.Context, f FlagData, p *gh.Project) *itemWriter {
+	return &itemWriter{ctx: ctx, b: p.NewItemBatcher(f.BatchSize, f.BatchMutations)}
+}
+
+// Write queues an item, writing the queue when a batch is full
+func (w *itemWriter) Write(iw itemWrite) error {
+	w.queue = append(w.queue, iw)
+	if len(w.queue) < w.b.Size {
+		return nil
+	}
+
+	return w.Flush()
+}
+
+// Flush adds and updates the queued items, calling each one's Done in the order they were queued
+func (w *itemWriter) Flush() error {
+	ctx := w.ctx
+
+	queue := w.queue
+	w.queue = nil
+	if len(queue) == 0 {
+		return nil
+	}
+
+	var adds []string
+	for _, iw := range queue {
+		if iw.ItemID == "" {
+			adds = append(adds, iw.NodeID)
+		}
+	}
+
+	itemIDs := map[string]string{}
+	var addErrs map[string]error
+	if len(adds) > 0 {
+		itemIDs, addErrs = w.b.AddItems(ctx, adds)
+	}
+
+	itemID := func(iw itemWrite) string {
+		if iw.ItemID != "" {
+			return iw.ItemID
+		}
+		return itemIDs[iw.NodeID]
+	}
+
+	var updates []gh.ItemUpdate
+	index := map[string]int{} // item id -> update
+	for _, iw := range queue {
+		id := itemID(iw)
+		if id == "" || len(iw.Fields) == 0 {
+			continue
+		}
+
+		i, ok := index[id]
+		if !ok {
+			updates = append(updates, gh.ItemUpdate{ItemID: id})
+			i = len(updates) - 1
+			index[id] = i
+		}
+		updates[i].Fields = withFields(updates[i].Fields, iw.Fields)
+	}
+
+	var updateErrs map[string]error
+	if len(updates) > 0 {
+		updateErrs = w.b.UpdateItems(ctx, updates)
+	}
+
+	for _, iw := range queue {
+		if iw.Done == nil {
+			continue
+		}
+
+		id := itemID(iw)
+		err := addErrs[iw.NodeID]
+		if id != "" {
+			err = updateErrs[id]
+		}
+		if err := iw.Done(id, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the items still queued when a sync ends, deferred so it happens on every exit. An
+// interrupted run's items are written with a short context of their own as they were already reported as
+// syncing. err is the sync's own error, returned joined with any error writing the queue.
+func (w *itemWriter) Close(err error) error {
+	n := len(w.queue)
+	if n == 0 {
+		return err
+	}
+
+	if w.ctx.Err() != nil {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), itemWriterCloseTimeout)
+		defer cancel()
+		w.ctx = ctx
+		c.Printf("<yellow>writing the %d queued items before stopping..</>\n", n)
+	}
+
+	if ferr := w.Flush(); ferr != nil {
+		return errors.Join(err, fmt.Errorf("writing %d queued items: %w", n, ferr))
+	}
+
+	return err
+}
+
+// syncDone returns the Done of a synced issue or pr, logging its result and recording it in the checkpoint
+func syncDone(ctx context.Context, cp *Checkpoint, label, nodeID string) func(string, error) error {
+	return func(itemID string, err error) error {
+		if itemID == "" {
+			logItem(ctx, "add", nil, err)
+		} else {
+			logItem(ctx, "update", &itemID, err)
+		}
+
+		if err != nil {
+			c.Printf("<red>ERROR!!</> %s: %s\n", label, err)
+			return cp.MarkFailed()
+		}
+
+		return cp.MarkDone(nodeID)
+	}
+}
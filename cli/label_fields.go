@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// LabelField maps labels with a prefix to a project field, ie `service/storage` sets Service to storage.
+type LabelField struct {
+	Prefix string
+	Field  string
+}
+
+// GetLabelFields parses the `prefix=Field` label field mappings, checking the fields exist in the project.
+func (f FlagData) GetLabelFields(p gh.Project) ([]LabelField, error) {
+	lfs := make([]LabelField, 0, len(f.LabelFields))
+	for _, m := range f.LabelFields {
+		prefix, field, found := strings.Cut(m, "=")
+		if !found || prefix == "" {
+			return nil, fmt.Errorf("invalid label field %q, expected 'prefix=Field'", m)
+		}
+		field = strings.TrimSpace(field)
+
+		if _, ok := p.FieldIDs[field]; !ok {
+			return nil, fmt.Errorf("label field %q: field %q not found in project", m, field)
+		}
+
+		lfs = append(lfs, LabelField{Prefix: strings.TrimSpace(prefix), Field: field})
+	}
+
+	return lfs, nil
+}
+
+// Value returns the value for the field from the labels with the prefix. When several labels match the
+// one whose option comes first in the single select field wins, then labels without an option by name.
+func (lf LabelField) Value(p gh.Project, labels []string) (string, bool) {
+	var values []string
+	for _, l := range labels {
+		if len(l) > len(lf.Prefix) && strings.EqualFold(l[:len(lf.Prefix)], lf.Prefix) {
+			values = append(values, l[len(lf.Prefix):])
+		}
+	}
+	if len(values) == 0 {
+		return "", false
+	}
+
+	order := optionOrder(p, lf.Field)
+	rank := func(v string) int {
+		if i, ok := order[strings.ToLower(v)]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		ri, rj := rank(values[i]), rank(values[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return values[i] < values[j]
+	})
+
+	return values[0], true
+}
+
+// optionOrder returns the position of each of a single select field's options keyed by lowercase name
+func optionOrder(p gh.Project, fieldName string) map[string]int {
+	order := map[string]int{}
+	for _, field := range p.Fields {
+		if field.Name != fieldName {
+			continue
+		}
+		for i, o := range field.Options {
+			order[strings.ToLower(o.Name)] = i
+		}
+	}
+
+	return order
+}
+
+// labelItemFields builds the fields mapped from an item's labels
+func labelItemFields(ctx context.Context, f FlagData, p gh.Project, lfs []LabelField, labels []string) []gh.ProjectItemField {
+	var fields []gh.ProjectItemField
+	for _, lf := range lfs {
+		value, ok := lf.Value(p, labels)
+		if !ok {
+			continue
+		}
+
+		field, err := projectField(ctx, f, p, lf.Field, gh.ItemValueTypeText, value)
+		if err != nil {
+			c.Printf("  <yellow>WARNING:</> %s\n", err)
+			continue
+		}
+		c.Printf("  <lightBlue>%s</>: <yellow>%s</> <gray>(label)</>\n", lf.Field, value)
+		fields = append(fields, field)
+	}
+
+	return fields
+}
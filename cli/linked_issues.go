@@ -0,0 +1,428 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// how a field is resolved when a pr has several linked issues in the project
+const (
+	LinkedIssueStrategySkip     = "skip"     // don't sync the field
+	LinkedIssueStrategyFirst    = "first"    // the first linked issue with a value
+	LinkedIssueStrategyEarliest = "earliest" // the earliest date or smallest number
+	LinkedIssueStrategyPriority = "priority" // the single select option that comes first in the field
+	LinkedIssueStrategyConcat   = "concat"   // unique text values joined with a comma
+)
+
+// where linked issues are found
+const (
+	LinkedIssueSourceClosing  = "closing"  // closingIssuesReferences, ie `fixes #123`
+	LinkedIssueSourceBody     = "body"     // issues referenced in the pr body
+	LinkedIssueSourceTimeline = "timeline" // issues that cross reference the pr
+)
+
+// LinkedIssueSync is the configuration for syncing fields between prs and their linked issues.
+type LinkedIssueSync struct {
+	FromIssueFields []string          // copied from the linked issues to the pr
+	ToIssueFields   []string          // copied from the pr to the linked issues
+	Strategies      map[string]string // field -> strategy, "" for the default
+	Sources         map[string]bool
+
+	issueRef *regexp.Regexp // issue references in pr bodies, for the github host
+
+	// issues referenced in pr bodies by owner/repo#number, looked up once a run as the same issues are
+	// often referenced by many prs. Failed lookups and prs are kept as nil.
+	bodyRefs map[string]*linkedIssue
+	getIssue func(ctx context.Context, r *gh.Repo, number int) (*github.Issue, error) // Repo.GetIssue when nil
+}
+
+// GetLinkedIssueSync validates the linked issue flags, returning nil if no fields are synced.
+func (f FlagData) GetLinkedIssueSync(p gh.Project) (*LinkedIssueSync, error) {
+	if len(f.SyncLinkedIssueFields) == 0 && len(f.SyncPRFieldsToIssues) == 0 {
+		return nil, nil
+	}
+
+	ls := LinkedIssueSync{
+		FromIssueFields: f.SyncLinkedIssueFields,
+		ToIssueFields:   f.SyncPRFieldsToIssues,
+		Strategies:      map[string]string{"": LinkedIssueStrategySkip},
+		Sources:         map[string]bool{},
+		issueRef:        issueRefRe(gh.Host()),
+		bodyRefs:        map[string]*linkedIssue{},
+	}
+
+	from := map[string]bool{}
+	for _, name := range ls.FromIssueFields {
+		from[name] = true
+	}
+	for _, name := range ls.ToIssueFields {
+		if from[name] {
+			return nil, fmt.Errorf("field %q can't be synced both from and to linked issues", name)
+		}
+		if _, ok := p.FieldIDs[name]; !ok {
+			return nil, fmt.Errorf("pr field %q to sync to linked issues not found in project", name)
+		}
+	}
+
+	for _, s := range f.LinkedIssueStrategies {
+		field, strategy, found := strings.Cut(s, "=")
+		if !found {
+			field, strategy = "", s
+		}
+		strategy = strings.ToLower(strings.TrimSpace(strategy))
+
+		switch strategy {
+		case LinkedIssueStrategySkip, LinkedIssueStrategyFirst, LinkedIssueStrategyEarliest, LinkedIssueStrategyPriority, LinkedIssueStrategyConcat:
+		default:
+			return nil, fmt.Errorf("invalid linked issue strategy %q, expected skip, first, earliest, priority or concat", s)
+		}
+		ls.Strategies[strings.TrimSpace(field)] = strategy
+	}
+
+	for _, s := range f.LinkedIssueSources {
+		switch s = strings.ToLower(strings.TrimSpace(s)); s {
+		case LinkedIssueSourceClosing, LinkedIssueSourceBody, LinkedIssueSourceTimeline:
+			ls.Sources[s] = true
+		default:
+			return nil, fmt.Errorf("invalid linked issue source %q, expected closing, body or timeline", s)
+		}
+	}
+	if len(ls.Sources) == 0 {
+		ls.Sources[LinkedIssueSourceClosing] = true
+	}
+
+	return &ls, nil
+}
+
+// Strategy returns how a field is resolved from several linked issues
+func (ls LinkedIssueSync) Strategy(field string) string {
+	if s, ok := ls.Strategies[field]; ok {
+		return s
+	}
+
+	return ls.Strategies[""]
+}
+
+// linkedIssue is an issue linked to a pr
+type linkedIssue struct {
+	NodeID string
+	Number int
+	Source string
+}
+
+// issueRefRe matches `#123`, `owner/repo#123` and the host's issue urls in a pr body. References must not
+// follow part of a word or path, so urls with fragments such as `example.com/page#3` aren't matched.
+func issueRefRe(host string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:https://` + regexp.QuoteMeta(host) + `/([\w.-]+/[\w.-]+)/issues/|(?:^|[^\w/.-])(?:([\w.-]+/[\w.-]+))?#)(\d+)\b`)
+}
+
+// findLinkedIssues returns the issues linked to a pr from the configured sources in order, without duplicates
+func findLinkedIssues(ctx context.Context, f FlagData, ls *LinkedIssueSync, repos map[string]*gh.Repo, pr gh.PullRequest) ([]linkedIssue, error) {
+	var issues []linkedIssue
+	seen := map[string]bool{}
+	add := func(i linkedIssue) {
+		if i.NodeID != "" && !seen[i.NodeID] {
+			seen[i.NodeID] = true
+			issues = append(issues, i)
+		}
+	}
+
+	if ls.Sources[LinkedIssueSourceClosing] {
+		for _, ci := range pr.ClosingIssues {
+			add(linkedIssue{NodeID: ci.NodeID, Number: ci.Number, Source: LinkedIssueSourceClosing})
+		}
+	}
+
+	if ls.Sources[LinkedIssueSourceBody] {
+		for _, m := range ls.issueRef.FindAllStringSubmatch(pr.Body, -1) {
+			repo := pr.Repository
+			if m[1] != "" {
+				repo = m[1]
+			} else if m[2] != "" {
+				repo = m[2]
+			}
+
+			number, _ := strconv.Atoi(m[3])
+			if number == pr.Number && strings.EqualFold(repo, pr.Repository) {
+				continue
+			}
+
+			li, err := ls.bodyRef(ctx, f, repos, repo, number)
+			if err != nil {
+				return nil, err
+			}
+			if li != nil {
+				add(*li)
+			}
+		}
+	}
+
+	if ls.Sources[LinkedIssueSourceTimeline] {
+		events, err := getPREvents(ctx, f, repos, pr)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			issue := e.GetSource().GetIssue()
+			if e.GetEvent() != "cross-referenced" || issue == nil || issue.IsPullRequest() {
+				continue
+			}
+			add(linkedIssue{NodeID: issue.GetNodeID(), Number: issue.GetNumber(), Source: LinkedIssueSourceTimeline})
+		}
+	}
+
+	return issues, nil
+}
+
+// bodyRef looks up an issue referenced in a pr body, nil for prs and issues that can't be looked up
+func (ls *LinkedIssueSync) bodyRef(ctx context.Context, f FlagData, repos map[string]*gh.Repo, repo string, number int) (*linkedIssue, error) {
+	key := strings.ToLower(repo) + "#" + strconv.Itoa(number)
+	if li, ok := ls.bodyRefs[key]; ok {
+		return li, nil
+	}
+
+	r, err := cachedRepo(f, repos, repo)
+	if err != nil {
+		return nil, err
+	}
+	getIssue := ls.getIssue
+	if getIssue == nil {
+		getIssue = func(ctx context.Context, r *gh.Repo, number int) (*github.Issue, error) {
+			return r.GetIssue(ctx, number)
+		}
+	}
+
+	var li *linkedIssue
+	issue, err := getIssue(ctx, r, number)
+	switch {
+	case err != nil:
+		c.Printf("    <yellow>⚠ unable to look up %s#%d referenced in the body:</> %s\n", repo, number, err)
+		if ctx.Err() != nil {
+			return nil, nil // not remembered, the run is stopping
+		}
+	case !issue.IsPullRequest():
+		li = &linkedIssue{NodeID: issue.GetNodeID(), Number: number, Source: LinkedIssueSourceBody}
+	}
+	if ls.bodyRefs == nil {
+		ls.bodyRefs = map[string]*linkedIssue{}
+	}
+	ls.bodyRefs[key] = li
+
+	return li, nil
+}
+
+// syncLinkedIssues copies fields from the pr's linked issues to the pr item and from the pr to the issue
+// items. prFields are the values just computed for the pr, other fields copied to the issues are read
+// from the project.
+func syncLinkedIssues(ctx context.Context, f FlagData, p gh.Project, ls *LinkedIssueSync, pl *Planner, w *itemWriter, repos map[string]*gh.Repo, pr gh.PullRequest, prFields []gh.ProjectItemField) error {
+	linked, err := findLinkedIssues(ctx, f, ls, repos, pr)
+	if err != nil {
+		return err
+	}
+
+	if len(linked) == 0 {
+		c.Printf("  <gray>🔗 linked issue sync: no linked issues referenced</>\n")
+		return nil
+	}
+
+	c.Printf("  <magenta>🔗</> linked issue sync\n")
+	c.Printf("    linked issue(s): ")
+	for i, li := range linked {
+		if i > 0 {
+			c.Printf(", ")
+		}
+		c.Printf("<lightCyan>#%d</> <gray>(%s)</>", li.Number, li.Source)
+	}
+	c.Printf("\n")
+
+	// look up the issue (and pr) items in one pass over the project
+	nodeIDs := []string{pr.NodeID}
+	for _, li := range linked {
+		nodeIDs = append(nodeIDs, li.NodeID)
+	}
+	items, err := p.GetItemsFieldValuesByNodeIDs(ctx, nodeIDs, append(append([]string{}, ls.FromIssueFields...), ls.ToIssueFields...))
+	if err != nil {
+		c.Printf("    <red>ERROR!</> reading linked issue fields: %s\n", err)
+		return nil
+	}
+
+	var inProject []linkedIssue
+	for _, li := range linked {
+		if item, ok := items[li.NodeID]; ok {
+			c.Printf("    <lightCyan>#%d</> <green>✓ in project</> (<gray>%s</>)\n", li.Number, item.ID)
+			inProject = append(inProject, li)
+		} else {
+			c.Printf("    <lightCyan>#%d</> <yellow>✗ not in project</>\n", li.Number)
+		}
+	}
+	if len(inProject) == 0 {
+		c.Printf("    <yellow>⚠ no linked issues found in project, skipping field sync</>\n")
+		return nil
+	}
+
+	if len(ls.FromIssueFields) > 0 {
+		var linkedFields []gh.ProjectItemField
+		for _, fieldName := range ls.FromIssueFields {
+			fieldID, hasField := p.FieldIDs[fieldName]
+			if !hasField {
+				c.Printf("      <yellow>%s: field not found in project, skipping</>\n", fieldName)
+				continue
+			}
+
+			strategy := ls.Strategy(fieldName)
+			if strategy == LinkedIssueStrategySkip && len(inProject) > 1 {
+				c.Printf("      <yellow>%s: multiple linked issues in project (%d), skipping</>\n", fieldName, len(inProject))
+				continue
+			}
+
+			var values []gh.ProjectItemFieldValue
+			for _, li := range inProject {
+				if fv, ok := items[li.NodeID].Values[fieldName]; ok {
+					values = append(values, fv)
+				}
+			}
+			fv, ok := resolveLinkedValue(p, fieldName, strategy, values)
+			if !ok {
+				c.Printf("      <gray>%s: <empty></>\n", fieldName)
+				continue
+			}
+
+			c.Printf("      <green>%s</>: <white>%v</> (<gray>%s, %s</>)\n", fieldName, fv.Value, fv.Type, strategy)
+			linkedFields = append(linkedFields, gh.ProjectItemField{
+				Name:    "linked_" + strings.ToLower(strings.NewReplacer(" ", "_", "#", "").Replace(fieldName)),
+				FieldID: fieldID,
+				Type:    fv.Type,
+				Value:   fv.Value,
+			})
+		}
+
+		switch {
+		case len(linkedFields) == 0:
+			c.Printf("    <yellow>⚠ no field values to sync</>\n")
+		case !f.DryRun:
+			c.Printf("    syncing <lightGreen>%d</> field(s) to PR\n", len(linkedFields))
+			label := fmt.Sprintf("%s#%d linked issue fields", pr.Repository, pr.Number)
+			if err := w.Write(itemWrite{NodeID: pr.NodeID, Fields: linkedFields, Done: linkedDone(label)}); err != nil {
+				return err
+			}
+		default:
+			if err := pl.Item(ctx, pr.NodeID, fmt.Sprintf("%s#%d %s", pr.Repository, pr.Number, pr.Title), linkedFields); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(ls.ToIssueFields) > 0 {
+		toIssueFields := prFieldsForIssues(p, ls.ToIssueFields, prFields, items[pr.NodeID])
+		if len(toIssueFields) == 0 {
+			c.Printf("    <yellow>⚠ no pr field values to sync to linked issues</>\n")
+			return nil
+		}
+
+		for _, li := range inProject {
+			switch {
+			case f.DryRun:
+				if err := pl.Item(ctx, li.NodeID, fmt.Sprintf("issue #%d", li.Number), toIssueFields); err != nil {
+					return err
+				}
+			default:
+				c.Printf("    syncing <lightGreen>%d</> field(s) to issue <lightCyan>#%d</>\n", len(toIssueFields), li.Number)
+				label := fmt.Sprintf("issue #%d fields from %s#%d", li.Number, pr.Repository, pr.Number)
+				if err := w.Write(itemWrite{ItemID: items[li.NodeID].ID, Fields: toIssueFields, Done: linkedDone(label)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkedDone returns the Done of a linked issue field sync, which only reports failures
+func linkedDone(label string) func(string, error) error {
+	return func(_ string, err error) error {
+		if err != nil {
+			c.Printf("<red>ERROR!</> %s: %s\n", label, err)
+		}
+		return nil
+	}
+}
+
+// prFieldsForIssues returns the pr values of the fields to copy to linked issues, preferring the values
+// computed this run over those read from the project
+func prFieldsForIssues(p gh.Project, names []string, computed []gh.ProjectItemField, item gh.ProjectItemValues) []gh.ProjectItemField {
+	byID := map[string]gh.ProjectItemField{}
+	for _, field := range computed {
+		byID[field.FieldID] = field
+	}
+
+	var fields []gh.ProjectItemField
+	for _, name := range names {
+		fieldID := p.FieldIDs[name]
+		alias := "pr_" + strings.ToLower(strings.NewReplacer(" ", "_", "#", "").Replace(name))
+
+		if field, ok := byID[fieldID]; ok {
+			field.Name = alias
+			fields = append(fields, field)
+		} else if fv, ok := item.Values[name]; ok {
+			fields = append(fields, gh.ProjectItemField{Name: alias, FieldID: fieldID, Type: fv.Type, Value: fv.Value})
+		}
+	}
+
+	return fields
+}
+
+// resolveLinkedValue picks the value of a field from several linked issues using the strategy, falling
+// back to the first value when the strategy doesn't apply to the field type
+func resolveLinkedValue(p gh.Project, fieldName, strategy string, values []gh.ProjectItemFieldValue) (gh.ProjectItemFieldValue, bool) {
+	if len(values) == 0 {
+		return gh.ProjectItemFieldValue{}, false
+	}
+	first := values[0]
+
+	switch {
+	case strategy == LinkedIssueStrategyEarliest && (first.Type == gh.ItemValueTypeDate || first.Type == gh.ItemValueTypeNumber):
+		sort.SliceStable(values, func(i, j int) bool {
+			if a, ok := values[i].Value.(float64); ok {
+				b, _ := values[j].Value.(float64)
+				return a < b
+			}
+			return fmt.Sprint(values[i].Value) < fmt.Sprint(values[j].Value) // dates are YYYY-MM-DD
+		})
+		return values[0], true
+
+	case strategy == LinkedIssueStrategyPriority && first.Type == gh.ItemValueTypeSingleSelect:
+		order := optionOrder(p, fieldName)
+		rank := func(v gh.ProjectItemFieldValue) int {
+			if i, ok := order[strings.ToLower(p.SingleSelectOptionNames[fieldName][fmt.Sprint(v.Value)])]; ok {
+				return i
+			}
+			return len(order)
+		}
+		sort.SliceStable(values, func(i, j int) bool {
+			return rank(values[i]) < rank(values[j])
+		})
+		return values[0], true
+
+	case strategy == LinkedIssueStrategyConcat && first.Type == gh.ItemValueTypeText:
+		var texts []string
+		seen := map[string]bool{}
+		for _, v := range values {
+			if t := fmt.Sprint(v.Value); t != "" && !seen[t] {
+				seen[t] = true
+				texts = append(texts, t)
+			}
+		}
+		return gh.ProjectItemFieldValue{Type: gh.ItemValueTypeText, Value: strings.Join(texts, ", ")}, true
+	}
+
+	return first, true
+}
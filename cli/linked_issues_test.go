@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/katbyte/ghp-sync/lib/pointer"
+)
+
+func TestIssueRefRe(t *testing.T) {
+	t.Parallel()
+
+	type ref struct {
+		repo   string
+		number string
+	}
+
+	cases := []struct {
+		body string
+		want []ref
+	}{
+		{"#45", []ref{{"", "45"}}},
+		{"Fixes #123", []ref{{"", "123"}}},
+		{"fixes #1, closes #2 and resolves #3", []ref{{"", "1"}, {"", "2"}, {"", "3"}}},
+		{"Add the thing (#8)", []ref{{"", "8"}}},
+		{"#1,#2", []ref{{"", "1"}, {"", "2"}}},
+		{"line one\n#7 on line two", []ref{{"", "7"}}},
+		{"Fixes owner/repo#12", []ref{{"owner/repo", "12"}}},
+		{"see hashicorp/terraform-provider-azurerm#30000.", []ref{{"hashicorp/terraform-provider-azurerm", "30000"}}},
+		{"see my.org/repo.name#4", []ref{{"my.org/repo.name", "4"}}},
+		{"Fixes https://github.com/owner/repo/issues/99", []ref{{"owner/repo", "99"}}},
+		{"https://github.com/owner/repo/issues/5#issuecomment-1", []ref{{"owner/repo", "5"}}},
+		{"both #1 and https://github.com/o/r/issues/2", []ref{{"", "1"}, {"o/r", "2"}}},
+
+		{"", nil},
+		{"no references", nil},
+		{"issue#12", nil},
+		{"#12abc", nil},
+		{"#abc", nil},
+		{"see https://example.com/page#3", nil},
+		{"https://github.com/owner/repo/pull/5", nil},
+		{"https://other.example.com/owner/repo/issues/5", nil},
+	}
+
+	re := issueRefRe("github.com")
+	for _, tc := range cases {
+		t.Run(tc.body, func(t *testing.T) {
+			t.Parallel()
+
+			var got []ref
+			for _, m := range re.FindAllStringSubmatch(tc.body, -1) {
+				repo := m[1]
+				if repo == "" {
+					repo = m[2]
+				}
+				got = append(got, ref{repo, m[3]})
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("references in %q = %v, want %v", tc.body, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindLinkedIssuesBodyLookedUpOnce(t *testing.T) {
+	t.Parallel()
+
+	lookups := map[string]int{}
+	ls := &LinkedIssueSync{
+		Sources:  map[string]bool{LinkedIssueSourceBody: true},
+		issueRef: issueRefRe("github.com"),
+		bodyRefs: map[string]*linkedIssue{},
+		getIssue: func(_ context.Context, r *gh.Repo, number int) (*github.Issue, error) {
+			lookups[r.Owner+"/"+r.Name+"#"+strconv.Itoa(number)]++
+			switch number {
+			case 2:
+				return &github.Issue{PullRequestLinks: &github.PullRequestLinks{}}, nil
+			case 3:
+				return nil, errors.New("not found")
+			}
+			return &github.Issue{NodeID: pointer.To("I_" + r.Name)}, nil
+		},
+	}
+
+	repos := map[string]*gh.Repo{}
+	for _, pr := range []gh.PullRequest{
+		{Number: 10, Repository: "owner/a", Body: "fixes #1, see #2 and #3, also owner/b#1"},
+		{Number: 11, Repository: "owner/a", Body: "same as owner/a#1 and Owner/A#1 and #2, #3"},
+	} {
+		if _, err := findLinkedIssues(t.Context(), FlagData{}, ls, repos, pr); err != nil {
+			t.Fatalf("findLinkedIssues(%d) error = %v", pr.Number, err)
+		}
+	}
+
+	got, err := findLinkedIssues(t.Context(), FlagData{}, ls, repos, gh.PullRequest{Number: 12, Repository: "owner/a", Body: "#1 owner/b#1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []linkedIssue{
+		{NodeID: "I_a", Number: 1, Source: LinkedIssueSourceBody},
+		{NodeID: "I_b", Number: 1, Source: LinkedIssueSourceBody},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findLinkedIssues() = %+v, want %+v", got, want)
+	}
+
+	for ref, n := range lookups {
+		if n != 1 {
+			t.Errorf("%s looked up %d times, want once", ref, n)
+		}
+	}
+	if len(lookups) != 4 {
+		t.Errorf("looked up %v, want owner/a#1-3 and owner/b#1", lookups)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// loadLogging sets the --log-format and adds the job's fields to the command's context, so every line
+// logged during the run can be tied back to it
+func loadLogging(cmd *cobra.Command) error {
+	if err := clog.SetFormat(viper.GetString("log-format")); err != nil {
+		return err
+	}
+
+	fields := logrus.Fields{
+		"job":    cmd.Name(),
+		"run_id": newRunID(),
+	}
+	if owner, number := viper.GetString("project-owner"), viper.GetInt("project-number"); owner != "" && number != 0 {
+		fields["project"] = fmt.Sprintf("%s/%d", owner, number)
+	}
+	cmd.SetContext(clog.WithFields(cmd.Context(), fields))
+
+	return nil
+}
+
+// newRunID returns a short random id for the run
+func newRunID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// itemContext adds an issue or pr's fields to the context so its log lines, and those of the api calls made
+// for it, can be correlated
+func itemContext(ctx context.Context, repo string, number int, nodeID string) context.Context {
+	return clog.WithFields(ctx, logrus.Fields{
+		"repo":    repo,
+		"number":  number,
+		"node_id": nodeID,
+	})
+}
+
+// logItem logs the result of an action on a project item, ie `add` or `update`, for log pipelines. It is
+// logged at INFO whether or not it failed as the failure is already printed with the progress.
+func logItem(ctx context.Context, action string, itemID *string, err error) {
+	l := clog.From(ctx).WithField("action", action)
+	if itemID != nil {
+		l = l.WithField("item_id", *itemID)
+	}
+
+	if err != nil {
+		l.WithError(err).WithField("result", "failed").Infof("%s failed", action)
+		return
+	}
+
+	l.WithField("result", "ok").Info(action)
+}
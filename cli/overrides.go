@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// RepoOverride holds the settings from a --config file `repo-overrides` block, applied on top of the
+// global settings for repos matching the pattern:
+//
+//	repo-overrides:
+//	  hashicorp/terraform-provider-azurerm:
+//	    waiting-labels: [waiting-reply]
+//	    status-rules: ["Stale=days-since-author-activity > 30d"]
+//	    set: ["Repo=azurerm"]
+type RepoOverride struct {
+	Pattern       string   `yaml:"-"`
+	WaitingLabels []string `yaml:"waiting-labels"` // replaces the global waiting labels
+	StatusRules   []string `yaml:"status-rules"`   // checked before the global status rules
+	Set           []string `yaml:"set"`            // fixed `Field=Value` fields set on every item
+}
+
+// loadConfig reads the --config file if one was given, its keys are the same as the flag names.
+func loadConfig() error {
+	file := viper.GetString("config")
+	if file == "" {
+		return nil
+	}
+
+	viper.SetConfigFile(file)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("reading config file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// GetRepoOverrides returns the repo overrides from the config file in the order they are written. They are
+// read from the file rather than viper as it lowercases keys and splits them on dots, which would break
+// patterns such as `owner/repo.name`, and loses their order.
+func GetRepoOverrides() ([]RepoOverride, error) {
+	file := viper.GetString("config")
+	if file == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %w", file, err)
+	}
+
+	var config struct {
+		RepoOverrides yaml.Node `yaml:"repo-overrides"`
+	}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", file, err)
+	}
+
+	n := config.RepoOverrides
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.MappingNode:
+	default:
+		return nil, errors.New("parsing repo-overrides: expected a map of repo patterns to overrides")
+	}
+
+	// mapping nodes alternate keys and values
+	overrides := make([]RepoOverride, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var o RepoOverride
+		if err := n.Content[i+1].Decode(&o); err != nil {
+			return nil, fmt.Errorf("parsing repo-overrides %s: %w", n.Content[i].Value, err)
+		}
+		o.Pattern = n.Content[i].Value
+		overrides = append(overrides, o)
+	}
+
+	return overrides, nil
+}
+
+// ForRepo returns the flags with the overrides for an owner/name repo layered on top in config file order,
+// so when several match a repo the later ones win.
+func (f FlagData) ForRepo(repo string) (FlagData, error) {
+	rf := f
+	rf.StatusRules = append([]string{}, f.StatusRules...)
+
+	for _, o := range f.RepoOverrides {
+		match, err := matchesRepoPattern(repo, []string{o.Pattern})
+		if err != nil {
+			return rf, fmt.Errorf("repo override: %w", err)
+		}
+		if !match {
+			continue
+		}
+
+		if len(o.WaitingLabels) > 0 {
+			rf.WaitingLabels = o.WaitingLabels
+		}
+		rf.StatusRules = append(append([]string{}, o.StatusRules...), rf.StatusRules...)
+		rf.Set = append(append([]string{}, rf.Set...), o.Set...)
+	}
+
+	return rf, nil
+}
+
+// GetSetFields resolves the `Field=Value` fixed fields against the project, later values for the same
+// field win so repo overrides can replace global ones.
+func (f FlagData) GetSetFields(p gh.Project) ([]gh.ProjectItemField, error) {
+	byName := map[string]int{}
+	var fields []gh.ProjectItemField
+	for _, s := range f.Set {
+		name, value, found := strings.Cut(s, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid set field %q, expected 'Field=Value'", s)
+		}
+		name = strings.TrimSpace(name)
+
+		field, err := resolveField(p, "set_"+strings.ToLower(strings.ReplaceAll(name, " ", "_")), name, strings.TrimSpace(value), nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid set field %q: %w", s, err)
+		}
+
+		if i, ok := byName[name]; ok {
+			fields[i] = field
+			continue
+		}
+		byName[name] = len(fields)
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// withFields adds the fixed fields replacing any computed field with the same id
+func withFields(fields, set []gh.ProjectItemField) []gh.ProjectItemField {
+	ids := map[string]bool{}
+	for _, s := range set {
+		ids[s.FieldID] = true
+	}
+
+	result := make([]gh.ProjectItemField, 0, len(fields)+len(set))
+	for _, field := range fields {
+		if !ids[field.FieldID] {
+			result = append(result, field)
+		}
+	}
+
+	return append(result, set...)
+}
+
+// repoSettings are the flags, status rules and fixed fields for a repo with its overrides applied
+type repoSettings struct {
+	Flags FlagData
+	Rules []StatusRule
+	Set   []gh.ProjectItemField
+}
+
+// settingsForRepo resolves the settings for a repo, caching them as search results can span many repos
+func (f FlagData) settingsForRepo(p gh.Project, cache map[string]*repoSettings, repo string) (*repoSettings, error) {
+	if s, ok := cache[repo]; ok {
+		return s, nil
+	}
+
+	rf, err := f.ForRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := rf.GetStatusRules(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", repo, err)
+	}
+
+	set, err := rf.GetSetFields(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", repo, err)
+	}
+
+	s := &repoSettings{Flags: rf, Rules: rules, Set: set}
+	cache[repo] = s
+
+	return s, nil
+}
+
+// issueRepo returns the owner/name of an issue's repo, issues from the REST api only have the api url
+func issueRepo(issue github.Issue) string {
+	if name := issue.GetRepository().GetFullName(); name != "" {
+		return name
+	}
+
+	_, repo, _ := strings.Cut(issue.GetRepositoryURL(), "/repos/")
+	return repo
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// plan item actions
+const (
+	PlanActionAdd    = "add"    // the item will be added to the project and its fields set
+	PlanActionUpdate = "update" // the item is in the project and some fields will change
+	PlanActionNoOp   = "no-op"  // the item is in the project and no fields will change
+	PlanActionRemove = "remove" // the item is in the project and no longer matches the filters
+)
+
+// Plan is what a sync would change in a project, written by --plan-file and executed by apply.
+type Plan struct {
+	ProjectOwner  string     `json:"project_owner"`
+	ProjectNumber int        `json:"project_number"`
+	Items         []PlanItem `json:"items"`
+}
+
+// PlanItem is the change to a single issue or pr.
+type PlanItem struct {
+	Action  string       `json:"action"`
+	NodeID  string       `json:"node_id"`
+	ItemID  string       `json:"item_id,omitempty"` // empty when adding
+	Label   string       `json:"label"`
+	Changes []PlanChange `json:"changes,omitempty"`
+}
+
+// PlanChange is a field going from its current to a new value.
+type PlanChange struct {
+	Field    string           `json:"field"`
+	FieldID  string           `json:"field_id"`
+	Type     gh.ItemValueType `json:"type"`
+	OldValue string           `json:"old_value,omitempty"` // normalised current value, used to detect stale plans
+	Old      string           `json:"old,omitempty"`       // current value for display, option names resolved
+	New      string           `json:"new"`
+	Value    any              `json:"value"` // the value to set, option ids for single selects
+}
+
+// Planner reads the current state of a project and builds the plan for a dry run.
+type Planner struct {
+	Plan
+
+	p       gh.Project
+	current map[string]gh.ProjectItemValues // content node id -> item, loaded on first use
+	index   map[string]int                  // content node id -> plan item
+	kept    int                             // items no longer matching the filters left in the project
+}
+
+func NewPlanner(p gh.Project) *Planner {
+	return &Planner{
+		Plan: Plan{ProjectOwner: p.Owner, ProjectNumber: p.Number},
+		p:    p,
+	}
+}
+
+// loadProjectState reads every item in the project with the values of all its fields, keyed by content node id
+func loadProjectState(ctx context.Context, p gh.Project) (map[string]gh.ProjectItemValues, error) {
+	names := make([]string, 0, len(p.Fields))
+	for _, field := range p.Fields {
+		names = append(names, field.Name)
+	}
+
+	current := map[string]gh.ProjectItemValues{}
+	err := p.ListItemFieldValues(ctx, names, func(item gh.ProjectItemValues) bool {
+		current[item.NodeID] = item
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading project items: %w", err)
+	}
+
+	return current, nil
+}
+
+// Item plans setting the fields on an issue or pr, merging with any earlier plan for it, and prints the
+// changes.
+func (pl *Planner) Item(ctx context.Context, nodeID, label string, fields []gh.ProjectItemField) error {
+	if err := pl.load(ctx); err != nil {
+		return err
+	}
+
+	existing, inProject := pl.current[nodeID]
+
+	i, ok := pl.index[nodeID]
+	if !ok {
+		item := PlanItem{Action: PlanActionAdd, NodeID: nodeID, Label: label}
+		if inProject {
+			item.Action, item.ItemID = PlanActionNoOp, existing.ID
+		}
+		pl.Items = append(pl.Items, item)
+		i = len(pl.Items) - 1
+		pl.index[nodeID] = i
+	}
+	item := &pl.Items[i]
+
+	names := map[string]string{}
+	for name, id := range pl.p.FieldIDs {
+		names[id] = name
+	}
+
+	for _, field := range fields {
+		name, ok := names[field.FieldID]
+		if !ok {
+			continue // not in the project, would fail to update
+		}
+		change := PlanChange{
+			Field:   name,
+			FieldID: field.FieldID,
+			Type:    field.Type,
+			New:     displayValue(pl.p, name, field.Type, field.Value),
+			Value:   field.Value,
+		}
+
+		if old, ok := existing.Values[name]; ok {
+			change.OldValue = normaliseValue(old.Type, old.Value)
+			change.Old = displayValue(pl.p, name, old.Type, old.Value)
+		}
+		if inProject && sameValue(field.Type, change.OldValue, normaliseValue(field.Type, field.Value)) {
+			continue
+		}
+
+		// a later change to the same field replaces the earlier one
+		replaced := false
+		for j := range item.Changes {
+			if item.Changes[j].FieldID == field.FieldID {
+				item.Changes[j], replaced = change, true
+			}
+		}
+		if !replaced {
+			item.Changes = append(item.Changes, change)
+		}
+	}
+
+	if item.Action != PlanActionAdd {
+		item.Action = PlanActionNoOp
+		if len(item.Changes) > 0 {
+			item.Action = PlanActionUpdate
+		}
+	}
+
+	printPlanItem(*item)
+	return nil
+}
+
+// Unmatched plans removing an issue or pr that no longer matches the filters from the project when remove
+// is set, otherwise it is reported as kept. Items not in the project are ignored.
+func (pl *Planner) Unmatched(ctx context.Context, nodeID, label string, remove bool) error {
+	if err := pl.load(ctx); err != nil {
+		return err
+	}
+
+	existing, inProject := pl.current[nodeID]
+	if !inProject {
+		return nil
+	}
+	if _, ok := pl.index[nodeID]; ok {
+		return nil // planned by another sync, ie a linked issue
+	}
+
+	if !remove {
+		pl.kept++
+		c.Printf("  <gray>  kept %s, no longer matches the filters</>\n", label)
+		return nil
+	}
+
+	item := PlanItem{Action: PlanActionRemove, NodeID: nodeID, ItemID: existing.ID, Label: label}
+	pl.Items = append(pl.Items, item)
+	pl.index[nodeID] = len(pl.Items) - 1
+
+	printPlanItem(item)
+	return nil
+}
+
+// load reads the current project state on first use
+func (pl *Planner) load(ctx context.Context) error {
+	if pl.current != nil {
+		return nil
+	}
+
+	c.Printf("  <gray>reading current project state for the plan..</> ")
+	current, err := loadProjectState(ctx, pl.p)
+	if err != nil {
+		return err
+	}
+	c.Printf("<yellow>%d</> items\n", len(current))
+
+	pl.current = current
+	pl.index = map[string]int{}
+	return nil
+}
+
+func printPlanItem(item PlanItem) {
+	switch item.Action {
+	case PlanActionAdd:
+		c.Printf("  <green>+ add</> %s\n", item.Label)
+	case PlanActionUpdate:
+		c.Printf("  <yellow>~ update</> %s\n", item.Label)
+	case PlanActionRemove:
+		c.Printf("  <red>- remove</> %s\n", item.Label)
+	default:
+		c.Printf("  <gray>  no-op %s</>\n", item.Label)
+	}
+
+	for _, ch := range item.Changes {
+		if ch.Old == "" {
+			c.Printf("      <lightBlue>%s</>: <green>%s</>\n", ch.Field, ch.New)
+		} else {
+			c.Printf("      <lightBlue>%s</>: <red>%s</> -> <green>%s</>\n", ch.Field, ch.Old, ch.New)
+		}
+	}
+}
+
+// Finish prints the plan summary and writes the plan file if one was given
+func (pl *Planner) Finish(planFile string) error {
+	counts := map[string]int{}
+	changes := 0
+	for _, item := range pl.Items {
+		counts[item.Action]++
+		changes += len(item.Changes)
+	}
+
+	c.Printf("<white>Plan:</> <green>%d</> to add, <yellow>%d</> to update, <red>%d</> to remove, <gray>%d</> unchanged, <lightBlue>%d</> field changes\n",
+		counts[PlanActionAdd], counts[PlanActionUpdate], counts[PlanActionRemove], counts[PlanActionNoOp], changes)
+	if pl.kept > 0 {
+		c.Printf("  <gray>%d</> items no longer matching the filters kept, <white>--remove-unmatched</> removes them\n", pl.kept)
+	}
+
+	if planFile == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(pl.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding plan: %w", err)
+	}
+	if err := os.WriteFile(planFile, b, 0o600); err != nil {
+		return fmt.Errorf("writing plan %s: %w", planFile, err)
+	}
+	c.Printf("Plan written to <cyan>%s</>, run <white>ghp-sync apply --plan-file %s</> to apply it\n", planFile, planFile)
+
+	return nil
+}
+
+// normaliseValue returns a field value as a comparable string, numbers in their shortest form so 3 and 3.0
+// are the same
+func normaliseValue(t gh.ItemValueType, v any) string {
+	s := fmt.Sprint(v)
+	if t == gh.ItemValueTypeNumber {
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	}
+
+	return s
+}
+
+// sameValue returns true if two normalised values are equal, numbers are compared by value as plans written
+// by older versions may hold them formatted differently
+func sameValue(t gh.ItemValueType, a, b string) bool {
+	if t == gh.ItemValueTypeNumber {
+		x, xerr := strconv.ParseFloat(a, 64)
+		y, yerr := strconv.ParseFloat(b, 64)
+		if xerr == nil && yerr == nil {
+			return x == y
+		}
+	}
+
+	return a == b
+}
+
+// displayValue returns a field value for display, single select option names instead of ids
+func displayValue(p gh.Project, fieldName string, t gh.ItemValueType, v any) string {
+	if t == gh.ItemValueTypeSingleSelect {
+		if name, ok := p.SingleSelectOptionNames[fieldName][fmt.Sprint(v)]; ok {
+			return name
+		}
+	}
+
+	return normaliseValue(t, v)
+}
+
+// finishPlan finishes the plan of a dry run
+func finishPlan(f FlagData, pl *Planner) error {
+	if pl == nil {
+		return nil
+	}
+
+	return pl.Finish(f.PlanFile)
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+func TestNormaliseValue(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		typ  gh.ItemValueType
+		v    any
+		want string
+	}{
+		{gh.ItemValueTypeNumber, 3, "3"},
+		{gh.ItemValueTypeNumber, 3.0, "3"},
+		{gh.ItemValueTypeNumber, int64(3), "3"},
+		{gh.ItemValueTypeNumber, "3.0", "3"},
+		{gh.ItemValueTypeNumber, 2.5, "2.5"},
+		{gh.ItemValueTypeNumber, "n/a", "n/a"},
+		{gh.ItemValueTypeText, "3.0", "3.0"},
+		{gh.ItemValueTypeDate, "2026-01-02", "2026-01-02"},
+		{gh.ItemValueTypeSingleSelect, "opt1", "opt1"},
+	}
+
+	for _, tc := range cases {
+		if got := normaliseValue(tc.typ, tc.v); got != tc.want {
+			t.Errorf("normaliseValue(%v, %#v) = %q, want %q", tc.typ, tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestSameValue(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		typ  gh.ItemValueType
+		a, b string
+		want bool
+	}{
+		{gh.ItemValueTypeNumber, "3", "3", true},
+		{gh.ItemValueTypeNumber, "3", "3.0", true},
+		{gh.ItemValueTypeNumber, "3", "3.5", false},
+		{gh.ItemValueTypeNumber, "", "0", false},
+		{gh.ItemValueTypeNumber, "", "", true},
+		{gh.ItemValueTypeText, "3", "3.0", false},
+		{gh.ItemValueTypeText, "a", "a", true},
+	}
+
+	for _, tc := range cases {
+		if got := sameValue(tc.typ, tc.a, tc.b); got != tc.want {
+			t.Errorf("sameValue(%v, %q, %q) = %t, want %t", tc.typ, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// testPlanner returns a planner for a project with Count, Status and Notes fields holding current, without
+// reading the project
+func testPlanner(current map[string]gh.ProjectItemValues) *Planner {
+	p := gh.Project{Owner: "owner", Number: 1, ProjectDetails: &gh.ProjectDetails{
+		FieldIDs: map[string]string{"Count": "F_COUNT", "Status": "F_STATUS", "Notes": "F_NOTES"},
+		SingleSelectOptionNames: map[string]map[string]string{
+			"Status": {"OPT_TODO": "Todo", "OPT_DONE": "Done"},
+		},
+	}}
+
+	pl := NewPlanner(p)
+	pl.current = current
+	pl.index = map[string]int{}
+
+	return pl
+}
+
+func count(v any) gh.ProjectItemField {
+	return gh.ProjectItemField{Name: "Count", FieldID: "F_COUNT", Type: gh.ItemValueTypeNumber, Value: v}
+}
+
+func status(optionID string) gh.ProjectItemField {
+	return gh.ProjectItemField{Name: "Status", FieldID: "F_STATUS", Type: gh.ItemValueTypeSingleSelect, Value: optionID}
+}
+
+func projectItem(id string, count float64, status string) gh.ProjectItemValues {
+	return gh.ProjectItemValues{ID: id, Values: map[string]gh.ProjectItemFieldValue{
+		"Count":  {Type: gh.ItemValueTypeNumber, Value: count},
+		"Status": {Type: gh.ItemValueTypeSingleSelect, Value: status},
+	}}
+}
+
+func TestPlannerItem(t *testing.T) {
+	t.Parallel()
+
+	pl := testPlanner(map[string]gh.ProjectItemValues{
+		"PR_same":    projectItem("ITEM_same", 3, "OPT_TODO"),
+		"PR_changed": projectItem("ITEM_changed", 3, "OPT_TODO"),
+	})
+
+	steps := []struct {
+		nodeID string
+		fields []gh.ProjectItemField
+	}{
+		// the project's 3.0 is the same number as 3
+		{"PR_same", []gh.ProjectItemField{count(3), status("OPT_TODO")}},
+		{"PR_changed", []gh.ProjectItemField{count(4), status("OPT_TODO")}},
+		// a later change to a field replaces the earlier one, fields not in the project are skipped
+		{"PR_changed", []gh.ProjectItemField{status("OPT_DONE"), {Name: "Missing", FieldID: "F_MISSING", Type: gh.ItemValueTypeText, Value: "x"}}},
+		{"PR_changed", []gh.ProjectItemField{count(5)}},
+		{"PR_new", []gh.ProjectItemField{count(1)}},
+	}
+	for _, s := range steps {
+		if err := pl.Item(t.Context(), s.nodeID, s.nodeID, s.fields); err != nil {
+			t.Fatalf("Item(%s) error = %v", s.nodeID, err)
+		}
+	}
+
+	want := []PlanItem{
+		{Action: PlanActionNoOp, NodeID: "PR_same", ItemID: "ITEM_same", Label: "PR_same"},
+		{Action: PlanActionUpdate, NodeID: "PR_changed", ItemID: "ITEM_changed", Label: "PR_changed", Changes: []PlanChange{
+			{Field: "Count", FieldID: "F_COUNT", Type: gh.ItemValueTypeNumber, OldValue: "3", Old: "3", New: "5", Value: 5},
+			{Field: "Status", FieldID: "F_STATUS", Type: gh.ItemValueTypeSingleSelect, OldValue: "OPT_TODO", Old: "Todo", New: "Done", Value: "OPT_DONE"},
+		}},
+		{Action: PlanActionAdd, NodeID: "PR_new", Label: "PR_new", Changes: []PlanChange{
+			{Field: "Count", FieldID: "F_COUNT", Type: gh.ItemValueTypeNumber, New: "1", Value: 1},
+		}},
+	}
+	if !reflect.DeepEqual(pl.Items, want) {
+		t.Errorf("plan items =\n%+v\nwant\n%+v", pl.Items, want)
+	}
+}
+
+func TestPlannerUnmatched(t *testing.T) {
+	t.Parallel()
+
+	pl := testPlanner(map[string]gh.ProjectItemValues{
+		"PR_synced": projectItem("ITEM_synced", 1, "OPT_TODO"),
+		"PR_old":    projectItem("ITEM_old", 1, "OPT_TODO"),
+		"PR_kept":   projectItem("ITEM_kept", 1, "OPT_TODO"),
+	})
+
+	if err := pl.Item(t.Context(), "PR_synced", "PR_synced", []gh.ProjectItemField{count(1)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []struct {
+		nodeID string
+		remove bool
+	}{
+		{"PR_synced", true}, // planned by another sync
+		{"PR_old", true},
+		{"PR_kept", false},
+		{"PR_elsewhere", true}, // not in the project
+	} {
+		if err := pl.Unmatched(t.Context(), u.nodeID, u.nodeID, u.remove); err != nil {
+			t.Fatalf("Unmatched(%s) error = %v", u.nodeID, err)
+		}
+	}
+
+	want := []PlanItem{
+		{Action: PlanActionNoOp, NodeID: "PR_synced", ItemID: "ITEM_synced", Label: "PR_synced"},
+		{Action: PlanActionRemove, NodeID: "PR_old", ItemID: "ITEM_old", Label: "PR_old"},
+	}
+	if !reflect.DeepEqual(pl.Items, want) {
+		t.Errorf("plan items = %+v, want %+v", pl.Items, want)
+	}
+	if pl.kept != 1 {
+		t.Errorf("kept = %d, want 1", pl.kept)
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	t.Parallel()
+
+	change := func(field string, typ gh.ItemValueType, old string) PlanChange {
+		return PlanChange{Field: field, FieldID: "F_" + field, Type: typ, OldValue: old}
+	}
+
+	plan := Plan{Items: []PlanItem{
+		{Action: PlanActionNoOp, NodeID: "PR_noop"},
+		{Action: PlanActionUpdate, NodeID: "PR_update", Changes: []PlanChange{change("Count", gh.ItemValueTypeNumber, "3")}},
+		// written before numbers were normalised
+		{Action: PlanActionUpdate, NodeID: "PR_formatted", Changes: []PlanChange{change("Count", gh.ItemValueTypeNumber, "3.0")}},
+		{Action: PlanActionUpdate, NodeID: "PR_stale", Changes: []PlanChange{change("Count", gh.ItemValueTypeNumber, "3")}},
+		{Action: PlanActionUpdate, NodeID: "PR_status", Changes: []PlanChange{change("Status", gh.ItemValueTypeSingleSelect, "OPT_TODO")}},
+		{Action: PlanActionAdd, NodeID: "PR_add", Changes: []PlanChange{change("Count", gh.ItemValueTypeNumber, "")}},
+		// added by something else since
+		{Action: PlanActionAdd, NodeID: "PR_added", Changes: []PlanChange{change("Count", gh.ItemValueTypeNumber, "")}},
+		{Action: PlanActionRemove, NodeID: "PR_remove"},
+		{Action: PlanActionRemove, NodeID: "PR_removed"},
+	}}
+	current := map[string]gh.ProjectItemValues{
+		"PR_noop":      projectItem("ITEM_noop", 3, "OPT_TODO"),
+		"PR_update":    projectItem("ITEM_update", 3, "OPT_TODO"),
+		"PR_formatted": projectItem("ITEM_formatted", 3, "OPT_TODO"),
+		"PR_stale":     projectItem("ITEM_stale", 4, "OPT_TODO"),
+		"PR_status":    projectItem("ITEM_status", 3, "OPT_DONE"),
+		"PR_added":     projectItem("ITEM_added", 2, "OPT_TODO"),
+		"PR_remove":    projectItem("ITEM_remove", 3, "OPT_TODO"),
+	}
+
+	pending, removes, itemIDs, stale := checkPlan(plan, current)
+
+	nodeIDs := func(items []PlanItem) []string {
+		ids := make([]string, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.NodeID)
+		}
+		return ids
+	}
+	if got, want := nodeIDs(pending), []string{"PR_update", "PR_formatted", "PR_add"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("checkPlan() pending = %v, want %v", got, want)
+	}
+	if got, want := nodeIDs(removes), []string{"PR_remove"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("checkPlan() removes = %v, want %v", got, want)
+	}
+	wantIDs := map[string]string{"PR_update": "ITEM_update", "PR_formatted": "ITEM_formatted", "PR_remove": "ITEM_remove"}
+	if !reflect.DeepEqual(itemIDs, wantIDs) {
+		t.Errorf("checkPlan() item ids = %v, want %v", itemIDs, wantIDs)
+	}
+	if stale != 3 {
+		t.Errorf("checkPlan() stale = %d, want 3", stale)
+	}
+}
@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// push mapping targets
+const (
+	PushTargetLabel     = "label"
+	PushTargetMilestone = "milestone"
+	PushTargetAssignees = "assignees"
+)
+
+// PushMapping pushes a project field back to the issue/pr:
+//
+//	Priority=label:priority/         sets the priority/<value> label, removing other priority/ labels
+//	Status:Blocked=label:blocked     adds the blocked label while Status is Blocked, removes it otherwise
+//	Target=milestone                 sets the milestone with the same title as the value
+//	Status:Blocked=milestone:Blocked sets the Blocked milestone while Status is Blocked, clears it otherwise
+//	Owners=assignees                 sets the assignees to the comma separated logins
+//
+// Empty field values are never pushed.
+type PushMapping struct {
+	Field  string
+	Value  string // only when the field has this value, empty for any value
+	Target string
+	Arg    string // label prefix or name, milestone title
+}
+
+// GetPushMappings parses the push mappings, checking the fields exist in the project.
+func (f FlagData) GetPushMappings(p gh.Project) ([]PushMapping, error) {
+	mappings := make([]PushMapping, 0, len(f.PushMappings))
+	for _, s := range f.PushMappings {
+		lhs, rhs, found := strings.Cut(s, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid push mapping %q, expected 'Field[:Value]=target[:arg]'", s)
+		}
+
+		m := PushMapping{}
+		m.Field, m.Value, _ = strings.Cut(strings.TrimSpace(lhs), ":")
+		m.Target, m.Arg, _ = strings.Cut(strings.TrimSpace(rhs), ":")
+
+		if _, ok := p.FieldIDs[m.Field]; !ok {
+			return nil, fmt.Errorf("push mapping %q: field %q not found in project", s, m.Field)
+		}
+
+		switch m.Target {
+		case PushTargetLabel:
+			if m.Arg == "" {
+				return nil, fmt.Errorf("push mapping %q: label requires a prefix or name, ie 'label:priority/'", s)
+			}
+		case PushTargetMilestone:
+			if m.Value != "" && m.Arg == "" {
+				return nil, fmt.Errorf("push mapping %q: a milestone title is required when matching a value", s)
+			}
+			if m.Value == "" && m.Arg != "" {
+				return nil, fmt.Errorf("push mapping %q: the milestone title comes from the field unless matching a value", s)
+			}
+		case PushTargetAssignees:
+			if m.Value != "" || m.Arg != "" {
+				return nil, fmt.Errorf("push mapping %q: assignees are set from the field value, expected 'Field=assignees'", s)
+			}
+		default:
+			return nil, fmt.Errorf("push mapping %q: unknown target %q, expected label, milestone or assignees", s, m.Target)
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	return mappings, nil
+}
+
+func (m PushMapping) String() string {
+	lhs, rhs := m.Field, m.Target
+	if m.Value != "" {
+		lhs += ":" + m.Value
+	}
+	if m.Arg != "" {
+		rhs += ":" + m.Arg
+	}
+
+	return lhs + "=" + rhs
+}
+
+// Current returns the issue/pr's value for the mapping target, sorted labels or assignees, the milestone
+// title, or for a label matching a value the label when it is present. It is empty when there are none.
+func (m PushMapping) Current(issue *github.Issue) []string {
+	var current []string
+	switch m.Target {
+	case PushTargetLabel:
+		for _, l := range issue.Labels {
+			if m.Value != "" && strings.EqualFold(l.GetName(), m.Arg) {
+				return []string{l.GetName()}
+			}
+			if m.Value == "" && strings.HasPrefix(strings.ToLower(l.GetName()), strings.ToLower(m.Arg)) {
+				current = append(current, l.GetName())
+			}
+		}
+
+	case PushTargetMilestone:
+		if t := issue.GetMilestone().GetTitle(); t != "" {
+			current = append(current, t)
+		}
+
+	case PushTargetAssignees:
+		for _, a := range issue.Assignees {
+			current = append(current, a.GetLogin())
+		}
+	}
+
+	sort.Strings(current)
+	return current
+}
+
+// Desired returns what the target should be for a field value, false if there is nothing to push
+func (m PushMapping) Desired(value string, current []string) ([]string, bool) {
+	if value == "" {
+		return nil, false
+	}
+
+	switch m.Target {
+	case PushTargetLabel:
+		if m.Value == "" {
+			return []string{m.Arg + value}, true
+		}
+		if strings.EqualFold(value, m.Value) {
+			return []string{m.Arg}, true
+		}
+		return nil, true
+
+	case PushTargetMilestone:
+		if m.Value == "" {
+			return []string{value}, true
+		}
+		if strings.EqualFold(value, m.Value) {
+			return []string{m.Arg}, true
+		}
+		if samePushValue(current, []string{m.Arg}) {
+			return nil, true // no longer matches, clear it
+		}
+		return current, true
+
+	case PushTargetAssignees:
+		var logins []string
+		for _, l := range strings.Split(value, ",") {
+			if l = strings.TrimPrefix(strings.TrimSpace(l), "@"); l != "" {
+				logins = append(logins, l)
+			}
+		}
+		sort.Strings(logins)
+		return logins, true
+	}
+
+	return nil, false
+}
+
+// Apply changes the issue/pr from the current to the desired target value
+func (m PushMapping) Apply(ctx context.Context, r *gh.Repo, number int, current, desired []string) error {
+	switch m.Target {
+	case PushTargetLabel:
+		if m.Value != "" {
+			if len(desired) > 0 {
+				return r.AddLabels(ctx, number, desired)
+			}
+			return r.RemoveLabel(ctx, number, m.Arg)
+		}
+
+		for _, l := range current {
+			if !containsFold(desired, l) {
+				if err := r.RemoveLabel(ctx, number, l); err != nil {
+					return err
+				}
+			}
+		}
+		return r.AddLabels(ctx, number, desired)
+
+	case PushTargetMilestone:
+		if len(desired) == 0 {
+			return r.RemoveMilestone(ctx, number)
+		}
+		return r.SetMilestone(ctx, number, desired[0])
+
+	case PushTargetAssignees:
+		var add, remove []string
+		for _, l := range desired {
+			if !containsFold(current, l) {
+				add = append(add, l)
+			}
+		}
+		for _, l := range current {
+			if !containsFold(desired, l) {
+				remove = append(remove, l)
+			}
+		}
+
+		if len(add) > 0 {
+			if err := r.AddAssignees(ctx, number, add); err != nil {
+				return err
+			}
+		}
+		if len(remove) > 0 {
+			return r.RemoveAssignees(ctx, number, remove)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown push target %q", m.Target)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// samePushValue returns true if two target values hold the same labels, logins or milestone, ignoring case
+// and order
+func samePushValue(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, s := range a {
+		if !containsFold(b, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pushValueText returns a target value for display
+func pushValueText(v []string) string {
+	if len(v) == 0 {
+		return "<none>"
+	}
+
+	return strings.Join(v, ", ")
+}
+
+// PushStateEntry is the field and github values of a mapping the last time they were in sync.
+type PushStateEntry struct {
+	Field  string   `json:"field"`
+	GitHub []string `json:"github"` // labels can contain commas so lists are kept as arrays
+}
+
+// Conflicts returns true if github changed from current since the last push, an empty field at the last push
+// is no baseline for github changes
+func (e PushStateEntry) Conflicts(current []string) bool {
+	return e.Field != "" && !samePushValue(e.GitHub, current)
+}
+
+// PushState records the values at the last push keyed by item node id and mapping, so a change on both
+// sides since then can be detected as a conflict.
+type PushState map[string]map[string]PushStateEntry
+
+// LoadPushState reads the push state file, a missing file is an empty state.
+func LoadPushState(path string) (PushState, error) {
+	s := PushState{}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading push state %s: %w", path, err)
+	}
+
+	var raw map[string]map[string]struct {
+		Field  string          `json:"field"`
+		GitHub json.RawMessage `json:"github"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parsing push state %s: %w", path, err)
+	}
+
+	// entries written before the github values were arrays can't be split reliably, so they are dropped and
+	// recorded again on the next push
+	for nodeID, mappings := range raw {
+		for m, r := range mappings {
+			var github []string
+			if err := json.Unmarshal(r.GitHub, &github); err != nil {
+				continue
+			}
+			if s[nodeID] == nil {
+				s[nodeID] = map[string]PushStateEntry{}
+			}
+			s[nodeID][m] = PushStateEntry{Field: r.Field, GitHub: github}
+		}
+	}
+
+	return s, nil
+}
+
+// Save writes the push state file.
+func (s PushState) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding push state: %w", err)
+	}
+
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("writing push state %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Get returns the state of a mapping for an item
+func (s PushState) Get(nodeID string, m PushMapping) (PushStateEntry, bool) {
+	e, ok := s[nodeID][m.String()]
+	return e, ok
+}
+
+// Set records the state of a mapping for an item
+func (s PushState) Set(nodeID string, m PushMapping, e PushStateEntry) {
+	if s[nodeID] == nil {
+		s[nodeID] = map[string]PushStateEntry{}
+	}
+	s[nodeID][m.String()] = e
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/pointer"
+)
+
+func testIssue(milestone string, labels []string, assignees []string) *github.Issue {
+	issue := &github.Issue{}
+	if milestone != "" {
+		issue.Milestone = &github.Milestone{Title: pointer.To(milestone)}
+	}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, &github.Label{Name: pointer.To(l)})
+	}
+	for _, a := range assignees {
+		issue.Assignees = append(issue.Assignees, &github.User{Login: pointer.To(a)})
+	}
+
+	return issue
+}
+
+var (
+	pushPriorityLabel    = PushMapping{Field: "Priority", Target: PushTargetLabel, Arg: "priority/"}
+	pushBlockedLabel     = PushMapping{Field: "Status", Value: "Blocked", Target: PushTargetLabel, Arg: "blocked"}
+	pushTargetMilestone  = PushMapping{Field: "Target", Target: PushTargetMilestone}
+	pushBlockedMilestone = PushMapping{Field: "Status", Value: "Blocked", Target: PushTargetMilestone, Arg: "Blocked"}
+	pushOwnerAssignees   = PushMapping{Field: "Owners", Target: PushTargetAssignees}
+)
+
+func TestPushMappingCurrent(t *testing.T) {
+	t.Parallel()
+
+	issue := testIssue("v1.2", []string{"bug", "Priority/high, urgent", "priority/low", "Blocked"}, []string{"zed", "alice"})
+
+	cases := []struct {
+		m    PushMapping
+		want []string
+	}{
+		{pushPriorityLabel, []string{"Priority/high, urgent", "priority/low"}},
+		{pushBlockedLabel, []string{"Blocked"}},
+		{PushMapping{Field: "Status", Value: "Done", Target: PushTargetLabel, Arg: "done"}, nil},
+		{pushTargetMilestone, []string{"v1.2"}},
+		{pushOwnerAssignees, []string{"alice", "zed"}},
+	}
+
+	for _, tc := range cases {
+		if got := tc.m.Current(issue); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s Current() = %q, want %q", tc.m, got, tc.want)
+		}
+	}
+
+	empty := testIssue("", nil, nil)
+	for _, m := range []PushMapping{pushPriorityLabel, pushBlockedLabel, pushTargetMilestone, pushOwnerAssignees} {
+		if got := m.Current(empty); len(got) != 0 {
+			t.Errorf("%s Current() of an issue without any = %q, want none", m, got)
+		}
+	}
+}
+
+func TestPushMappingDesired(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		m       PushMapping
+		value   string
+		current []string
+		want    []string
+		ok      bool
+	}{
+		{"empty field", pushPriorityLabel, "", []string{"priority/low"}, nil, false},
+		{"label prefix", pushPriorityLabel, "high, urgent", []string{"priority/low"}, []string{"priority/high, urgent"}, true},
+		{"label matching value", pushBlockedLabel, "blocked", nil, []string{"blocked"}, true},
+		{"label not matching value", pushBlockedLabel, "Todo", []string{"blocked"}, nil, true},
+		{"milestone from field", pushTargetMilestone, "v2.0", []string{"v1.2"}, []string{"v2.0"}, true},
+		{"milestone matching value", pushBlockedMilestone, "Blocked", []string{"v1.2"}, []string{"Blocked"}, true},
+		{"milestone no longer matching", pushBlockedMilestone, "Todo", []string{"blocked"}, nil, true},
+		{"other milestone kept", pushBlockedMilestone, "Todo", []string{"v1.2"}, []string{"v1.2"}, true},
+		{"assignees", pushOwnerAssignees, "@zed, alice,,", nil, []string{"alice", "zed"}, true},
+	}
+
+	for _, tc := range cases {
+		got, ok := tc.m.Desired(tc.value, tc.current)
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Desired(%q, %q) = %q, %t, want %q, %t", tc.name, tc.value, tc.current, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSamePushValue(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		a, b []string
+		want bool
+	}{
+		{nil, nil, true},
+		{nil, []string{}, true},
+		{[]string{"Blocked"}, []string{"blocked"}, true},
+		{[]string{"alice", "zed"}, []string{"zed", "Alice"}, true},
+		// a label containing a comma isn't two labels
+		{[]string{"high, urgent"}, []string{"high", "urgent"}, false},
+		{[]string{"alice"}, []string{"alice", "zed"}, false},
+		{[]string{"v1.2"}, nil, false},
+	}
+
+	for _, tc := range cases {
+		if got := samePushValue(tc.a, tc.b); got != tc.want {
+			t.Errorf("samePushValue(%q, %q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestPushStateEntryConflicts(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		last    PushStateEntry
+		current []string
+		want    bool
+	}{
+		{"github unchanged", PushStateEntry{Field: "high", GitHub: []string{"priority/high"}}, []string{"priority/high"}, false},
+		{"github changed case only", PushStateEntry{Field: "high", GitHub: []string{"priority/high"}}, []string{"Priority/High"}, false},
+		{"github changed", PushStateEntry{Field: "high", GitHub: []string{"priority/high"}}, []string{"priority/low"}, true},
+		{"github cleared", PushStateEntry{Field: "v1", GitHub: []string{"v1"}}, nil, true},
+		{"comma in label", PushStateEntry{Field: "a, b", GitHub: []string{"x/a, b"}}, []string{"x/a", "x/b"}, true},
+		{"field was empty", PushStateEntry{GitHub: []string{"priority/high"}}, []string{"priority/low"}, false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.last.Conflicts(tc.current); got != tc.want {
+			t.Errorf("%s: Conflicts(%q) = %t, want %t", tc.name, tc.current, got, tc.want)
+		}
+	}
+}
+
+func TestPushStateSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "push.json")
+
+	s, err := LoadPushState(path)
+	if err != nil || len(s) != 0 {
+		t.Fatalf("LoadPushState() of a missing file = %v, %v, want an empty state", s, err)
+	}
+
+	s.Set("I_1", pushPriorityLabel, PushStateEntry{Field: "high, urgent", GitHub: []string{"priority/high, urgent"}})
+	s.Set("I_1", pushOwnerAssignees, PushStateEntry{Field: "alice,zed", GitHub: []string{"alice", "zed"}})
+	s.Set("I_2", pushTargetMilestone, PushStateEntry{Field: "", GitHub: nil})
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadPushState(path)
+	if err != nil {
+		t.Fatalf("LoadPushState() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("LoadPushState() = %v, want %v", loaded, s)
+	}
+	if e, ok := loaded.Get("I_1", pushPriorityLabel); !ok || len(e.GitHub) != 1 {
+		t.Errorf("Get() = %v, %t, want the one label", e, ok)
+	}
+}
+
+func TestPushStateLoadDropsJoinedValues(t *testing.T) {
+	t.Parallel()
+
+	// written when lists were comma joined strings
+	path := filepath.Join(t.TempDir(), "push.json")
+	legacy := `{
+  "I_1": {
+    "Priority=label:priority/": {"field": "high", "github": "priority/high,priority/low"},
+    "Owners=assignees": {"field": "alice", "github": ["alice"]}
+  }
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadPushState(path)
+	if err != nil {
+		t.Fatalf("LoadPushState() error = %v", err)
+	}
+	if _, ok := s.Get("I_1", pushPriorityLabel); ok {
+		t.Errorf("joined github value was loaded")
+	}
+	if e, ok := s.Get("I_1", pushOwnerAssignees); !ok || !reflect.DeepEqual(e.GitHub, []string{"alice"}) {
+		t.Errorf("Get() = %v, %t, want alice", e, ok)
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	c "github.com/gookit/color"
+)
+
+// orgRepos caches the repositories listed for each org for the run
+var orgRepos = map[string][]*github.Repository{}
+
+// ResolveRepos expands the patterns (`owner/terraform-provider-*`) and topics (`topic:name` or
+// `owner/topic:name`, the owner defaults to the project owner) in --repos by listing the org's
+// repositories, skipping archived repos unless --include-archived is set, and then removes any
+// repos matching --exclude-repos.
+func (f *FlagData) ResolveRepos(ctx context.Context) error {
+	seen := map[string]bool{}
+	var repos []string
+
+	for _, entry := range f.Repos {
+		owner, pattern, found := strings.Cut(entry, "/")
+		if !found {
+			owner, pattern = f.ProjectOwner, entry
+		}
+
+		topic, isTopic := strings.CutPrefix(pattern, "topic:")
+		if !isTopic && !strings.ContainsAny(pattern, "*?[") {
+			if !found {
+				return fmt.Errorf("invalid repo %q, expected owner/name", entry)
+			}
+			if !seen[entry] {
+				seen[entry] = true
+				repos = append(repos, entry)
+			}
+			continue
+		}
+
+		all, ok := orgRepos[owner]
+		if !ok {
+			c.Printf("Listing repos for <white>%s</> to expand <cyan>%s</>...", owner, entry)
+			var err error
+			all, err = f.Auth.ListOrgRepos(ctx, owner)
+			if err != nil {
+				return fmt.Errorf("listing repos for %s: %w", owner, err)
+			}
+			orgRepos[owner] = all
+			c.Printf(" found <yellow>%d</>\n", len(all))
+		}
+
+		matched := 0
+		for _, r := range all {
+			if r.GetArchived() && !f.IncludeArchived {
+				continue
+			}
+
+			if isTopic {
+				if !hasTopic(r.Topics, topic) {
+					continue
+				}
+			} else if m, err := path.Match(strings.ToLower(pattern), strings.ToLower(r.GetName())); err != nil {
+				return fmt.Errorf("invalid repo pattern %q: %w", entry, err)
+			} else if !m {
+				continue
+			}
+
+			matched++
+			if name := r.GetFullName(); !seen[name] {
+				seen[name] = true
+				repos = append(repos, name)
+			}
+		}
+
+		c.Printf("  <cyan>%s</> matched <yellow>%d</> repos\n", entry, matched)
+	}
+
+	// remove excluded repos
+	filtered := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		excluded, err := matchesRepoPattern(repo, f.ExcludeRepos)
+		if err != nil {
+			return err
+		}
+
+		if excluded {
+			c.Printf("  <gray>excluding %s</>\n", repo)
+			continue
+		}
+		filtered = append(filtered, repo)
+	}
+
+	f.Repos = filtered
+	return nil
+}
+
+func hasTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if strings.EqualFold(t, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesRepoPattern returns true if the owner/name repo matches any of the patterns
+func matchesRepoPattern(repo string, patterns []string) (bool, error) {
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+
+		m, err := path.Match(strings.ToLower(p), strings.ToLower(repo))
+		if err != nil {
+			return false, fmt.Errorf("invalid repo pattern %q: %w", p, err)
+		}
+		if m {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
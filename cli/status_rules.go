@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/katbyte/ghp-sync/lib/filter"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// blockedMilestone is the milestone that marks a pr as blocked
+const blockedMilestone = "Blocked"
+
+// StatusRule sets the project status of PRs matching a filter expression, rules are checked in order
+// before the built-in status logic.
+type StatusRule struct {
+	Status string
+	Expr   filter.Expr
+}
+
+// GetStatusRules parses the `Status=expression` status rules, checking the statuses exist in the project.
+func (f FlagData) GetStatusRules(p gh.Project) ([]StatusRule, error) {
+	rules := make([]StatusRule, 0, len(f.StatusRules))
+	for _, r := range f.StatusRules {
+		status, expression, found := strings.Cut(r, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid status rule %q, expected 'Status=expression'", r)
+		}
+		status = strings.TrimSpace(status)
+
+		if _, ok := p.StatusIDs[status]; !ok {
+			return nil, fmt.Errorf("status rule %q: status %q not found in project", r, status)
+		}
+
+		e, err := filter.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("parsing status rule %q: %w", r, err)
+		}
+
+		rules = append(rules, StatusRule{Status: status, Expr: e})
+	}
+
+	return rules, nil
+}
+
+// addTimelineFilterFields adds the fields computed from a pr's timeline so status rules can use them
+func addTimelineFilterFields(fields filter.Fields, s *gh.TimelineStats) {
+	if s == nil {
+		return
+	}
+
+	fields["ball-in-court"] = s.BallInCourt
+	fields["days-in-court"] = float64(s.DaysInCourt())
+	fields["maintainer-waiting-days"] = float64(s.MaintainerWaitingDays)
+	fields["author-waiting-days"] = float64(s.AuthorWaitingDays)
+	fields["days-since-maintainer-review"] = float64(s.DaysSinceMaintainerReview())
+	fields["days-since-author-activity"] = float64(s.DaysSinceAuthorActivity())
+	fields["days-since-community-comment"] = float64(s.DaysSinceCommunityComment())
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+	"github.com/katbyte/ghp-sync/lib/pointer"
+)
+
+// issueRefValue is how a related issue is shown in the Parent field, `#123` for an issue in the same repo
+// and `owner/name#123` otherwise
+func issueRefValue(issue github.Issue, repo string, number string) string {
+	if strings.EqualFold(repo, issueRepo(issue)) {
+		return "#" + number
+	}
+
+	return repo + "#" + number
+}
+
+// parentFieldValue is the value of the built-in Parent field, the issue's sub-issue parent or failing that
+// the first issue tracking it in its task list
+func parentFieldValue(issue github.Issue, tracked gh.TrackedIssues) string {
+	if _, parent, found := strings.Cut(issue.GetParentIssueURL(), "/repos/"); found {
+		if repo, number, found := strings.Cut(parent, "/issues/"); found {
+			return issueRefValue(issue, repo, number)
+		}
+	}
+
+	if len(tracked.TrackedBy) > 0 {
+		t := tracked.TrackedBy[0]
+		return issueRefValue(issue, t.Repo, strconv.Itoa(t.Number))
+	}
+
+	return ""
+}
+
+// subIssueProgress is the value of the built-in Sub-issue Progress field, `done/total` of the issue's
+// sub-issues or failing that of the issues tracked in its task list
+func subIssueProgress(issue github.Issue, tracked gh.TrackedIssues) string {
+	if s := issue.GetSubIssuesSummary(); s.GetTotal() > 0 {
+		return fmt.Sprintf("%d/%d", s.GetCompleted(), s.GetTotal())
+	}
+
+	if len(tracked.Tracks) == 0 {
+		return ""
+	}
+
+	done := 0
+	for _, t := range tracked.Tracks {
+		if t.Closed {
+			done++
+		}
+	}
+
+	return fmt.Sprintf("%d/%d", done, len(tracked.Tracks))
+}
+
+// trackedIssuesFor gets the task list relationships of issues when the project has a field using them
+func trackedIssuesFor(ctx context.Context, p gh.Project, issues []github.Issue) (map[string]gh.TrackedIssues, error) {
+	_, parent := p.FieldIDs["Parent"]
+	_, progress := p.FieldIDs["Sub-issue Progress"]
+	if !parent && !progress {
+		return nil, nil
+	}
+
+	nodeIDs := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		nodeIDs = append(nodeIDs, issue.GetNodeID())
+	}
+
+	c.Printf("Getting tracked issues of <yellow>%d</> issues...", len(nodeIDs))
+	tracked, err := p.GetTrackedIssues(ctx, nodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("getting tracked issues: %w", err)
+	}
+	c.Printf(" <yellow>%d</>\n", len(tracked))
+
+	return tracked, nil
+}
+
+// addSubIssues adds the sub-issues and tracked issues of every issue in the project that aren't already in
+// it, including those of the issues added, so the whole hierarchy under each issue on the board is in the
+// project.
+func addSubIssues(ctx context.Context, f FlagData, p gh.Project, pl *Planner) (err error) {
+	c.Printf("Getting project items to add sub-issues...")
+	items, err := p.GetItems(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project items: %w", err)
+	}
+	c.Printf(" <yellow>%d</>\n", len(items))
+
+	type queued struct {
+		url    string
+		nodeID string
+	}
+
+	inProject := map[string]bool{}
+	var queue []queued
+	for _, item := range items {
+		inProject[item.NodeID] = true
+		if item.Type == "ISSUE" {
+			queue = append(queue, queued{item.URL, item.NodeID})
+		}
+	}
+
+	repos := map[string]*gh.Repo{}
+	added := 0
+	w := newItemWriter(ctx, f, &p)
+	defer func() {
+		err = w.Close(err)
+	}()
+
+	// add puts a sub-issue or tracked issue of owner/name#number into the project
+	add := func(s github.Issue, tracked gh.TrackedIssues, kind, owner, name string, number int) error {
+		inProject[s.GetNodeID()] = true
+		queue = append(queue, queued{s.GetHTMLURL(), s.GetNodeID()})
+
+		c.Printf("  adding %s <lightCyan>%s</> of <white>%s/%s</>#<cyan>%d</>", kind, s.GetHTMLURL(), owner, name, number)
+		if f.DryRun {
+			c.Printf(".. <yellow>[dry-run]</>\n")
+			if err := pl.Item(ctx, s.GetNodeID(), kind+" "+s.GetHTMLURL(), issueItemFields(ctx, f, p, s, tracked)); err != nil {
+				return err
+			}
+			added++
+			return nil
+		}
+
+		// added in batches, listing its own sub-issues doesn't need it to be in the project yet
+		c.Printf("\n")
+		return w.Write(itemWrite{
+			NodeID: s.GetNodeID(),
+			Fields: issueItemFields(ctx, f, p, s, tracked),
+			Done: func(_ string, err error) error {
+				if err != nil {
+					c.Printf("<red>ERROR!!</> %s %s: %s\n", kind, s.GetHTMLURL(), err)
+					return nil
+				}
+				added++
+				return nil
+			},
+		})
+	}
+
+	for len(queue) > 0 {
+		if err := interrupted(ctx, "%d sub-issues added", added); err != nil {
+			return err
+		}
+		q := queue[0]
+		queue = queue[1:]
+
+		owner, name, _, number, err := gh.ParseGitHubURL(q.url)
+		if err != nil {
+			c.Printf("  <red>ERROR!</> %s: %s\n", q.url, err)
+			continue
+		}
+
+		r, err := cachedRepo(f, repos, owner+"/"+name)
+		if err != nil {
+			return err
+		}
+
+		subIssues, err := r.ListSubIssues(ctx, number)
+		if err != nil {
+			c.Printf("  <red>ERROR!</> %s\n", err)
+			continue
+		}
+
+		for _, s := range subIssues {
+			if inProject[s.GetNodeID()] {
+				continue
+			}
+
+			// the sub-issue list doesn't include the parent
+			if s.ParentIssueURL == nil {
+				s.ParentIssueURL = pointer.To(fmt.Sprintf(gh.APIURL()+"repos/%s/%s/issues/%d", owner, name, number))
+			}
+
+			if err := add(s, gh.TrackedIssues{}, "sub-issue", owner, name, number); err != nil {
+				return err
+			}
+		}
+
+		tracked, err := r.GetTrackedIssues(ctx, []string{q.nodeID})
+		if err != nil {
+			c.Printf("  <red>ERROR!</> %s\n", err)
+			continue
+		}
+
+		parent := gh.IssueRef{NodeID: q.nodeID, Repo: owner + "/" + name, Number: number, URL: q.url}
+		for _, t := range tracked[q.nodeID].Tracks {
+			if inProject[t.NodeID] {
+				continue
+			}
+
+			tr, err := cachedRepo(f, repos, t.Repo)
+			if err != nil {
+				return err
+			}
+			issue, err := tr.GetIssue(ctx, t.Number)
+			if err != nil {
+				c.Printf("  <red>ERROR!</> %s#%d: %s\n", t.Repo, t.Number, err)
+				continue
+			}
+
+			if err := add(*issue, gh.TrackedIssues{TrackedBy: []gh.IssueRef{parent}}, "tracked issue", owner, name, number); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	c.Printf("Added <yellow>%d</> sub-issues and tracked issues\n", added)
+	return nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// teamMembers caches team members for the run keyed by `org/team-slug`
+var teamMembers = map[string][]string{}
+
+// ExpandTeams replaces any `@org/team-slug` entries in the author, assignee and reviewer filters with
+// the logins of the team's members.
+func (f *FlagData) ExpandTeams(ctx context.Context) error {
+	var err error
+
+	if f.Filters.Authors, err = ExpandTeamLogins(ctx, f.Auth, f.Filters.Authors); err != nil {
+		return fmt.Errorf("expanding authors: %w", err)
+	}
+	if f.Filters.Assignees, err = ExpandTeamLogins(ctx, f.Auth, f.Filters.Assignees); err != nil {
+		return fmt.Errorf("expanding assignees: %w", err)
+	}
+	if f.Filters.Reviewers, err = ExpandTeamLogins(ctx, f.Auth, f.Filters.Reviewers); err != nil {
+		return fmt.Errorf("expanding reviewers: %w", err)
+	}
+
+	return nil
+}
+
+// ExpandTeamLogins returns logins with `@org/team-slug` entries replaced by the team's members
+// (including nested teams), removing any duplicates.
+func ExpandTeamLogins(ctx context.Context, token gh.Token, logins []string) ([]string, error) {
+	seen := map[string]bool{}
+	expanded := make([]string, 0, len(logins))
+	add := func(login string) {
+		if !seen[login] {
+			seen[login] = true
+			expanded = append(expanded, login)
+		}
+	}
+
+	for _, l := range logins {
+		team, isTeam := strings.CutPrefix(l, "@")
+		if !isTeam {
+			add(l)
+			continue
+		}
+
+		members, ok := teamMembers[team]
+		if !ok {
+			org, slug, found := strings.Cut(team, "/")
+			if !found || org == "" || slug == "" {
+				return nil, fmt.Errorf("invalid team %q, expected '@org/team-slug'", l)
+			}
+
+			var err error
+			members, err = token.GetTeamMembers(ctx, org, slug)
+			if err != nil {
+				return nil, fmt.Errorf("getting members of team %s: %w", l, err)
+			}
+			teamMembers[team] = members
+
+			c.Printf("  team <magenta>%s</>: <yellow>%s</>\n", l, strings.Join(members, "</>,<yellow>"))
+		}
+
+		for _, m := range members {
+			add(m)
+		}
+	}
+
+	return expanded, nil
+}
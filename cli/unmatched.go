@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	c "github.com/gookit/color"
+	"github.com/katbyte/ghp-sync/lib/gh"
+)
+
+// unmatchedItem is an issue or pr that was synced but no longer matches the filters
+type unmatchedItem struct {
+	NodeID string
+	Label  string
+}
+
+// removeUnmatched removes the unmatched items that are in the project when --remove-unmatched is set, dry
+// runs plan the removal or report them as kept.
+func removeUnmatched(ctx context.Context, f FlagData, p gh.Project, pl *Planner, items []unmatchedItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	if pl != nil {
+		for _, item := range items {
+			if err := pl.Unmatched(ctx, item.NodeID, item.Label, f.RemoveUnmatched); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !f.RemoveUnmatched {
+		return nil
+	}
+
+	itemIDs := map[string]string{} // content node id -> project item id
+	err := p.ListItemFieldValues(ctx, nil, func(item gh.ProjectItemValues) bool {
+		itemIDs[item.NodeID] = item.ID
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("listing project items: %w", err)
+	}
+
+	removed := 0
+	for _, item := range items {
+		if err := interrupted(ctx, "%d unmatched items removed", removed); err != nil {
+			return err
+		}
+
+		itemID, ok := itemIDs[item.NodeID]
+		if !ok {
+			continue
+		}
+
+		c.Printf("Removing <lightCyan>%s</>, no longer matches the filters.. ", item.Label)
+		err := p.DeleteItem(ctx, itemID)
+		logItem(ctx, PlanActionRemove, &itemID, err)
+		if err != nil {
+			c.Printf("<red>ERROR!!</> %s\n", err)
+			continue
+		}
+		c.Printf("<magenta>%s</>\n", itemID)
+		removed++
+	}
+
+	if removed > 0 {
+		c.Printf("Removed <yellow>%d</> items no longer matching the filters\n", removed)
+	}
+	return nil
+}
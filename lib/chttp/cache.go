@@ -0,0 +1,303 @@
+package chttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// Cache stores GET responses on disk with their ETag/Last-Modified so later requests for the same url are
+// sent as conditional requests, GitHub answers those with a 304 that doesn't count against the rate limit
+// when nothing has changed. Entries not used within the max age are pruned when the cache is opened, as
+// are the least recently used ones while it is over the max size.
+type Cache struct {
+	dir     string
+	maxAge  time.Duration // 0 for no limit
+	maxSize int64         // bytes, 0 for no limit
+
+	requests atomic.Int64
+	hits     atomic.Int64 // 304s answered from the cache
+	stored   atomic.Int64
+	saved    atomic.Int64 // bytes served from the cache instead of downloaded
+	pruned   atomic.Int64
+}
+
+// CacheStats are the counts of cacheable requests made through a Cache
+type CacheStats struct {
+	Requests   int64
+	Hits       int64
+	Misses     int64
+	Stored     int64
+	BytesSaved int64
+	Pruned     int64
+}
+
+// cacheEntry is a cached response
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// NewCache returns a cache storing responses in dir, creating it if needed, and prunes it to maxAge and
+// maxSize bytes. Either limit can be 0 for none.
+func NewCache(dir string, maxAge time.Duration, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cache dir %s: %w", dir, err)
+	}
+
+	c := &Cache{dir: dir, maxAge: maxAge, maxSize: maxSize}
+	if err := c.Prune(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Prune removes the entries not used within the max age, then the least recently used entries until the
+// cache is within its max size. An entry's modification time is when it was last stored or used.
+func (c *Cache) Prune() error {
+	if c.maxAge == 0 && c.maxSize == 0 {
+		return nil
+	}
+
+	type file struct {
+		path string
+		size int64
+		used time.Time
+	}
+
+	var files []file
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // removed by a concurrent run
+		}
+		files = append(files, file{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading cache dir %s: %w", c.dir, err)
+	}
+
+	// oldest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].used.Before(files[j].used)
+	})
+
+	cutoff := time.Now().Add(-c.maxAge)
+	for _, f := range files {
+		expired := c.maxAge != 0 && f.used.Before(cutoff)
+		tooBig := c.maxSize != 0 && total > c.maxSize
+		if !expired && !tooBig {
+			break
+		}
+
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("pruning cache entry %s: %w", f.path, err)
+		}
+		total -= f.size
+		c.pruned.Add(1)
+	}
+
+	if n := c.pruned.Load(); n > 0 {
+		clog.Log.Debugf("pruned %d cache entries from %s, %d bytes left", n, c.dir, total)
+	}
+	return nil
+}
+
+// Stats returns the cache's counts so far
+func (c *Cache) Stats() CacheStats {
+	s := CacheStats{
+		Requests:   c.requests.Load(),
+		Hits:       c.hits.Load(),
+		Stored:     c.stored.Load(),
+		BytesSaved: c.saved.Load(),
+		Pruned:     c.pruned.Load(),
+	}
+	s.Misses = s.Requests - s.Hits
+
+	return s
+}
+
+// Transport returns a RoundTripper that makes GET requests conditional on the cached response, and
+// caches the responses that have an ETag or Last-Modified header.
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	return &cacheTransport{cache: c, base: base}
+}
+
+type cacheTransport struct {
+	cache *Cache
+	base  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+	t.cache.requests.Add(1)
+
+	key := t.cache.key(req)
+	entry := t.cache.load(key)
+	if entry != nil && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		// RoundTrippers must not modify the request
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close() //nolint:errcheck,gosec // the 304 has no body
+		t.cache.hits.Add(1)
+		t.cache.saved.Add(int64(len(entry.Body)))
+		t.cache.touch(key)
+		clog.Log.Debugf("cache hit %s", entry.URL)
+
+		return entry.response(req, resp.Header), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck,gosec // fully read above
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cache.store(key, &cacheEntry{
+		URL:          req.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+	})
+
+	return resp, nil
+}
+
+// key identifies a request by its url, the representation asked for and the credential it is sent with, as
+// GitHub's responses vary on Authorization and one token must never be served a body fetched by another.
+// The credential is only ever stored hashed into the key.
+func (c *Cache) key(req *http.Request) string {
+	h := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept") + "\n" + req.Header.Get("Authorization")))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// load returns the cached response for a key, nil when there isn't one or it can't be read
+func (c *Cache) load(key string) *cacheEntry {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		clog.Log.Debugf("ignoring unreadable cache entry %s: %s", c.path(key), err)
+		return nil
+	}
+
+	return &e
+}
+
+// touch marks an entry as used now so pruning keeps it
+func (c *Cache) touch(key string) {
+	now := time.Now()
+	if err := os.Chtimes(c.path(key), now, now); err != nil {
+		clog.Log.Debugf("unable to touch cache entry %s: %s", c.path(key), err)
+	}
+}
+
+// store writes a response to the cache, failures are only logged as the cache is best effort
+func (c *Cache) store(key string, e *cacheEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		clog.Log.Debugf("unable to encode cache entry for %s: %s", e.URL, err)
+		return
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		clog.Log.Debugf("unable to create cache dir: %s", err)
+		return
+	}
+
+	// write then rename so a concurrent or interrupted run never reads a partial entry
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		clog.Log.Debugf("unable to write cache entry for %s: %s", e.URL, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		clog.Log.Debugf("unable to write cache entry for %s: %s", e.URL, err)
+		return
+	}
+
+	c.stored.Add(1)
+}
+
+// response rebuilds the cached response, with the headers of the 304 (rate limits, date) on top
+func (e *cacheEntry) response(req *http.Request, fresh http.Header) *http.Response {
+	h := e.Header.Clone()
+	for k, v := range fresh {
+		if strings.HasPrefix(k, "X-Ratelimit-") || k == "Date" {
+			h[k] = v
+		}
+	}
+	h.Set("Content-Length", strconv.Itoa(len(e.Body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
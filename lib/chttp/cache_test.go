@@ -0,0 +1,278 @@
+package chttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// etagServer answers with a fixed body and ETag, and a 304 to requests for that ETag
+func etagServer(t *testing.T, conditional *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	const etag = `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", r.Header.Get("X-Test-Remaining"))
+		if r.Header.Get("If-None-Match") == etag {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"login":"`+r.Header.Get("Authorization")+`"}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func cachedGet(t *testing.T, client *http.Client, url, auth, remaining string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("X-Test-Remaining", remaining)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+
+	return resp, string(body)
+}
+
+func TestCacheConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	var conditional atomic.Int64
+	srv := etagServer(t, &conditional)
+
+	c, err := NewCache(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+
+	_, first := cachedGet(t, client, srv.URL+"/user", "token one", "4999")
+	if conditional.Load() != 0 {
+		t.Fatalf("first request was conditional")
+	}
+
+	// the 304 is answered from the cache with the fresh rate limit headers
+	resp, second := cachedGet(t, client, srv.URL+"/user", "token one", "4998")
+	if conditional.Load() != 1 {
+		t.Fatalf("second request wasn't conditional")
+	}
+	if resp.StatusCode != http.StatusOK || second != first {
+		t.Errorf("cached response = %d %q, want 200 %q", resp.StatusCode, second, first)
+	}
+	if got := resp.Header.Get("X-Ratelimit-Remaining"); got != "4998" {
+		t.Errorf("cached response X-Ratelimit-Remaining = %q, want the 304's 4998", got)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("cached response Content-Type = %q, want the cached application/json", got)
+	}
+
+	// another token isn't sent the first token's ETag or served its body
+	_, other := cachedGet(t, client, srv.URL+"/user", "token two", "4997")
+	if conditional.Load() != 1 {
+		t.Errorf("request with another token was conditional on the first token's response")
+	}
+	if other == first {
+		t.Errorf("request with another token was served %q", other)
+	}
+
+	s := c.Stats()
+	want := CacheStats{Requests: 3, Hits: 1, Misses: 2, Stored: 2, BytesSaved: int64(len(first))}
+	if s != want {
+		t.Errorf("Stats() = %+v, want %+v", s, want)
+	}
+}
+
+func TestCacheSkipsUncacheable(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("%s %s was conditional", r.Method, r.URL.Path)
+		}
+		if r.URL.Path == "/etag" {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		_, _ = io.WriteString(w, "{}")
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewCache(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+
+	// responses without validators aren't stored, and only GETs are cached
+	cachedGet(t, client, srv.URL+"/none", "token", "")
+	cachedGet(t, client, srv.URL+"/none", "token", "")
+	cachedGet(t, client, srv.URL+"/etag", "token", "")
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+"/etag", nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+
+	if s := c.Stats(); s.Requests != 3 || s.Stored != 1 || s.Hits != 0 {
+		t.Errorf("Stats() = %+v, want 3 requests and 1 stored", s)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("server got %d requests, want 4", requests.Load())
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	entries := []struct {
+		name string
+		size int
+		used time.Time
+	}{
+		{"ab/old.json", 10, now.Add(-48 * time.Hour)},
+		{"ab/older.json", 10, now.Add(-72 * time.Hour)},
+		{"cd/recent.json", 10, now.Add(-2 * time.Hour)},
+		{"cd/lru.json", 10, now.Add(-3 * time.Hour)},
+		{"ef/new.json", 10, now},
+	}
+
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		want    []string
+	}{
+		{
+			name: "no limits",
+			want: []string{"ab/old.json", "ab/older.json", "cd/recent.json", "cd/lru.json", "ef/new.json"},
+		},
+		{
+			name:   "max age",
+			maxAge: 24 * time.Hour,
+			want:   []string{"cd/recent.json", "cd/lru.json", "ef/new.json"},
+		},
+		{
+			name:    "max size removes least recently used",
+			maxSize: 25,
+			want:    []string{"cd/recent.json", "ef/new.json"},
+		},
+		{
+			name:    "both",
+			maxAge:  24 * time.Hour,
+			maxSize: 10,
+			want:    []string{"ef/new.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for _, e := range entries {
+				path := filepath.Join(dir, e.name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, make([]byte, e.size), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path, e.used, e.used); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := NewCache(dir, tt.maxAge, tt.maxSize)
+			if err != nil {
+				t.Fatalf("NewCache() error = %v", err)
+			}
+
+			for _, e := range entries {
+				_, err := os.Stat(filepath.Join(dir, e.name))
+				kept := err == nil
+				want := false
+				for _, w := range tt.want {
+					want = want || w == e.name
+				}
+				if kept != want {
+					t.Errorf("%s kept = %t, want %t", e.name, kept, want)
+				}
+			}
+			if got, want := c.Stats().Pruned, int64(len(entries)-len(tt.want)); got != want {
+				t.Errorf("Stats().Pruned = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestCacheTouchKeepsUsedEntries(t *testing.T) {
+	t.Parallel()
+
+	var conditional atomic.Int64
+	srv := etagServer(t, &conditional)
+
+	dir := t.TempDir()
+	c, err := NewCache(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+	cachedGet(t, client, srv.URL+"/user", "token", "")
+
+	var path string
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			path = p
+		}
+		return err
+	})
+	if err != nil || path == "" {
+		t.Fatalf("no cache entry stored: %v", err)
+	}
+
+	// a hit marks the entry as used so it survives the next prune
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	cachedGet(t, client, srv.URL+"/user", "token", "")
+	if conditional.Load() != 1 {
+		t.Fatalf("second request wasn't a cache hit")
+	}
+
+	if _, err := NewCache(dir, time.Hour, 0); err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("used entry was pruned: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package chttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// Fixtures records sanitized request/response pairs to a directory, or replays them from one so a run can
+// be reproduced offline. Fixtures are keyed by what was requested, repeats of the same request are numbered
+// and replayed in order. Once they run out a request fails, unless repeat is set when the last one is
+// replayed again.
+type Fixtures struct {
+	dir    string
+	replay bool
+	repeat bool
+
+	mu  sync.Mutex
+	seq map[string]int
+}
+
+// NewRecorder returns fixtures recording to dir, which must be empty or not exist yet so a recording is
+// never mixed up with an older one
+func NewRecorder(dir string) (*Fixtures, error) {
+	entries, err := os.ReadDir(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading fixtures dir %s: %w", dir, err)
+	case len(entries) > 0:
+		return nil, fmt.Errorf("fixtures dir %s isn't empty, remove it or record to another", dir)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating fixtures dir %s: %w", dir, err)
+	}
+
+	return &Fixtures{dir: dir, seq: map[string]int{}}, nil
+}
+
+// NewReplayer returns fixtures replaying the recording in dir, with repeat the last fixture for a request
+// is replayed again once they run out
+func NewReplayer(dir string, repeat bool) (*Fixtures, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("reading fixtures dir %s: %w", dir, err)
+	}
+
+	return &Fixtures{dir: dir, replay: true, repeat: repeat, seq: map[string]int{}}, nil
+}
+
+// Replaying returns true when fixtures are served instead of making requests
+func (f *Fixtures) Replaying() bool {
+	return f.replay
+}
+
+// FixtureKey returns the name of the fixtures for a request of a kind, from the parts that identify it
+func FixtureKey(kind string, parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return kind + "-" + hex.EncodeToString(h[:8])
+}
+
+func (f *Fixtures) path(key string, n int) string {
+	return filepath.Join(f.dir, key+"-"+strconv.Itoa(n)+".json")
+}
+
+// Save writes the next fixture for a key
+func (f *Fixtures) Save(key string, v any) error {
+	f.mu.Lock()
+	f.seq[key]++
+	path := f.path(key, f.seq[key])
+	f.mu.Unlock()
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding fixture %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("writing fixture %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Load reads the next fixture for a key into v
+func (f *Fixtures) Load(key string, v any) error {
+	f.mu.Lock()
+	n := f.seq[key] + 1
+	if _, err := os.Stat(f.path(key, n)); err != nil && n > 1 {
+		if !f.repeat {
+			f.mu.Unlock()
+			return fmt.Errorf("only %d recorded in %s for this request (%s), --replay-repeat replays the last again", n-1, f.dir, key)
+		}
+		clog.Log.Warnf("replaying the last of %d recorded for %s again", n-1, key)
+		n--
+	}
+	f.seq[key] = n
+	path := f.path(key, n)
+	f.mu.Unlock()
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("nothing recorded in %s for this request (%s)", f.dir, key)
+	}
+	if err != nil {
+		return fmt.Errorf("reading fixture %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// httpFixture is a recorded request and its response, the request is only kept to make them readable
+type httpFixture struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header"`
+		Body       string      `json:"body"`
+	} `json:"response"`
+}
+
+// Transport returns a RoundTripper that records the requests sent through base, or serves them from the
+// recording without sending them
+func (f *Fixtures) Transport(base http.RoundTripper) http.RoundTripper {
+	return &fixturesTransport{fixtures: f, base: base}
+}
+
+type fixturesTransport struct {
+	fixtures *Fixtures
+	base     http.RoundTripper
+}
+
+func (t *fixturesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close() //nolint:errcheck,gosec // fully read above
+		if err != nil {
+			return nil, err
+		}
+		body = b
+
+		// RoundTrippers must not modify the request
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// credentials aren't part of the key so a recording replays with any token
+	key := FixtureKey("http", req.Method, req.URL.String(), string(body))
+
+	if t.fixtures.replay {
+		var fx httpFixture
+		if err := t.fixtures.Load(key, &fx); err != nil {
+			return nil, fmt.Errorf("replaying %s %s: %w", req.Method, req.URL, err)
+		}
+		return fx.response(req), nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck,gosec // fully read above
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var fx httpFixture
+	fx.Request.Method = req.Method
+	fx.Request.URL = req.URL.String()
+	fx.Request.Body = clog.Redact(string(body))
+	fx.Response.StatusCode = resp.StatusCode
+	fx.Response.Header = sanitizeHeader(resp.Header)
+	fx.Response.Body = clog.Redact(string(respBody))
+
+	// a failed recording shouldn't fail the run, it will show up when replaying
+	if err := t.fixtures.Save(key, &fx); err != nil {
+		clog.Log.Warnf("recording %s %s: %s", req.Method, req.URL, err)
+	}
+
+	return resp, nil
+}
+
+// sanitizeHeader drops cookies and redacts anything that looks like a credential, the length is dropped as
+// redacting can change it
+func sanitizeHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for k, vs := range h {
+		if k == "Set-Cookie" || k == "Content-Length" {
+			continue
+		}
+		for _, v := range vs {
+			out.Add(k, clog.Redact(v))
+		}
+	}
+
+	return out
+}
+
+// response rebuilds the recorded response
+func (fx *httpFixture) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fx.Response.StatusCode, http.StatusText(fx.Response.StatusCode)),
+		StatusCode:    fx.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fx.Response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(fx.Response.Body)),
+		ContentLength: int64(len(fx.Response.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,163 @@
+package chttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// built up so the fake token doesn't look like a leaked one
+var testToken = "ghp_" + strings.Repeat("x", 36)
+
+// tokenServer answers with the token in its headers and body, numbering its responses
+func tokenServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Set-Cookie", "session="+testToken)
+		w.Header().Set("X-Token", testToken)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"n":`+strconv.FormatInt(n.Add(1), 10)+`,"token":"`+testToken+`"}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func fixturePost(t *testing.T, client *http.Client, url string) (*http.Response, string, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url, strings.NewReader(`{"query":"viewer"}`))
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	req.Header.Set("Authorization", "bearer "+testToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+
+	return resp, string(body), nil
+}
+
+func TestFixturesRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	srv := tokenServer(t)
+	dir := filepath.Join(t.TempDir(), "fixtures")
+
+	recorder, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := &http.Client{Transport: recorder.Transport(http.DefaultTransport)}
+	for i := range 2 {
+		// the run itself still sees the real response
+		if _, body, err := fixturePost(t, client, srv.URL); err != nil || !strings.Contains(body, testToken) {
+			t.Fatalf("recording request %d = %q, %v, want the real response", i+1, body, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("recorded %d fixtures, want 2", len(entries))
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), testToken) || strings.Contains(string(b), "session=") {
+			t.Errorf("fixture %s contains a credential:\n%s", e.Name(), b)
+		}
+	}
+
+	// replayed in order without sending anything, then failing once they run out
+	srv.Close()
+	replayer, err := NewReplayer(dir, false)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	client = &http.Client{Transport: replayer.Transport(http.DefaultTransport)}
+	for i := range 2 {
+		resp, body, err := fixturePost(t, client, srv.URL)
+		if err != nil {
+			t.Fatalf("replaying request %d: %v", i+1, err)
+		}
+		if want := `"n":` + strconv.Itoa(i+1); !strings.Contains(body, want) || strings.Contains(body, testToken) {
+			t.Errorf("replayed body %d = %q, want %s and the token redacted", i+1, body, want)
+		}
+		if resp.Header.Get("X-Token") != "[REDACTED]" || resp.Header.Get("Set-Cookie") != "" {
+			t.Errorf("replayed header %d = %v, want the token redacted and no cookie", i+1, resp.Header)
+		}
+	}
+	if _, _, err := fixturePost(t, client, srv.URL); err == nil {
+		t.Errorf("replaying more requests than recorded didn't fail")
+	}
+
+	// unless the last one is repeated
+	replayer, err = NewReplayer(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replayer.Transport(http.DefaultTransport)}
+	for i, want := range []string{`"n":1`, `"n":2`, `"n":2`} {
+		if _, body, err := fixturePost(t, client, srv.URL); err != nil || !strings.Contains(body, want) {
+			t.Errorf("replaying request %d with repeat = %q, %v, want %s", i+1, body, err, want)
+		}
+	}
+}
+
+func TestFixturesNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	replayer, err := NewReplayer(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replayer.Transport(http.DefaultTransport)}
+	if _, _, err := fixturePost(t, client, "http://example.invalid/graphql"); err == nil {
+		t.Errorf("replaying a request that wasn't recorded didn't fail")
+	}
+}
+
+func TestSanitizeHeader(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	h.Set("Authorization", "token "+testToken)
+	h.Set("Set-Cookie", "session=abc")
+	h.Set("Content-Length", "42")
+	h.Set("X-Ratelimit-Remaining", "4999")
+	h.Add("Link", `<https://api.github.com/x?page=2>; rel="next"`)
+
+	got := sanitizeHeader(h)
+	if v := got.Get("Authorization"); strings.Contains(v, testToken) {
+		t.Errorf("Authorization = %q, want it redacted", v)
+	}
+	for _, k := range []string{"Set-Cookie", "Content-Length"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("%s kept", k)
+		}
+	}
+	if got.Get("X-Ratelimit-Remaining") != "4999" || got.Get("Link") != h.Get("Link") {
+		t.Errorf("sanitizeHeader() = %v, want other headers kept", got)
+	}
+}
@@ -1,4 +1,5 @@
-// Package chttp provides an http.Client wrapper that trace-logs requests and responses.
+// Package chttp provides an http.Client wrapper that trace-logs requests and responses, with credentials
+// redacted by the logger.
 package chttp
 
 import (
@@ -7,8 +8,10 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"time"
 
 	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/sirupsen/logrus"
 )
 
 var HTTP = http.DefaultClient
@@ -25,24 +28,39 @@ type transport struct {
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	reqData, err := httputil.DumpRequestOut(req, true)
+	l := clog.From(req.Context()).WithFields(logrus.Fields{
+		"client": t.name,
+		"method": req.Method,
+		"url":    req.URL.String(),
+	})
 
-	if err == nil {
-		clog.Log.Tracef(logReqMsg, t.name, prettyPrintJSON(reqData))
-	} else {
-		clog.Log.Debugf("%s API Request error: %#v", t.name, err)
+	// dumping reads the bodies, so only do it when the dumps will be logged
+	trace := l.Logger.IsLevelEnabled(logrus.TraceLevel)
+	if trace {
+		reqData, err := httputil.DumpRequestOut(req, true)
+		if err == nil {
+			l.Tracef(logReqMsg, t.name, prettyPrintJSON(reqData))
+		} else {
+			l.Debugf("%s API Request error: %#v", t.name, err)
+		}
 	}
 
+	start := time.Now()
 	resp, err := t.transport.RoundTrip(req)
+	l = l.WithField("duration_ms", time.Since(start).Milliseconds())
 	if err != nil {
+		l.WithError(err).Debugf("%s API request failed", t.name)
 		return resp, err
 	}
 
-	respData, err := httputil.DumpResponse(resp, true)
-	if err == nil {
-		clog.Log.Tracef(logRespMsg, t.name, prettyPrintJSON(respData))
-	} else {
-		clog.Log.Debugf("%s API Response error: %#v", t.name, err)
+	l = l.WithField("status", resp.StatusCode)
+	if trace {
+		respData, err := httputil.DumpResponse(resp, true)
+		if err == nil {
+			l.Tracef(logRespMsg, t.name, prettyPrintJSON(respData))
+		} else {
+			l.Debugf("%s API Response error: %#v", t.name, err)
+		}
 	}
 
 	return resp, nil
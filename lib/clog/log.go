@@ -2,7 +2,10 @@
 package clog
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -13,11 +16,7 @@ func createLogger() *logrus.Logger {
 	l := logrus.New()
 
 	l.SetOutput(os.Stderr)
-
-	customFormatter := new(logrus.TextFormatter)
-	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
-	customFormatter.FullTimestamp = true
-	l.SetFormatter(customFormatter)
+	l.SetFormatter(&redactingFormatter{base: textFormatter()})
 
 	lls := os.Getenv("GHP_SYNC_LOG")
 	if lls == "" {
@@ -34,3 +33,62 @@ func createLogger() *logrus.Logger {
 
 	return l
 }
+
+func textFormatter() logrus.Formatter {
+	customFormatter := new(logrus.TextFormatter)
+	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
+	customFormatter.FullTimestamp = true
+
+	return customFormatter
+}
+
+// jsonFormat is set when logging json lines
+var jsonFormat bool
+
+// SetFormat switches the logger between `text` and `json` lines. JSON logs are meant for a log pipeline so
+// default to the INFO level, which includes an event per synced item and api call, unless GHP_SYNC_LOG is
+// set.
+func SetFormat(format string) error {
+	jsonFormat = format == "json"
+
+	switch format {
+	case "", "text":
+		Log.SetFormatter(&redactingFormatter{base: textFormatter()})
+	case "json":
+		Log.SetFormatter(&redactingFormatter{base: &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}})
+		if os.Getenv("GHP_SYNC_LOG") == "" {
+			Log.SetLevel(logrus.InfoLevel)
+		}
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", format)
+	}
+
+	return nil
+}
+
+// EventLevel is the level of events meant for log pipelines, such as api call timings. It is INFO for
+// json lines and DEBUG otherwise, where they would drown out the progress.
+func EventLevel() logrus.Level {
+	if jsonFormat {
+		return logrus.InfoLevel
+	}
+
+	return logrus.DebugLevel
+}
+
+type ctxKey struct{}
+
+// WithFields returns a context whose logger adds the fields to every line, so the lines of a job or item,
+// including those of the api calls made for it, can be correlated
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, ctxKey{}, From(ctx).WithFields(fields))
+}
+
+// From returns the logger of a context, the shared logger without any fields when it has none
+func From(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(ctxKey{}).(*logrus.Entry); ok {
+		return e
+	}
+
+	return logrus.NewEntry(Log)
+}
@@ -0,0 +1,43 @@
+package clog
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// the token formats GitHub issues (personal, oauth, user to server, server to server, refresh and fine
+	// grained) and JWTs such as the ones signed for an app
+	tokenRe = regexp.MustCompile(`(?:gh[pousr]_[A-Za-z0-9_]{16,}|github_pat_[A-Za-z0-9_]{16,}|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*)`)
+
+	// credentials in headers, stopping at the end of the line in a dump or the escaped end of one in json
+	authHeaderRe = regexp.MustCompile(`(?i)((?:authorization|x-github-token|github_token|gh_enterprise_token)\s*[:=]\s*)[^\r\n\\"]+`)
+
+	// the token of an app installation token response, escaped when inside a json log line
+	tokenFieldRe = regexp.MustCompile(`(\\?"token\\?"\s*:\s*\\?")[^"\\]+`)
+)
+
+// Redact replaces access tokens, JWTs and authorization headers in s
+func Redact(s string) string {
+	s = authHeaderRe.ReplaceAllString(s, "${1}"+redacted)
+	s = tokenFieldRe.ReplaceAllString(s, "${1}"+redacted)
+
+	return tokenRe.ReplaceAllString(s, redacted)
+}
+
+// redactingFormatter redacts credentials from every formatted line, whichever field or message they are in
+type redactingFormatter struct {
+	base logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	b, err := f.base.Format(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(Redact(string(b))), nil
+}
@@ -0,0 +1,336 @@
+// Package filter implements a small expression language for filtering issues and PRs, ie
+// `label:bug and not label:wontfix and author in (a,b) and age > 30d and draft = false`.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fields are the values an expression is evaluated against keyed by lowercase field name. Values can be
+// a string, []string (matches when any element matches), float64 or bool.
+type Fields map[string]any
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	Eval(fields Fields) (bool, error)
+	String() string
+}
+
+// Operators supported by comparisons, `field:value` is shorthand for `field = value`.
+const (
+	OpEq    = "="
+	OpNotEq = "!="
+	OpGt    = ">"
+	OpGtEq  = ">="
+	OpLt    = "<"
+	OpLtEq  = "<="
+	OpIn    = "in"
+	OpNotIn = "not in"
+)
+
+type and []Expr
+
+// And returns an expression matching when all of exprs match, nil exprs are ignored.
+func And(exprs ...Expr) Expr {
+	return combine(exprs, func(e []Expr) Expr { return and(e) })
+}
+
+func (a and) Eval(fields Fields) (bool, error) {
+	for _, e := range a {
+		m, err := e.Eval(fields)
+		if err != nil || !m {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (a and) String() string {
+	return join(a, " and ")
+}
+
+type or []Expr
+
+// Or returns an expression matching when any of exprs match, nil exprs are ignored.
+func Or(exprs ...Expr) Expr {
+	return combine(exprs, func(e []Expr) Expr { return or(e) })
+}
+
+func (o or) Eval(fields Fields) (bool, error) {
+	for _, e := range o {
+		m, err := e.Eval(fields)
+		if err != nil || m {
+			return m, err
+		}
+	}
+
+	return false, nil
+}
+
+func (o or) String() string {
+	return join(o, " or ")
+}
+
+type not struct {
+	Expr
+}
+
+// Not returns an expression matching when e does not.
+func Not(e Expr) Expr {
+	return not{e}
+}
+
+func (n not) Eval(fields Fields) (bool, error) {
+	m, err := n.Expr.Eval(fields)
+	return !m, err
+}
+
+func (n not) String() string {
+	return "not " + wrap(n.Expr)
+}
+
+type comparison struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+// Compare returns an expression comparing field against values with op, multiple values are only
+// valid with OpIn and OpNotIn.
+func Compare(field, op string, values ...string) Expr {
+	return comparison{Field: strings.ToLower(field), Op: op, Values: values}
+}
+
+func (c comparison) Eval(fields Fields) (bool, error) {
+	v, ok := fields[c.Field]
+	if !ok {
+		return false, fmt.Errorf("unknown filter field %q", c.Field)
+	}
+
+	switch c.Op {
+	case OpEq, OpIn:
+		return matchAny(v, c.Values)
+	case OpNotEq, OpNotIn:
+		m, err := matchAny(v, c.Values)
+		return !m, err
+	}
+
+	n, ok := v.(float64)
+	if !ok {
+		return false, fmt.Errorf("filter field %q is not a number and can't be compared with %s", c.Field, c.Op)
+	}
+	want, err := parseNumber(c.Values[0])
+	if err != nil {
+		return false, fmt.Errorf("filter field %q: %w", c.Field, err)
+	}
+
+	switch c.Op {
+	case OpGt:
+		return n > want, nil
+	case OpGtEq:
+		return n >= want, nil
+	case OpLt:
+		return n < want, nil
+	case OpLtEq:
+		return n <= want, nil
+	}
+
+	return false, fmt.Errorf("unknown filter operator %q", c.Op)
+}
+
+func (c comparison) String() string {
+	values := make([]string, 0, len(c.Values))
+	for _, v := range c.Values {
+		values = append(values, quote(v))
+	}
+
+	if c.Op == OpIn || c.Op == OpNotIn {
+		return c.Field + " " + c.Op + " (" + strings.Join(values, ",") + ")"
+	}
+	if c.Op == OpEq {
+		return c.Field + ":" + values[0]
+	}
+
+	return c.Field + " " + c.Op + " " + values[0]
+}
+
+// Uses returns true if the expression looks at field, so values that are expensive to look up are only
+// looked up when needed.
+func Uses(e Expr, field string) bool {
+	field = strings.ToLower(field)
+
+	switch e := e.(type) {
+	case and:
+		return usesAny(e, field)
+	case or:
+		return usesAny(e, field)
+	case not:
+		return Uses(e.Expr, field)
+	case comparison:
+		return e.Field == field
+	case truthy:
+		return string(e) == field
+	}
+
+	return false
+}
+
+func usesAny(exprs []Expr, field string) bool {
+	for _, e := range exprs {
+		if Uses(e, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type truthy string
+
+func (t truthy) Eval(fields Fields) (bool, error) {
+	return comparison{Field: string(t), Op: OpEq, Values: []string{"true"}}.Eval(fields)
+}
+
+func (t truthy) String() string {
+	return string(t)
+}
+
+// matchAny returns true if the field value v matches any of the wanted values.
+func matchAny(v any, wanted []string) (bool, error) {
+	for _, w := range wanted {
+		m, err := match(v, w)
+		if err != nil || m {
+			return m, err
+		}
+	}
+
+	return false, nil
+}
+
+func match(v any, want string) (bool, error) {
+	switch v := v.(type) {
+	case string:
+		return matchString(v, want), nil
+	case []string:
+		for _, s := range v {
+			if matchString(s, want) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case float64:
+		n, err := parseNumber(want)
+		if err != nil {
+			return false, err
+		}
+		return v == n, nil
+	case bool:
+		b, err := strconv.ParseBool(want)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a boolean", want)
+		}
+		return v == b, nil
+	}
+
+	return false, fmt.Errorf("unsupported filter value type %T", v)
+}
+
+// matchString compares case-insensitively, with support for `*` wildcards ie `label:service/*`
+func matchString(s, want string) bool {
+	if strings.Contains(want, "*") {
+		return glob(strings.ToLower(want), strings.ToLower(s))
+	}
+
+	return strings.EqualFold(s, want)
+}
+
+// glob matches s against a pattern where `*` matches any run of characters, including `/` so
+// `service/*` matches labels nested more than one segment deep
+func glob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+
+	prefix, suffix := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	s = s[len(prefix):]
+
+	// the earliest match of each middle part leaves the most for the rest
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+
+	return strings.HasSuffix(s, suffix)
+}
+
+// parseNumber parses a number or a duration of days/weeks/hours (`30d`, `2w`, `12h`) into days.
+func parseNumber(s string) (float64, error) {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "d"):
+		s = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "w"):
+		s, multiplier = strings.TrimSuffix(s, "w"), 7
+	case strings.HasSuffix(s, "h"):
+		s, multiplier = strings.TrimSuffix(s, "h"), 1.0/24
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number or duration", s)
+	}
+
+	return n * multiplier, nil
+}
+
+func combine(exprs []Expr, fn func([]Expr) Expr) Expr {
+	var nonNil []Expr
+	for _, e := range exprs {
+		if e != nil {
+			nonNil = append(nonNil, e)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	}
+
+	return fn(nonNil)
+}
+
+func join(exprs []Expr, sep string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		parts = append(parts, wrap(e))
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// wrap puts parentheses around compound expressions
+func wrap(e Expr) string {
+	switch e.(type) {
+	case and, or:
+		return "(" + e.String() + ")"
+	}
+
+	return e.String()
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t(),:=!<>\"") || isKeyword(s) {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
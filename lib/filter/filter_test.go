@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+
+	fields := Fields{
+		"kind":     "pr",
+		"number":   float64(42),
+		"title":    "Fix the thing",
+		"author":   "Alice",
+		"assignee": []string{"bob", "carol"},
+		"label":    []string{"bug", "service/storage/blob", "größe"},
+		"project":  []string{},
+		"draft":    false,
+		"age":      float64(45),
+	}
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"kind:pr", true},
+		{"kind:issue", false},
+		{"author:alice", true},
+		{"author in (bob,alice)", true},
+		{"author not in (bob,alice)", false},
+		{"assignee:carol", true},
+		{"assignee:dave", false},
+		{"label:BUG", true},
+		{"label:wontfix", false},
+		{"label != wontfix", true},
+		{"label:service/*", true},
+		{"label:service/*/blob", true},
+		{"label:*blob", true},
+		{"label:service/*/queue", false},
+		{"label:*", true},
+		{"label:größe", true},
+		{"title:fix*", true},
+		{"title:*thing", true},
+		{"title:*other*", false},
+		{"number = 42", true},
+		{"number >= 43", false},
+		{"age > 30d", true},
+		{"age > 6w", true},
+		{"age > 7w", false},
+		{"age < 1090h", true},
+		{"draft", false},
+		{"not draft", true},
+		{"draft = false", true},
+		{"project:1", false},
+		{"not project:1", true},
+		{"label:bug and not label:wontfix and author in (alice) and age > 30d and draft = false", true},
+		{"label:wontfix or kind:pr", true},
+		{"(label:wontfix or kind:issue) and draft = false", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.expr, err)
+			}
+
+			got, err := e.Eval(fields)
+			if err != nil {
+				t.Fatalf("Eval(%q): %v", tc.expr, err)
+			}
+			if got != tc.want {
+				t.Errorf("Eval(%q) = %t, want %t", tc.expr, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	t.Parallel()
+
+	fields := Fields{
+		"title": "Fix the thing",
+		"age":   float64(45),
+		"draft": false,
+	}
+
+	cases := []string{
+		"unknown:x",
+		"title > 3",
+		"age > soon",
+		"draft = maybe",
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := Parse(expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", expr, err)
+			}
+			if _, err := e.Eval(fields); err == nil {
+				t.Errorf("Eval(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "a/b/c", true},
+		{"a*", "a", true},
+		{"a*", "ba", false},
+		{"*a", "ba", true},
+		{"a*b", "ab", true},
+		{"a*b", "a/x/b", true},
+		{"a*b", "a/x/bc", false},
+		{"ab*ab", "ab", false},
+		{"ab*ab", "abab", true},
+		{"a*b*c", "a/b/b/c", true},
+		{"a*b*c", "a/c/b", false},
+	}
+
+	for _, tc := range cases {
+		if got := glob(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("glob(%q, %q) = %t, want %t", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestUses(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"project:1", true},
+		{"PROJECT:1", true},
+		{"label:bug and not project in (1,2)", true},
+		{"label:bug or (draft and project)", true},
+		{"label:project", false},
+		{"label:bug", false},
+	}
+
+	for _, tc := range cases {
+		e, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.expr, err)
+		}
+		if got := Uses(e, "project"); got != tc.want {
+			t.Errorf("Uses(%q, project) = %t, want %t", tc.expr, got, tc.want)
+		}
+	}
+}
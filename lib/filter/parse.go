@@ -0,0 +1,298 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenColon
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "and", "or", "not", "in":
+		return true
+	}
+
+	return false
+}
+
+// Parse parses a filter expression:
+//
+//	expr       := term ("or" term)*
+//	term       := factor ("and" factor)*
+//	factor     := "not" factor | "(" expr ")" | comparison
+//	comparison := field ":" value | field op value | field ["not"] "in" "(" value ("," value)* ")" | field
+//
+// where op is one of = != > >= < <= and a bare field matches when it is true. Values are words or
+// double-quoted strings, numbers can be durations such as `30d`, `2w` or `12h` which are in days.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+
+	return e, nil
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case r == ':':
+			tokens = append(tokens, token{tokenColon, ":", i})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{tokenOp, OpEq, i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(s) && s[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("expected != at position %d", i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		case r == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			str, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{tokenString, str, i})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) {
+				r, size := utf8.DecodeRuneInString(s[end:])
+				if unicode.IsSpace(r) || strings.ContainsRune("(),:=!<>\"", r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{tokenWord, s[i:end], i})
+			i = end
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "end of expression", len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// keyword consumes the next token if it is the given (case-insensitive) keyword
+func (p *parser) keyword(k string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.text, k) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := []Expr{e}
+	for p.keyword("or") {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	return Or(exprs...), nil
+}
+
+func (p *parser) and() (Expr, error) {
+	e, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := []Expr{e}
+	for p.keyword("and") {
+		e, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+
+	return And(exprs...), nil
+}
+
+func (p *parser) factor() (Expr, error) {
+	if p.keyword("not") {
+		e, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) at position %d, got %q", t.pos, t.text)
+		}
+		return e, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	t := p.next()
+	if t.kind != tokenWord || isKeyword(t.text) {
+		return nil, fmt.Errorf("expected a field name at position %d, got %q", t.pos, t.text)
+	}
+	field := strings.ToLower(t.text)
+
+	switch next := p.peek(); {
+	case next.kind == tokenColon:
+		p.next()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(field, OpEq, v), nil
+
+	case next.kind == tokenOp:
+		p.next()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(field, next.text, v), nil
+
+	case next.kind == tokenWord && (strings.EqualFold(next.text, "in") || strings.EqualFold(next.text, "not")):
+		op := OpIn
+		if p.keyword("not") {
+			op = OpNotIn
+		}
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected in at position %d, got %q", p.peek().pos, p.peek().text)
+		}
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(field, op, values...), nil
+	}
+
+	return truthy(field), nil
+}
+
+func (p *parser) list() ([]string, error) {
+	if t := p.next(); t.kind != tokenLParen {
+		return nil, fmt.Errorf("expected ( at position %d, got %q", t.pos, t.text)
+	}
+
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		switch t := p.next(); t.kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return values, nil
+		default:
+			return nil, fmt.Errorf("expected , or ) at position %d, got %q", t.pos, t.text)
+		}
+	}
+}
+
+func (p *parser) value() (string, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenString {
+		if t.kind == tokenEOF {
+			return "", errors.New("expected a value at end of expression")
+		}
+		return "", fmt.Errorf("expected a value at position %d, got %q", t.pos, t.text)
+	}
+
+	return t.text, nil
+}
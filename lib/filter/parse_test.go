@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"label:bug", "label:bug"},
+		{"LABEL:bug", "label:bug"},
+		{"label = bug", "label:bug"},
+		{"age > 30d", "age > 30d"},
+		{"age>=2w", "age >= 2w"},
+		{"comments != 0", "comments != 0"},
+		{"draft", "draft"},
+		{"not draft", "not draft"},
+		{"author in (a, b,c)", "author in (a,b,c)"},
+		{"author not in (a,b)", "author not in (a,b)"},
+		{"label:bug and not label:wontfix", "label:bug and not label:wontfix"},
+		{"label:a or label:b and label:c", "label:a or (label:b and label:c)"},
+		{"(label:a or label:b) and label:c", "(label:a or label:b) and label:c"},
+		{`title:"needs triage"`, `title:"needs triage"`},
+		{`title:"say \"hi\""`, `title:"say \"hi\""`},
+		{"label:service/*", "label:service/*"},
+		{"label:größe", "label:größe"},
+		{"label:バグ and author:josé", "label:バグ and author:josé"},
+		{"label:bug and　draft", "label:bug and draft"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := Parse(tc.in)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.in, err)
+			}
+			if got := e.String(); got != tc.want {
+				t.Errorf("Parse(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+
+			// the string form parses back to the same expression
+			again, err := Parse(e.String())
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", e.String(), err)
+			}
+			if again.String() != e.String() {
+				t.Errorf("Parse(%q) = %q, want %q", e.String(), again.String(), e.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []string{
+		"",
+		"label:",
+		"label:bug and",
+		"(label:bug",
+		"label:bug)",
+		"author in a",
+		"author in (a",
+		"author not (a)",
+		"age ! 3",
+		`title:"unterminated`,
+		"and",
+		"label:bug label:wontfix",
+	}
+
+	for _, in := range cases {
+		t.Run(in, func(t *testing.T) {
+			t.Parallel()
+
+			if e, err := Parse(in); err == nil {
+				t.Errorf("Parse(%q) = %q, want an error", in, e)
+			}
+		})
+	}
+}
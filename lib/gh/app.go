@@ -0,0 +1,160 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"golang.org/x/oauth2"
+)
+
+// appTokenEarlyExpiry is how long before expiry an installation token is replaced, so a request never
+// goes out with a token about to expire
+const appTokenEarlyExpiry = 5 * time.Minute
+
+// App authenticates as a GitHub App installation, signing JWTs with the app's private key to mint
+// installation tokens.
+type App struct {
+	ID             int64
+	InstallationID int64
+
+	ctx    context.Context // used by Token as oauth2.TokenSource has no context
+	key    *rsa.PrivateKey
+	apiURL string
+	once   sync.Once
+	source oauth2.TokenSource
+}
+
+// NewApp parses the app's PEM encoded private key (PKCS#1 as downloaded from GitHub, or PKCS#8). Tokens
+// minted through Token and TokenSource are requested with ctx, so they stop with the run.
+func NewApp(ctx context.Context, id, installationID int64, privateKey []byte) (*App, error) {
+	if id == 0 || installationID == 0 {
+		return nil, errors.New("github app auth requires both the app id and installation id")
+	}
+
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("github app private key is not PEM encoded")
+	}
+
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else {
+		k8, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err8 != nil {
+			return nil, fmt.Errorf("parsing github app private key: %w", err)
+		}
+
+		var ok bool
+		if key, ok = k8.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("github app private key is not an RSA key")
+		}
+	}
+
+	return &App{
+		ID:             id,
+		InstallationID: installationID,
+		ctx:            ctx,
+		key:            key,
+		apiURL:         strings.TrimSuffix(APIURL(), "/"),
+	}, nil
+}
+
+// JWT returns a JWT identifying the app, valid for 9 minutes (GitHub allows at most 10) and backdated a
+// minute to allow for clock drift.
+func (a *App) JWT() (string, error) {
+	now := time.Now()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.ID, 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding jwt claims: %w", err)
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("signing jwt: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Token mints a new installation token with the app's context, it implements oauth2.TokenSource. Use
+// TokenSource to reuse tokens until they are about to expire.
+func (a *App) Token() (*oauth2.Token, error) {
+	return a.TokenContext(a.ctx)
+}
+
+// TokenContext mints a new installation token
+func (a *App) TokenContext(ctx context.Context) (*oauth2.Token, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiURL, a.InstallationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := unauthenticatedClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("creating installation token: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // best-effort close of the response body
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("creating installation token for app %d installation %d failed: %s\n%s", a.ID, a.InstallationID, resp.Status, body)
+	}
+
+	var t struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, fmt.Errorf("decoding installation token: %w", err)
+	}
+	clog.Log.Debugf("minted installation token for app %d installation %d, expires %s", a.ID, a.InstallationID, t.ExpiresAt)
+
+	return &oauth2.Token{
+		AccessToken: t.Token,
+		TokenType:   "Bearer",
+		Expiry:      t.ExpiresAt,
+	}, nil
+}
+
+// TokenSource returns a source that shares one installation token, minting a new one shortly before it
+// expires.
+func (a *App) TokenSource() oauth2.TokenSource {
+	a.once.Do(func() {
+		a.source = oauth2.ReuseTokenSourceWithExpiry(nil, a, appTokenEarlyExpiry)
+	})
+
+	return a.source
+}
@@ -0,0 +1,186 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testApp returns an app minting installation tokens from apiURL
+func testApp(t *testing.T, apiURL string) *App {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	a, err := NewApp(t.Context(), 1, 2, pemData)
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	a.apiURL = apiURL
+
+	return a
+}
+
+// installationServer mints tokens expiring after expiresIn, counting them
+func installationServer(t *testing.T, expiresIn time.Duration, minted *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/2/access_tokens" || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer eyJ") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+
+		n := minted.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      "installation-" + strconv.FormatInt(n, 10),
+			"expires_at": time.Now().Add(expiresIn).UTC().Format(time.RFC3339),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAppJWT(t *testing.T) {
+	t.Parallel()
+
+	a := testApp(t, "")
+	jwt, err := a.JWT()
+	if err != nil {
+		t.Fatalf("JWT() error = %v", err)
+	}
+	now := time.Now()
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT() = %q, want 3 parts", jwt)
+	}
+	decode := func(part string, v any) {
+		t.Helper()
+
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("decoding %q: %v", part, err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatalf("parsing %s: %v", b, err)
+		}
+	}
+
+	var header struct{ Alg, Typ string }
+	decode(parts[0], &header)
+	if header.Alg != "RS256" || header.Typ != "JWT" {
+		t.Errorf("header = %+v, want RS256 JWT", header)
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rsa.VerifyPKCS1v15(&a.key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+		t.Errorf("signature doesn't verify with the app's key: %v", err)
+	}
+
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	decode(parts[1], &claims)
+	iat, exp := time.Unix(claims.Iat, 0), time.Unix(claims.Exp, 0)
+	if claims.Iss != "1" {
+		t.Errorf("iss = %q, want the app id", claims.Iss)
+	}
+	// backdated for clock drift, and within the 10 minutes GitHub allows
+	if !iat.Before(now.Add(-30 * time.Second)) {
+		t.Errorf("iat = %s, want it backdated from %s", iat, now)
+	}
+	if d := exp.Sub(iat); d > 10*time.Minute {
+		t.Errorf("exp - iat = %s, want at most 10m", d)
+	}
+	if !exp.After(now.Add(5 * time.Minute)) {
+		t.Errorf("exp = %s, want it several minutes after %s", exp, now)
+	}
+}
+
+func TestAppTokenSourceRefresh(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		expiresIn time.Duration
+		want      int64
+	}{
+		{"reused until 5 minutes before expiry", 6 * time.Minute, 1},
+		{"replaced within 5 minutes of expiry", 4 * time.Minute, 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var minted atomic.Int64
+			srv := installationServer(t, tc.expiresIn, &minted)
+			src := testApp(t, srv.URL).TokenSource()
+
+			for range 3 {
+				tok, err := src.Token()
+				if err != nil {
+					t.Fatalf("Token() error = %v", err)
+				}
+				if !strings.HasPrefix(tok.AccessToken, "installation-") || tok.Expiry.IsZero() {
+					t.Errorf("Token() = %+v, want the installation token and its expiry", tok)
+				}
+			}
+			if got := minted.Load(); got != tc.want {
+				t.Errorf("minted %d tokens, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppTokenCancelled(t *testing.T) {
+	t.Parallel()
+
+	var minted atomic.Int64
+	srv := installationServer(t, time.Hour, &minted)
+
+	// the app's context stops tokens minted through the source
+	ctx, cancel := context.WithCancel(t.Context())
+	a := testApp(t, srv.URL)
+	a.ctx = ctx
+	cancel()
+	if _, err := a.TokenSource().Token(); err == nil {
+		t.Errorf("Token() with a cancelled context didn't fail")
+	}
+
+	cancelled, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := testApp(t, srv.URL).TokenContext(cancelled); err == nil {
+		t.Errorf("TokenContext() with a cancelled context didn't fail")
+	}
+
+	if n := minted.Load(); n != 0 {
+		t.Errorf("minted %d tokens after cancelling, want none", n)
+	}
+}
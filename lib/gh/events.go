@@ -1,52 +1,66 @@
 package gh
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"sort"
 
 	"github.com/google/go-github/v89/github"
 	"github.com/katbyte/ghp-sync/lib/clog"
 )
 
-func (r Repo) ListAllIssueEvents(number int, cb func([]*github.Timeline, *github.Response) error) error {
-	client, ctx := r.NewClient()
+// TimelineEvent is an issue or PR timeline event along with its author's association with the repo, which
+// GitHub includes for comments and reviews but go-github's Timeline doesn't
+type TimelineEvent struct {
+	github.Timeline
+	AuthorAssociation string `json:"author_association,omitempty"`
+}
 
-	opts := &github.ListOptions{
-		Page:    1,
-		PerPage: 100,
-	}
+func (r Repo) ListAllIssueEvents(ctx context.Context, number int, cb func([]*TimelineEvent, *github.Response) error) error {
+	client := r.NewClient()
 
+	page := 1
 	for {
-		clog.Log.Debugf("Listing all events for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
-		events, resp, err := client.Issues.ListIssueTimeline(ctx, r.Owner, r.Name, number, opts)
+		clog.Log.Debugf("Listing all events for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, page)
+
+		// requested directly rather than with ListIssueTimeline to keep the author associations
+		u := fmt.Sprintf("repos/%s/%s/issues/%d/timeline?per_page=100&page=%d", r.Owner, r.Name, number, page)
+		req, err := client.NewRequest(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return fmt.Errorf("building request for events of %s/%s/%d: %w", r.Owner, r.Name, number, err)
+		}
+
+		var events []*TimelineEvent
+		resp, err := client.Do(req, &events)
 		if err != nil {
-			return fmt.Errorf("unable to list events for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+			return fmt.Errorf("unable to list events for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, page, err)
 		}
 
 		if err = cb(events, resp); err != nil {
-			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, page, err)
 		}
 
 		if resp.NextPage == 0 {
 			break
 		}
-		opts.Page = resp.NextPage
+		page = resp.NextPage
 	}
 
 	return nil
 }
 
-func (r Repo) GetAllIssueEvents(number int) (*[]github.Timeline, error) {
-	var allEvents []github.Timeline
+func (r Repo) GetAllIssueEvents(ctx context.Context, number int) (*[]TimelineEvent, error) {
+	var allEvents []TimelineEvent
 
-	err := r.ListAllIssueEvents(number, func(events []*github.Timeline, resp *github.Response) error {
+	err := r.ListAllIssueEvents(ctx, number, func(events []*TimelineEvent, resp *github.Response) error {
 		for i, e := range events {
 			if e == nil {
 				clog.Log.Debugf("events[%d] was nil, skipping", i)
 				continue
 			}
 
-			if e.CreatedAt == nil && e.SubmittedAt == nil {
+			if eventTime(e.Timeline).IsZero() {
 				clog.Log.Debugf("events[%d] has no date, skipping", i)
 				continue
 			}
@@ -62,17 +76,7 @@ func (r Repo) GetAllIssueEvents(number int) (*[]github.Timeline, error) {
 
 	// sort descending (most recent first) so callers can break on the first match
 	sort.Slice(allEvents, func(a, b int) bool {
-		dateA := allEvents[a].CreatedAt
-		if dateA == nil {
-			dateA = allEvents[a].SubmittedAt
-		}
-
-		dateB := allEvents[b].CreatedAt
-		if dateB == nil {
-			dateB = allEvents[b].SubmittedAt
-		}
-
-		return dateA.After(dateB.Time)
+		return eventTime(allEvents[a].Timeline).After(eventTime(allEvents[b].Timeline))
 	})
 
 	return &allEvents, nil
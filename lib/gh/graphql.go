@@ -1,79 +1,216 @@
 package gh
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/katbyte/ghp-sync/lib/chttp"
+	"github.com/katbyte/ghp-sync/lib/clog"
 )
 
-func (t Token) GraphQLQueryUnmarshal(query string, params [][]string, data any) error {
-	out, err := t.GraphQLQuery(query, params)
-	if err != nil {
-		return err
+// GraphQLQueryUnmarshal runs a query with GraphQLQuery and unmarshals the response into data, for
+// *GraphQLErrors with partial results the data that was returned is unmarshalled along with the error.
+func (t Token) GraphQLQueryUnmarshal(ctx context.Context, query string, params [][]string, data any) error {
+	out, err := t.GraphQLQuery(ctx, query, params)
+	if out != nil {
+		if uerr := json.Unmarshal([]byte(*out), data); uerr != nil && err == nil {
+			return fmt.Errorf("decoding graphql response: %w", uerr)
+		}
 	}
-	return json.Unmarshal([]byte(*out), data)
-}
 
-func (t Token) GraphQLQuery(query string, params [][]string) (*string, error) {
-	const (
-		maxAttempts = 5
-		baseDelay   = time.Second
-	)
+	return err
+}
 
-	args := make([]string, 0, 4+2*len(params))
+// GraphQLQuery runs a query with the gh CLI returning its output. Errors in the response are returned as
+// *GraphQLErrors, along with the output when it has partial results. gh is killed when ctx is done.
+func (t Token) GraphQLQuery(ctx context.Context, query string, params [][]string) (*string, error) {
+	args := make([]string, 0, 6+2*len(params))
 	args = append(args, "api", "graphql", "-f", query)
+	if IsEnterprise() {
+		args = append(args, "--hostname", Host())
+	}
 
 	for _, p := range params {
 		args = append(args, p[0], p[1])
 	}
 
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		ghc := exec.CommandContext(context.Background(), "gh", args...) //nolint:gosec // args are constructed internally
+	var out string
+	var partial bool
+	err := retryPolicy.do(ctx, "gh api graphql", func(n int) (*retryable, error) {
+		// fetched each attempt so an app installation token is refreshed before it expires
+		token, pt, err := t.accessToken(ResourceGraphQL)
+		if err != nil {
+			return nil, err
+		}
 
-		// Preserve existing environment and add GITHUB_TOKEN if present
-		env := os.Environ()
-		if t.Token != nil {
-			env = append(env, "GITHUB_TOKEN="+*t.Token)
+		start := time.Now()
+		stdout, stderr, runErr := runGH(ctx, args, token)
+		logAPICall(ctx, ResourceGraphQL, "gh api graphql", n, start, 0, runErr)
+		out = string(stdout)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		ghc.Env = env
 
-		out, err := ghc.CombinedOutput()
-		outstr := string(out)
+		// gh exits non zero for responses with errors, but check the response either way
+		if ge := parseGraphQLErrors(stdout); ge != nil {
+			partial = ge.Partial
+			if ge.HasType(GraphQLErrorRateLimited) {
+				return t.classifyGHOutput(ctx, token, pt, out), ge
+			}
+			return nil, ge
+		}
 
 		// on success return the output immediately
-		if err == nil {
-			return &outstr, nil
+		if runErr == nil {
+			return nil, nil
+		}
+
+		msg := strings.TrimSpace(string(stderr))
+		err = fmt.Errorf("gh graphql failed: %w: %s", runErr, msg)
+		return t.classifyGHOutput(ctx, token, pt, msg+"\n"+out), err
+	})
+
+	var ge *GraphQLErrors
+	if errors.As(err, &ge) && partial {
+		return &out, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// ghFixture is a recorded gh call
+type ghFixture struct {
+	Args   []string `json:"args"`
+	Stdout string   `json:"stdout"`
+	Stderr string   `json:"stderr,omitempty"`
+	Error  string   `json:"error,omitempty"` // ie `exit status 1`
+}
+
+// ghFixtureKey identifies a gh call for recording and replay. Mutations are keyed on the query and ids
+// without the field values they set, as those are often derived from when the run was (ie days open) and
+// would stop a recording replaying on a later day.
+func ghFixtureKey(args []string) string {
+	mutation := false
+	for _, a := range args {
+		if q, ok := strings.CutPrefix(a, "query="); ok && strings.HasPrefix(strings.TrimSpace(q), "mutation") {
+			mutation = true
+		}
+	}
+	if !mutation {
+		return chttp.FixtureKey("gh", args...)
+	}
+
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = a
+		if i == 0 || (args[i-1] != "-f" && args[i-1] != "-F") {
+			continue
 		}
+		if name, _, ok := strings.Cut(a, "="); ok && (name == "value" || strings.HasSuffix(name, "_value")) {
+			parts[i] = name + "="
+		}
+	}
+
+	return chttp.FixtureKey("gh", parts...)
+}
 
-		// If it doesn't look like a rate limit error, fail fast
-		if !isRateLimitError(outstr) && !isRateLimitError(err.Error()) {
-			return nil, fmt.Errorf("gh graphql failed: %w\noutput: %s", err, outstr)
+// runGH runs gh with the token returning its stdout, with the response, and stderr, with gh's own
+// messages. With fixtures the call is recorded, or replayed without running gh.
+func runGH(ctx context.Context, args []string, token string) ([]byte, []byte, error) {
+	key := ghFixtureKey(args)
+	if fixtures != nil && fixtures.Replaying() {
+		var fx ghFixture
+		if err := fixtures.Load(key, &fx); err != nil {
+			return nil, nil, fmt.Errorf("replaying gh api graphql: %w", err)
 		}
 
-		// If we've hit rate limit and used all attempts, bail out
-		if attempt == maxAttempts {
-			return nil, fmt.Errorf("rate limited after %d attempts: %w\nlast output: %s", attempt, err, outstr)
+		var err error
+		if fx.Error != "" {
+			err = errors.New(fx.Error)
 		}
+		return []byte(fx.Stdout), []byte(fx.Stderr), err
+	}
+
+	ghc := exec.CommandContext(ctx, "gh", args...) //nolint:gosec // args are constructed internally
+	// don't wait on output held open by anything gh started once it is killed
+	ghc.WaitDelay = time.Second
 
-		// Exponential backoff (1s, 2s, 4s, 8s, ...)
-		delay := baseDelay * time.Duration(math.Pow(2, float64(attempt-1)))
-		time.Sleep(delay)
+	// Preserve existing environment and add GITHUB_TOKEN if present
+	env := os.Environ()
+	if token != "" {
+		// gh only uses GITHUB_TOKEN for github.com
+		if IsEnterprise() {
+			env = append(env, "GH_ENTERPRISE_TOKEN="+token)
+		} else {
+			env = append(env, "GITHUB_TOKEN="+token)
+		}
 	}
+	ghc.Env = env
+
+	var stdout, stderr bytes.Buffer
+	ghc.Stdout = &stdout
+	ghc.Stderr = &stderr
+	err := ghc.Run()
 
-	// Should be unreachable, but keeps compiler happy
-	return nil, errors.New("gh graphql failed after retries, this should be unreachable")
+	// a killed gh is no response to replay
+	if fixtures != nil && ctx.Err() == nil {
+		fx := ghFixture{
+			Args:   make([]string, 0, len(args)),
+			Stdout: clog.Redact(stdout.String()),
+			Stderr: clog.Redact(stderr.String()),
+		}
+		for _, a := range args {
+			fx.Args = append(fx.Args, clog.Redact(a))
+		}
+		if err != nil {
+			fx.Error = err.Error()
+		}
+		if serr := fixtures.Save(key, &fx); serr != nil {
+			clog.Log.Warnf("recording gh api graphql: %s", serr)
+		}
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), err
 }
 
-func isRateLimitError(msg string) bool {
-	m := strings.ToLower(msg)
-	return strings.Contains(m, "rate limit") ||
-		strings.Contains(m, "api rate limit exceeded") ||
-		strings.Contains(m, "secondary rate limit") ||
-		strings.Contains(m, "abuse detection")
+// classifyGHOutput returns how a failed gh api call can be retried from its output, nil if it can't be
+func (t Token) classifyGHOutput(ctx context.Context, token string, pt *pooledToken, out string) *retryable {
+	if isRateLimitError(out) || graphQLErrorType([]byte(out)) == GraphQLErrorRateLimited {
+		// with several tokens switch to another with budget left, the rate limit api tells us whether
+		// this one is out of budget or hit a secondary limit
+		if pt != nil {
+			t.Pool.refresh(ctx, pt)
+			t.Pool.limited(pt, ResourceGraphQL, time.Now().Add(time.Minute))
+			if t.Pool.hasBudget(ResourceGraphQL) {
+				return &retryable{reason: "rate limited, switching tokens"}
+			}
+			return &retryable{reason: "rate limited on all tokens", wait: time.Until(t.Pool.nextReset(ResourceGraphQL)) + time.Second}
+		}
+
+		// gh doesn't show the response headers, so ask the rate limit api when the budget resets
+		if rl, err := GetRateLimit(ctx, NewToken(token)); err == nil && rl.GraphQL.Remaining == 0 {
+			return &retryable{reason: "rate limited", wait: time.Until(time.Unix(int64(rl.GraphQL.Reset), 0)) + time.Second}
+		}
+
+		// GitHub asks for at least a minute when a secondary limit doesn't say how long
+		return &retryable{reason: "secondary rate limited", wait: time.Minute}
+	}
+
+	for _, s := range []string{"HTTP 500", "HTTP 502", "HTTP 503", "HTTP 504", "Something went wrong"} {
+		if strings.Contains(out, s) {
+			return &retryable{reason: "server error"}
+		}
+	}
+
+	return nil
 }
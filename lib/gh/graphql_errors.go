@@ -0,0 +1,156 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/shurcooL/githubv4"
+)
+
+// GraphQLError is an entry in the errors array of a GraphQL response
+type GraphQLError struct {
+	Type    string `json:"type"` // ie NOT_FOUND, FORBIDDEN, RATE_LIMITED, empty for query errors
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+// PathString returns the path to the field with the error, ie `organization.projectV2.items.nodes.3`
+func (e GraphQLError) PathString() string {
+	parts := make([]string, 0, len(e.Path))
+	for _, p := range e.Path {
+		parts = append(parts, fmt.Sprint(p))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// GraphQLErrors is returned for a GraphQL response with errors, HTTP 200 responses included. When Partial
+// is set the data for the rest of the query was still returned and decoded.
+type GraphQLErrors struct {
+	Errors  []GraphQLError
+	Partial bool
+}
+
+func (e *GraphQLErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ge := range e.Errors {
+		m := ge.Message
+		if ge.Type != "" {
+			m = ge.Type + ": " + m
+		}
+		msgs = append(msgs, m)
+	}
+
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+// HasType returns true if any of the errors are of the type
+func (e *GraphQLErrors) HasType(t string) bool {
+	for _, ge := range e.Errors {
+		if ge.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsNotFound returns true for GraphQL NOT_FOUND errors, ie a missing project, item or node id
+func IsNotFound(err error) bool {
+	var ge *GraphQLErrors
+	return errors.As(err, &ge) && ge.HasType(GraphQLErrorNotFound)
+}
+
+// IsForbidden returns true for GraphQL FORBIDDEN errors where the token can't access something
+func IsForbidden(err error) bool {
+	var ge *GraphQLErrors
+	return errors.As(err, &ge) && ge.HasType(GraphQLErrorForbidden)
+}
+
+// parseGraphQLErrors returns the errors in a GraphQL response body, nil if it has none or isn't one
+func parseGraphQLErrors(body []byte) *GraphQLErrors {
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil || len(r.Errors) == 0 {
+		return nil
+	}
+
+	return &GraphQLErrors{
+		Errors:  r.Errors,
+		Partial: len(r.Data) > 0 && !bytes.Equal(r.Data, []byte("null")),
+	}
+}
+
+// allowItemErrors returns nil for partial results whose errors are all for individual list items (a path
+// through `nodes`), ie project items in repos the token can't see, logging them so one inaccessible item
+// doesn't fail the whole listing
+func allowItemErrors(err error, what string) error {
+	var ge *GraphQLErrors
+	if !errors.As(err, &ge) || !ge.Partial {
+		return err
+	}
+
+	for _, e := range ge.Errors {
+		if !strings.Contains("."+e.PathString()+".", ".nodes.") {
+			return err
+		}
+	}
+
+	for _, e := range ge.Errors {
+		clog.Log.Warnf("%s: skipping %s: %s", what, e.PathString(), e.Message)
+	}
+
+	return nil
+}
+
+// graphQLErrorsKey is the context key for where graphQLErrorsTransport records the errors of a response
+type graphQLErrorsKey struct{}
+
+// graphQLErrorsTransport records the typed errors of GraphQL responses for graphQLQuery, githubv4 only
+// returns their messages
+type graphQLErrorsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *graphQLErrorsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	errs, ok := req.Context().Value(graphQLErrorsKey{}).(**GraphQLErrors)
+	if !ok {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close() //nolint:errcheck,gosec // fully read above
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	*errs = parseGraphQLErrors(body)
+
+	return resp, nil
+}
+
+// graphQLQuery runs a githubv4 query returning *GraphQLErrors for errors in the response, the data that
+// was returned is still decoded into q
+func graphQLQuery(ctx context.Context, client *githubv4.Client, q any, variables map[string]any) error {
+	var errs *GraphQLErrors
+	err := client.Query(context.WithValue(ctx, graphQLErrorsKey{}, &errs), q, variables)
+	if errs != nil {
+		return errs
+	}
+
+	return err
+}
@@ -0,0 +1,36 @@
+package gh
+
+import "testing"
+
+func TestGHFixtureKey(t *testing.T) {
+	t.Parallel()
+
+	mutation := func(itemID, value, numberValue string) []string {
+		return []string{
+			"api", "graphql",
+			"-f", "query=mutation($item:ID!, $value:String!, $days_value:Float!) { x }",
+			"-f", "item=" + itemID,
+			"-f", "value=" + value,
+			"-F", "days_value=" + numberValue,
+		}
+	}
+	query := func(after string) []string {
+		return []string{"api", "graphql", "-f", "query=query($after:String) { x }", "-f", "after=" + after, "-f", "value=" + after}
+	}
+
+	key := ghFixtureKey(mutation("PVTI_1", "Todo", "3"))
+	if got := ghFixtureKey(mutation("PVTI_1", "Done", "4")); got != key {
+		t.Errorf("mutation key changes with the values set: %s != %s", got, key)
+	}
+	if got := ghFixtureKey(mutation("PVTI_2", "Todo", "3")); got == key {
+		t.Errorf("mutation key doesn't change with the item")
+	}
+	if ghFixtureKey(mutation("PVTI_1", "Todo", "3")) != key {
+		t.Errorf("mutation key isn't stable")
+	}
+
+	// queries are keyed on every arg
+	if ghFixtureKey(query("a")) == ghFixtureKey(query("b")) {
+		t.Errorf("query key doesn't change with its variables")
+	}
+}
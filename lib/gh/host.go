@@ -0,0 +1,80 @@
+package gh
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultHost is the host of github.com
+const DefaultHost = "github.com"
+
+// host is the GitHub host all requests go to, github.com or a GitHub Enterprise Server instance
+var host = DefaultHost
+
+// SetHost sets the GitHub host, either a hostname or the URL of a GitHub Enterprise Server instance ie
+// `https://github.example.com/api/v3`, an empty host is github.com
+func SetHost(h string) error {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		host = DefaultHost
+		return nil
+	}
+
+	if !strings.Contains(h, "://") {
+		h = "https://" + h
+	}
+	u, err := url.Parse(h)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid github host %q", h)
+	}
+
+	host = strings.ToLower(u.Host)
+	if host == "api."+DefaultHost {
+		host = DefaultHost
+	}
+
+	return nil
+}
+
+// Host returns the GitHub host
+func Host() string {
+	return host
+}
+
+// IsEnterprise returns true when the host is a GitHub Enterprise Server instance
+func IsEnterprise() bool {
+	return host != DefaultHost
+}
+
+// WebURL returns the base URL of the GitHub web interface, with a trailing slash
+func WebURL() string {
+	return "https://" + host + "/"
+}
+
+// APIURL returns the base URL of the REST API, with a trailing slash
+func APIURL() string {
+	if IsEnterprise() {
+		return "https://" + host + "/api/v3/"
+	}
+
+	return "https://api.github.com/"
+}
+
+// UploadURL returns the base URL for uploads, with a trailing slash
+func UploadURL() string {
+	if IsEnterprise() {
+		return "https://" + host + "/api/uploads/"
+	}
+
+	return "https://uploads.github.com/"
+}
+
+// GraphQLURL returns the URL of the GraphQL API
+func GraphQLURL() string {
+	if IsEnterprise() {
+		return "https://" + host + "/api/graphql"
+	}
+
+	return "https://api.github.com/graphql"
+}
@@ -1,6 +1,7 @@
 package gh
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
@@ -8,8 +9,8 @@ import (
 	"github.com/katbyte/ghp-sync/lib/clog"
 )
 
-func (r Repo) ListAllIssues(state string, cb func([]*github.Issue, *github.Response) error) error {
-	client, ctx := r.NewClient()
+func (r Repo) ListAllIssues(ctx context.Context, state string, cb func([]*github.Issue, *github.Response) error) error {
+	client := r.NewClient()
 	opts := &github.IssueListByRepoOptions{
 		State: state,
 		ListOptions: github.ListOptions{
@@ -38,8 +39,8 @@ func (r Repo) ListAllIssues(state string, cb func([]*github.Issue, *github.Respo
 	return nil
 }
 
-func (r Repo) GetIssue(number int) (*github.Issue, error) {
-	client, ctx := r.NewClient()
+func (r Repo) GetIssue(ctx context.Context, number int) (*github.Issue, error) {
+	client := r.NewClient()
 
 	i, _, err := client.Issues.Get(ctx, r.Owner, r.Name, number)
 	if err != nil {
@@ -49,10 +50,10 @@ func (r Repo) GetIssue(number int) (*github.Issue, error) {
 	return i, nil
 }
 
-func (r Repo) GetAllIssues(state string) (*[]github.Issue, error) {
+func (r Repo) GetAllIssues(ctx context.Context, state string) (*[]github.Issue, error) {
 	var allIssues []github.Issue
 
-	err := r.ListAllIssues(state, func(issues []*github.Issue, resp *github.Response) error {
+	err := r.ListAllIssues(ctx, state, func(issues []*github.Issue, resp *github.Response) error {
 		for index, i := range issues {
 			if i == nil {
 				clog.Log.Debugf("issues[%d] was nil, skipping", index)
@@ -83,3 +84,25 @@ func (r Repo) GetAllIssues(state string) (*[]github.Issue, error) {
 
 	return &allIssues, nil
 }
+
+// AddAssignees assigns users to an issue or pr.
+func (r Repo) AddAssignees(ctx context.Context, number int, logins []string) error {
+	client := r.NewClient()
+
+	if _, _, err := client.Issues.AddAssignees(ctx, r.Owner, r.Name, number, logins); err != nil {
+		return fmt.Errorf("unable to add assignees to %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
+
+// RemoveAssignees unassigns users from an issue or pr.
+func (r Repo) RemoveAssignees(ctx context.Context, number int, logins []string) error {
+	client := r.NewClient()
+
+	if _, _, err := client.Issues.RemoveAssignees(ctx, r.Owner, r.Name, number, logins); err != nil {
+		return fmt.Errorf("unable to remove assignees from %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
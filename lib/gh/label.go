@@ -1,14 +1,15 @@
 package gh
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/go-github/v89/github"
 	"github.com/katbyte/ghp-sync/lib/clog"
 )
 
-func (r Repo) GetLabelsFor(number int) (*[]string, error) {
-	client, ctx := r.NewClient()
+func (r Repo) GetLabelsFor(ctx context.Context, number int) (*[]string, error) {
+	client := r.NewClient()
 
 	opts := &github.ListOptions{
 		Page:    1,
@@ -35,3 +36,27 @@ func (r Repo) GetLabelsFor(number int) (*[]string, error) {
 
 	return &allLabels, nil
 }
+
+// AddLabels adds labels to an issue or pr, creating any that don't exist in the repo.
+func (r Repo) AddLabels(ctx context.Context, number int, labels []string) error {
+	client := r.NewClient()
+
+	clog.Log.Debugf("Adding labels %v to %s/%s/%d...", labels, r.Owner, r.Name, number)
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, r.Owner, r.Name, number, labels); err != nil {
+		return fmt.Errorf("unable to add labels to %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
+
+// RemoveLabel removes a label from an issue or pr.
+func (r Repo) RemoveLabel(ctx context.Context, number int, label string) error {
+	client := r.NewClient()
+
+	clog.Log.Debugf("Removing label %s from %s/%s/%d...", label, r.Owner, r.Name, number)
+	if _, err := client.Issues.RemoveLabelForIssue(ctx, r.Owner, r.Name, number, label); err != nil {
+		return fmt.Errorf("unable to remove label %s from %s/%s/%d: %w", label, r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
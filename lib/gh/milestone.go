@@ -0,0 +1,70 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// GetMilestoneNumber returns the number of the open or closed milestone with a title, ignoring case.
+func (r Repo) GetMilestoneNumber(ctx context.Context, title string) (int, error) {
+	client := r.NewClient()
+
+	opts := &github.MilestoneListOptions{
+		State: "all",
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	for {
+		clog.Log.Debugf("Listing milestones for %s/%s (Page %d)...", r.Owner, r.Name, opts.Page)
+		milestones, resp, err := client.Issues.ListMilestones(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list milestones for %s/%s (Page %d): %w", r.Owner, r.Name, opts.Page, err)
+		}
+
+		for _, m := range milestones {
+			if strings.EqualFold(m.GetTitle(), title) {
+				return m.GetNumber(), nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return 0, fmt.Errorf("milestone %q not found in %s/%s", title, r.Owner, r.Name)
+}
+
+// SetMilestone sets the milestone of an issue or pr by title.
+func (r Repo) SetMilestone(ctx context.Context, number int, title string) error {
+	milestone, err := r.GetMilestoneNumber(ctx, title)
+	if err != nil {
+		return err
+	}
+
+	client := r.NewClient()
+	if _, _, err := client.Issues.Edit(ctx, r.Owner, r.Name, number, &github.IssueRequest{Milestone: &milestone}); err != nil {
+		return fmt.Errorf("unable to set milestone of %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
+
+// RemoveMilestone clears the milestone of an issue or pr.
+func (r Repo) RemoveMilestone(ctx context.Context, number int) error {
+	client := r.NewClient()
+
+	if _, _, err := client.Issues.RemoveMilestone(ctx, r.Owner, r.Name, number); err != nil {
+		return fmt.Errorf("unable to remove milestone of %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package gh
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
@@ -10,11 +11,11 @@ import (
 )
 
 func (r Repo) PrURL(pr int) string {
-	return "https://github.com/" + r.Owner + "/" + r.Name + "/pull/" + strconv.Itoa(pr)
+	return WebURL() + r.Owner + "/" + r.Name + "/pull/" + strconv.Itoa(pr)
 }
 
-func (r Repo) ListAllPullRequests(state string, cb func([]*github.PullRequest, *github.Response) error) error {
-	client, ctx := r.NewClient()
+func (r Repo) ListAllPullRequests(ctx context.Context, state string, cb func([]*github.PullRequest, *github.Response) error) error {
+	client := r.NewClient()
 
 	opts := &github.PullRequestListOptions{
 		State: state,
@@ -44,10 +45,10 @@ func (r Repo) ListAllPullRequests(state string, cb func([]*github.PullRequest, *
 	return nil
 }
 
-func (r Repo) GetAllPullRequests(state string) (*[]github.PullRequest, error) {
+func (r Repo) GetAllPullRequests(ctx context.Context, state string) (*[]github.PullRequest, error) {
 	var allPRs []github.PullRequest
 
-	err := r.ListAllPullRequests(state, func(prs []*github.PullRequest, resp *github.Response) error {
+	err := r.ListAllPullRequests(ctx, state, func(prs []*github.PullRequest, resp *github.Response) error {
 		for i, p := range prs {
 			if p == nil {
 				clog.Log.Debugf("prs[%d] was nil, skipping", i)
@@ -76,8 +77,8 @@ func (r Repo) GetAllPullRequests(state string) (*[]github.PullRequest, error) {
 	return &allPRs, nil
 }
 
-func (r Repo) GetPullRequest(pr int) (*github.PullRequest, error) {
-	client, ctx := r.NewClient()
+func (r Repo) GetPullRequest(ctx context.Context, pr int) (*github.PullRequest, error) {
+	client := r.NewClient()
 
 	p, _, err := client.PullRequests.Get(ctx, r.Owner, r.Name, pr)
 	if err != nil {
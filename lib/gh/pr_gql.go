@@ -1,6 +1,7 @@
 package gh
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -15,8 +16,12 @@ type ClosingIssue struct {
 type PullRequest struct {
 	NodeID                     string
 	Author                     string
+	AuthorAssociation          string // OWNER, MEMBER, COLLABORATOR, CONTRIBUTOR, FIRST_TIME_CONTRIBUTOR, ...
 	Number                     int
 	Title                      string
+	Body                       string
+	URL                        string
+	Repository                 string // owner/name
 	State                      string
 	ReviewDecision             string
 	CreatedAt                  time.Time
@@ -29,6 +34,7 @@ type PullRequest struct {
 	ReviewCommentCount         int
 	FilteredReviewCount        int
 	FilteredReviewCommentCount int
+	MaintainerReviewCount      int
 
 	ClosingIssues            []ClosingIssue
 	Assignees                []string
@@ -36,79 +42,91 @@ type PullRequest struct {
 	AssociatedProjectNumbers map[int]bool
 }
 
-type pullRequestsQuery struct {
+// pullRequestNode is the PR shape shared by the repository and search queries
+type pullRequestNode struct {
+	ID                 string
+	Number             int
+	Title              string
+	Body               string
+	URL                string
+	State              string
+	ReviewDecision     string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	ClosedAt           time.Time
+	IsDraft            bool
+	TotalCommentsCount int
+	AuthorAssociation  string
+
 	Repository struct {
-		PullRequests struct {
-			Nodes []struct {
-				ID                 string
-				Number             int
-				Title              string
-				State              string
-				ReviewDecision     string
-				CreatedAt          time.Time
-				UpdatedAt          time.Time
-				ClosedAt           time.Time
-				IsDraft            bool
-				TotalCommentsCount int
-
-				Assignees struct {
-					Nodes []struct {
-						Login string
-					}
-				} `graphql:"assignees(first: 10)"`
-
-				Author struct {
-					Login string
-				}
-
-				Labels struct {
-					Nodes []struct {
-						Name string
-					}
-				} `graphql:"labels(first: 100)"`
-
-				Milestone struct {
-					Title string
-				}
-
-				Reviews struct {
-					Nodes []struct {
-						Author struct {
-							Login string
-						}
-						Comments struct {
-							TotalCount int
-						}
-						State string
-					}
-				} `graphql:"reviews(first: 100)"`
-
-				ProjectItems struct {
-					Nodes []struct {
-						Project struct {
-							Number int
-						}
-					}
-				} `graphql:"projectItems(first: 10)"`
-
-				ClosingIssuesReferences struct {
-					Nodes []struct {
-						ID     string
-						Number int
-					}
-				} `graphql:"closingIssuesReferences(first: 10)"`
+		NameWithOwner string
+	}
+
+	Assignees struct {
+		Nodes []struct {
+			Login string
+		}
+	} `graphql:"assignees(first: 10)"`
+
+	Author struct {
+		Login string
+	}
+
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 100)"`
+
+	Milestone struct {
+		Title string
+	}
+
+	Reviews struct {
+		Nodes []struct {
+			Author struct {
+				Login string
 			}
+			AuthorAssociation string
+			Comments          struct {
+				TotalCount int
+			}
+			State string
+		}
+	} `graphql:"reviews(first: 100)"`
 
-			PageInfo struct {
-				EndCursor   string
-				HasNextPage bool
+	ProjectItems struct {
+		Nodes []struct {
+			Project struct {
+				Number int
 			}
+		}
+	} `graphql:"projectItems(first: 10)"`
+
+	ClosingIssuesReferences struct {
+		Nodes []struct {
+			ID     string
+			Number int
+		}
+	} `graphql:"closingIssuesReferences(first: 10)"`
+}
+
+type pageInfo struct {
+	EndCursor   string
+	HasNextPage bool
+}
+
+type pullRequestsQuery struct {
+	Repository struct {
+		PullRequests struct {
+			Nodes    []pullRequestNode
+			PageInfo pageInfo
 		} `graphql:"pullRequests(first: 40, after: $cursor, states: $state, orderBy: {field: CREATED_AT, direction: DESC})"`
 	} `graphql:"repository(owner: $owner, name: $repository)"`
 }
 
-func (r Repo) GetAllPullRequestsGQL(states, reviewers []string, limit int, progress func(int)) (*[]PullRequest, error) {
-	client, ctx, err := r.NewGraphQLClient()
+func (r Repo) GetAllPullRequestsGQL(ctx context.Context, states, reviewers []string, limit int, progress func(int)) (*[]PullRequest, error) {
+	client, err := r.NewGraphQLClient()
 	if err != nil {
 		return nil, fmt.Errorf("instantiating GraphQL client: %w", err)
 	}
@@ -136,7 +154,10 @@ func (r Repo) GetAllPullRequestsGQL(states, reviewers []string, limit int, progr
 	}
 
 	for {
-		if err := client.Query(ctx, &query, variables); err != nil {
+		if err := allowItemErrors(graphQLQuery(ctx, client, &query, variables), "listing pull requests"); err != nil {
+			if IsNotFound(err) {
+				return nil, fmt.Errorf("repository %s/%s not found: %w", r.Owner, r.Name, err)
+			}
 			return nil, err
 		}
 
@@ -159,59 +180,87 @@ func (q pullRequestsQuery) flatten(reviewers map[string]struct{}) []PullRequest
 	result := make([]PullRequest, 0, len(q.Repository.PullRequests.Nodes))
 
 	for _, pullRequest := range q.Repository.PullRequests.Nodes {
-		pr := PullRequest{
-			NodeID:                   pullRequest.ID,
-			Author:                   pullRequest.Author.Login,
-			Number:                   pullRequest.Number,
-			Title:                    pullRequest.Title,
-			State:                    pullRequest.State,
-			ReviewDecision:           pullRequest.ReviewDecision,
-			CreatedAt:                pullRequest.CreatedAt,
-			UpdatedAt:                pullRequest.UpdatedAt,
-			ClosedAt:                 pullRequest.ClosedAt,
-			Draft:                    pullRequest.IsDraft,
-			Milestone:                pullRequest.Milestone.Title,
-			TotalCommentCount:        pullRequest.TotalCommentsCount,
-			AssociatedLabels:         make(map[string]bool),
-			AssociatedProjectNumbers: make(map[int]bool),
-		}
+		result = append(result, pullRequest.flatten(reviewers))
+	}
 
-		for _, assignee := range pullRequest.Assignees.Nodes {
-			pr.Assignees = append(pr.Assignees, assignee.Login)
-		}
+	return result
+}
 
-		for _, project := range pullRequest.ProjectItems.Nodes {
-			pr.AssociatedProjectNumbers[project.Project.Number] = true
-		}
+func (pullRequest pullRequestNode) flatten(reviewers map[string]struct{}) PullRequest {
+	pr := PullRequest{
+		NodeID:                   pullRequest.ID,
+		Author:                   pullRequest.Author.Login,
+		AuthorAssociation:        pullRequest.AuthorAssociation,
+		Number:                   pullRequest.Number,
+		Title:                    pullRequest.Title,
+		Body:                     pullRequest.Body,
+		URL:                      pullRequest.URL,
+		Repository:               pullRequest.Repository.NameWithOwner,
+		State:                    pullRequest.State,
+		ReviewDecision:           pullRequest.ReviewDecision,
+		CreatedAt:                pullRequest.CreatedAt,
+		UpdatedAt:                pullRequest.UpdatedAt,
+		ClosedAt:                 pullRequest.ClosedAt,
+		Draft:                    pullRequest.IsDraft,
+		Milestone:                pullRequest.Milestone.Title,
+		TotalCommentCount:        pullRequest.TotalCommentsCount,
+		AssociatedLabels:         make(map[string]bool),
+		AssociatedProjectNumbers: make(map[int]bool),
+	}
 
-		for _, issue := range pullRequest.ClosingIssuesReferences.Nodes {
-			pr.ClosingIssues = append(pr.ClosingIssues, ClosingIssue{
-				NodeID: issue.ID,
-				Number: issue.Number,
-			})
-		}
+	for _, assignee := range pullRequest.Assignees.Nodes {
+		pr.Assignees = append(pr.Assignees, assignee.Login)
+	}
 
-		for _, label := range pullRequest.Labels.Nodes {
-			pr.AssociatedLabels[label.Name] = true
+	for _, project := range pullRequest.ProjectItems.Nodes {
+		pr.AssociatedProjectNumbers[project.Project.Number] = true
+	}
+
+	for _, issue := range pullRequest.ClosingIssuesReferences.Nodes {
+		pr.ClosingIssues = append(pr.ClosingIssues, ClosingIssue{
+			NodeID: issue.ID,
+			Number: issue.Number,
+		})
+	}
+
+	for _, label := range pullRequest.Labels.Nodes {
+		pr.AssociatedLabels[label.Name] = true
+	}
+
+	for _, review := range pullRequest.Reviews.Nodes {
+		// We're only interested in `APPROVED`, `CHANGES_REQUESTED`, and `DISMISSED` states.
+		if review.State == string(githubv4.PullRequestReviewStateCommented) || review.State == string(githubv4.PullRequestReviewStatePending) {
+			continue
 		}
+		pr.TotalReviewCount++
+		pr.ReviewCommentCount += review.Comments.TotalCount
 
-		for _, review := range pullRequest.Reviews.Nodes {
-			// We're only interested in `APPROVED`, `CHANGES_REQUESTED`, and `DISMISSED` states.
-			if review.State == string(githubv4.PullRequestReviewStateCommented) || review.State == string(githubv4.PullRequestReviewStatePending) {
-				continue
-			}
-			pr.TotalReviewCount++
-			pr.ReviewCommentCount += review.Comments.TotalCount
+		if IsMaintainerAssociation(review.AuthorAssociation) {
+			pr.MaintainerReviewCount++
+		}
 
-			// Only add filtered review count if `reviewers` filter was provided
-			if _, ok := reviewers[review.Author.Login]; ok {
-				pr.FilteredReviewCount++
-				pr.FilteredReviewCommentCount += review.Comments.TotalCount
-			}
+		// Only add filtered review count if `reviewers` filter was provided
+		if _, ok := reviewers[review.Author.Login]; ok {
+			pr.FilteredReviewCount++
+			pr.FilteredReviewCommentCount += review.Comments.TotalCount
 		}
+	}
+
+	return pr
+}
 
-		result = append(result, pr)
+// IsMaintainerAssociation returns true if an author association is for someone with write access to the
+// repo (OWNER, MEMBER or COLLABORATOR) rather than a community contributor.
+func IsMaintainerAssociation(association string) bool {
+	switch association {
+	case "OWNER", "MEMBER", "COLLABORATOR":
+		return true
 	}
 
-	return result
+	return false
+}
+
+// IsFirstTimeAssociation returns true if an author association is for someone contributing for the first time.
+func IsFirstTimeAssociation(association string) bool {
+	return association == "FIRST_TIME_CONTRIBUTOR" || association == "FIRST_TIMER"
 }
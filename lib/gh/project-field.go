@@ -0,0 +1,132 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type singleSelectOptionsResult struct {
+	Data struct {
+		Node struct {
+			Options []struct {
+				ID          string `json:"id"`
+				Name        string `json:"name"`
+				Color       string `json:"color"`
+				Description string `json:"description"`
+			} `json:"options"`
+		} `json:"node"`
+	} `json:"data"`
+}
+
+type updateFieldOptionsResult struct {
+	Data struct {
+		UpdateProjectV2Field struct {
+			ProjectV2Field struct {
+				Options []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"options"`
+			} `json:"projectV2Field"`
+		} `json:"updateProjectV2Field"`
+	} `json:"data"`
+}
+
+// AddSingleSelectOptions creates options on a single select field. updateProjectV2Field replaces all of a
+// field's options so the existing ones are passed back by id to keep them (and the item values using them),
+// the new options are gray with no description. The loaded project details are updated with the new option IDs.
+func (p *Project) AddSingleSelectOptions(ctx context.Context, fieldName string, names []string) error {
+	if p.ProjectDetails == nil {
+		return errors.New("project details not loaded yet")
+	}
+
+	fieldID, ok := p.FieldIDs[fieldName]
+	if !ok {
+		return fmt.Errorf("field %q not found in project", fieldName)
+	}
+	if p.FieldTypes[fieldName] != ItemValueTypeSingleSelect {
+		return fmt.Errorf("field %q is not a single select field", fieldName)
+	}
+
+	q := `query=
+        query($field: ID!) {
+            node(id: $field) {
+                ... on ProjectV2SingleSelectField {
+                    options {
+                        id
+                        name
+                        color
+                        description
+                    }
+                }
+            }
+        }
+    `
+
+	var current singleSelectOptionsResult
+	if err := p.GraphQLQueryUnmarshal(ctx, q, [][]string{{"-f", "field=" + fieldID}}, &current); err != nil {
+		return fmt.Errorf("reading options for field %q: %w", fieldName, err)
+	}
+
+	// the options are written inline as they are a list of input objects, json strings are valid graphql strings
+	quote := func(s string) string {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+
+	existing := map[string]bool{}
+	options := make([]string, 0, len(current.Data.Node.Options)+len(names))
+	for _, o := range current.Data.Node.Options {
+		existing[strings.ToLower(o.Name)] = true
+		options = append(options, fmt.Sprintf("{id: %s, name: %s, color: %s, description: %s}", quote(o.ID), quote(o.Name), o.Color, quote(o.Description)))
+	}
+
+	added := 0
+	for _, name := range names {
+		if existing[strings.ToLower(name)] {
+			continue
+		}
+		existing[strings.ToLower(name)] = true
+		options = append(options, fmt.Sprintf(`{name: %s, color: GRAY, description: ""}`, quote(name)))
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+
+	m := fmt.Sprintf(`query=
+        mutation($field: ID!) {
+            updateProjectV2Field(input: {fieldId: $field, singleSelectOptions: [%s]}) {
+                projectV2Field {
+                    ... on ProjectV2SingleSelectField {
+                        options {
+                            id
+                            name
+                        }
+                    }
+                }
+            }
+        }
+    `, strings.Join(options, ", "))
+
+	var result updateFieldOptionsResult
+	if err := p.GraphQLQueryUnmarshal(ctx, m, [][]string{{"-f", "field=" + fieldID}}, &result); err != nil {
+		return fmt.Errorf("adding options to field %q: %w", fieldName, err)
+	}
+
+	ids := map[string]string{}
+	byID := map[string]string{}
+	for _, o := range result.Data.UpdateProjectV2Field.ProjectV2Field.Options {
+		ids[o.Name] = o.ID
+		byID[o.ID] = o.Name
+	}
+	p.SingleSelectOptionIDs[fieldName] = ids
+	p.SingleSelectOptionNames[fieldName] = byID
+	if fieldName == "Status" {
+		p.StatusIDs = ids
+	}
+
+	return nil
+}
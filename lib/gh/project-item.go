@@ -1,6 +1,7 @@
 package gh
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +11,7 @@ import (
 
 // HasItem checks whether a given content node (issue or PR) is already in this project.
 // Returns the project item ID if found, nil if not found.
-func (p *Project) HasItem(nodeID string) (*string, error) {
+func (p *Project) HasItem(ctx context.Context, nodeID string) (*string, error) {
 	if p.ProjectDetails == nil {
 		return nil, errors.New("project details not loaded yet")
 	}
@@ -63,7 +64,10 @@ func (p *Project) HasItem(nodeID string) (*string, error) {
 	}
 
 	var result hasItemResult
-	if err := p.GraphQLQueryUnmarshal(q, params, &result); err != nil {
+	if err := p.GraphQLQueryUnmarshal(ctx, q, params, &result); err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("content %s not found: %w", nodeID, err)
+		}
 		return nil, fmt.Errorf("checking if item exists in project: %w", err)
 	}
 
@@ -76,7 +80,7 @@ func (p *Project) HasItem(nodeID string) (*string, error) {
 	return nil, nil
 }
 
-func (p *Project) AddItem(nodeID string) (*string, error) {
+func (p *Project) AddItem(ctx context.Context, nodeID string) (*string, error) {
 	if p.ProjectDetails == nil {
 		return nil, errors.New("project details not loaded yet")
 	}
@@ -97,10 +101,36 @@ func (p *Project) AddItem(nodeID string) (*string, error) {
 		{"--jq", ".data.addProjectV2ItemById.item.id"},
 	}
 
-	return p.GraphQLQuery(q, fields)
+	return p.GraphQLQuery(ctx, q, fields)
+}
+
+// DeleteItem removes an item from the project, the issue or pr itself is untouched.
+func (p *Project) DeleteItem(ctx context.Context, itemID string) error {
+	if p.ProjectDetails == nil {
+		return errors.New("project details not loaded yet")
+	}
+
+	q := `query=
+        mutation($project:ID!, $item:ID!) {
+          deleteProjectV2Item(input: {projectId: $project, itemId: $item}) {
+            deletedItemId
+          }
+        }
+    `
+
+	fields := [][]string{
+		{"-f", "project=" + p.ID},
+		{"-f", "item=" + itemID},
+	}
+
+	if _, err := p.GraphQLQuery(ctx, q, fields); err != nil {
+		return fmt.Errorf("deleting item %s: %w", itemID, err)
+	}
+
+	return nil
 }
 
-func (p *Project) SetItemStatus(itemID, status string) error {
+func (p *Project) SetItemStatus(ctx context.Context, itemID, status string) error {
 	// should this be a method of ProjectItem? (to do this we'll need to figure out how to get all the fields and values
 
 	if p.ProjectDetails == nil {
@@ -111,7 +141,7 @@ func (p *Project) SetItemStatus(itemID, status string) error {
 		{Name: "number", FieldID: p.FieldIDs["Status"], Type: ItemValueTypeSingleSelect, Value: status},
 	}
 
-	return p.UpdateItem(itemID, fields)
+	return p.UpdateItem(ctx, itemID, fields)
 }
 
 // ProjectItemsResult is the result of the project items query; for now we hard code the project
@@ -164,7 +194,7 @@ type ProjectItem struct {
 
 // GetItems returns all items in the project.
 // todo: allow configure the fields we want to get
-func (p *Project) GetItems() ([]ProjectItem, error) {
+func (p *Project) GetItems(ctx context.Context) ([]ProjectItem, error) {
 	q := `query=
 		query($org: String!, $number: Int!, $cursor: String) {
 			organization(login: $org) {
@@ -225,11 +255,16 @@ func (p *Project) GetItems() ([]ProjectItem, error) {
 		}
 
 		var result ProjectItemsResult
-		if err := p.GraphQLQueryUnmarshal(q, params, &result); err != nil {
-			return nil, err
+		if err := allowItemErrors(p.GraphQLQueryUnmarshal(ctx, q, params, &result), "listing project items"); err != nil {
+			return nil, fmt.Errorf("listing items of project %d: %w", p.Number, err)
 		}
 
 		for _, i := range result.Data.Organization.ProjectV2.Items.Nodes {
+			// items that errored are null
+			if i.ID == "" {
+				continue
+			}
+
 			item := ProjectItem{
 				ID:     i.ID,
 				Type:   i.Type,
@@ -295,94 +330,115 @@ type ProjectItemField struct {
 }
 
 // UpdateItem updates the fields of a project item by building a dynamic GraphQL mutation.
-func (p *Project) UpdateItem(itemID string, fields []ProjectItemField) error {
-	// We'll build the mutation parts dynamically; Always include project and item as variables
-	varDefs := []string{"$project:ID!", "$item:ID!"}
-	setCalls := []string{}
-	params := [][]string{
-		{"-f", "project=" + p.ID},
-		{"-f", "item=" + itemID},
-	}
+func (p *Project) UpdateItem(ctx context.Context, itemID string, fields []ProjectItemField) error {
+	// Always include project and item as variables
+	m := newMutation(p.ID)
+	m.variable("item", "ID!", itemID)
 
-	// For each field, we create a pair of variables: one for the fieldId, and one for the value
-	// We'll name them based on the field's index to ensure uniqueness.
 	for _, f := range fields {
-		fieldAlias := f.Name
-		if fieldAlias == "" {
+		if f.Name == "" {
 			return errors.New("field name cannot be empty")
 		}
-		fieldIDVar := fmt.Sprintf("$%s_field", fieldAlias)
-		fieldValueVar := fmt.Sprintf("$%s_value", fieldAlias)
-
-		// Validate the field id it can never be empty
-		if f.FieldID == "" {
-			return fmt.Errorf("field ID for %s is empty", fieldAlias)
+		if err := m.setField("set_"+f.Name, f.Name, "$item", f); err != nil {
+			return err
 		}
+	}
 
-		// Variable definitions based on Type
-		varDefs = append(varDefs, fieldIDVar+":ID!")
-		switch f.Type {
-		case ItemValueTypeText, ItemValueTypeSingleSelect:
-			varDefs = append(varDefs, fieldValueVar+":String!")
-		case ItemValueTypeNumber:
-			varDefs = append(varDefs, fieldValueVar+":Float!")
-		case ItemValueTypeDate:
-			varDefs = append(varDefs, fieldValueVar+":Date!")
-		default:
-			return fmt.Errorf("unsupported value type: %v for %s ", f.Type, fieldAlias)
+	mutation := m.query()
+	out, err := p.GraphQLQuery(ctx, mutation, m.params)
+	if err != nil {
+		outStr := ""
+		if out != nil {
+			outStr = *out
 		}
+		return fmt.Errorf("error updating project item: %w\noutput: %s\nmutation: %s", err, outStr, mutation)
+	}
 
-		// Add parameters for this field
-		params = append(params, []string{"-f", fmt.Sprintf("%s_field=%s", fieldAlias, f.FieldID)})
-		switch f.Type {
-		case ItemValueTypeText, ItemValueTypeDate, ItemValueTypeSingleSelect:
-			params = append(params, []string{"-f", fmt.Sprintf("%s_value=%v", fieldAlias, f.Value)})
-		case ItemValueTypeNumber:
-			// Use -F so the value is recognized as a JSON number
-			params = append(params, []string{"-F", fmt.Sprintf("%s_value=%v", fieldAlias, f.Value)})
-		}
+	return nil
+}
 
-		// Build the update call for the mutation
-		var valuePart string
-		switch f.Type {
-		case ItemValueTypeText:
-			valuePart = fmt.Sprintf("value: { text: %s }", fieldValueVar)
-		case ItemValueTypeNumber:
-			valuePart = fmt.Sprintf("value: { number: %s }", fieldValueVar)
-		case ItemValueTypeDate:
-			valuePart = fmt.Sprintf("value: { date: %s }", fieldValueVar)
-		case ItemValueTypeSingleSelect:
-			valuePart = fmt.Sprintf("value: { singleSelectOptionId: %s }", fieldValueVar)
-		}
+// mutation assembles a mutation of aliased calls on a project along with the gh parameters for its
+// variables
+type mutation struct {
+	varDefs []string
+	calls   []string
+	params  [][]string
+}
+
+// newMutation returns a mutation with the project as the $project variable
+func newMutation(projectID string) *mutation {
+	m := &mutation{}
+	m.variable("project", "ID!", projectID)
+
+	return m
+}
+
+// variable defines $name, numbers are passed with -F so they are recognized as JSON numbers
+func (m *mutation) variable(name, typ string, value any) {
+	m.varDefs = append(m.varDefs, "$"+name+":"+typ)
+
+	flag := "-f"
+	if typ == "Float!" {
+		flag = "-F"
+	}
+	m.params = append(m.params, []string{flag, fmt.Sprintf("%s=%v", name, value)})
+}
+
+// call adds an aliased call to the mutation
+func (m *mutation) call(alias, call string) {
+	m.calls = append(m.calls, fmt.Sprintf("\n  %s: %s", alias, call))
+}
 
-		setCalls = append(setCalls, fmt.Sprintf(`
-  %s: updateProjectV2ItemFieldValue(input: {
+// addItem adds the content node to the project
+func (m *mutation) addItem(alias, nodeID string) {
+	m.variable(alias+"_content", "ID!", nodeID)
+	m.call(alias, fmt.Sprintf(`addProjectV2ItemById(input: {projectId: $project, contentId: $%s_content}) {
+    item { id }
+  }`, alias))
+}
+
+// setField sets a field of the item in itemVar, the field id and value variables are named from prefix
+func (m *mutation) setField(alias, prefix, itemVar string, f ProjectItemField) error {
+	// Validate the field id it can never be empty
+	if f.FieldID == "" {
+		return fmt.Errorf("field ID for %s is empty", f.Name)
+	}
+
+	var valueType, valuePart string
+	switch f.Type {
+	case ItemValueTypeText:
+		valueType, valuePart = "String!", "text"
+	case ItemValueTypeSingleSelect:
+		valueType, valuePart = "String!", "singleSelectOptionId"
+	case ItemValueTypeNumber:
+		valueType, valuePart = "Float!", "number"
+	case ItemValueTypeDate:
+		valueType, valuePart = "Date!", "date"
+	default:
+		return fmt.Errorf("unsupported value type: %v for %s ", f.Type, f.Name)
+	}
+
+	m.variable(prefix+"_field", "ID!", f.FieldID)
+	m.variable(prefix+"_value", valueType, f.Value)
+	m.call(alias, fmt.Sprintf(`updateProjectV2ItemFieldValue(input: {
     projectId: $project
-    itemId: $item
-    fieldId: %s
-    %s
+    itemId: %s
+    fieldId: $%s_field
+    value: { %s: $%s_value }
   }) {
     projectV2Item { id }
-  }`, "set_"+fieldAlias, fieldIDVar, valuePart))
-	}
+  }`, itemVar, prefix, valuePart, prefix))
+
+	return nil
+}
 
-	// Now assemble the full mutation
-	mutation := fmt.Sprintf(`query=mutation(
+// query returns the mutation as the query parameter for gh
+func (m *mutation) query() string {
+	return fmt.Sprintf(`query=mutation(
   %s
 ) {
   %s
-}`, strings.Join(varDefs, ", "), strings.Join(setCalls, "\n"))
-
-	out, err := p.GraphQLQuery(mutation, params)
-	if err != nil {
-		outStr := ""
-		if out != nil {
-			outStr = *out
-		}
-		return fmt.Errorf("error updating project item: %w\noutput: %s\nmutation: %s", err, outStr, mutation)
-	}
-
-	return nil
+}`, strings.Join(m.varDefs, ", "), strings.Join(m.calls, "\n"))
 }
 
 // ProjectItemFieldValue holds a field value read from a project item.
@@ -391,12 +447,79 @@ type ProjectItemFieldValue struct {
 	Value any // string for text/date/singleSelect option ID, float64 for number
 }
 
+// ProjectItemValues is a project item along with the values of a set of its fields.
+type ProjectItemValues struct {
+	ID     string // project item ID
+	Type   string // ISSUE, PULL_REQUEST, DRAFT_ISSUE, ...
+	NodeID string // actual pr/issue node id
+	URL    string
+	Values map[string]ProjectItemFieldValue // field name -> value, unset fields are omitted
+}
+
 // GetItemFieldValuesByNodeID looks up the project item for a given content node ID (e.g. an issue)
 // and returns the field values for the requested field names. The returned map is keyed by field name.
 // If the item is not found in the project, returns nil map with no error.
-func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []string) (map[string]ProjectItemFieldValue, error) {
+func (p *Project) GetItemFieldValuesByNodeID(ctx context.Context, contentNodeID string, fieldNames []string) (map[string]ProjectItemFieldValue, error) {
+	var values map[string]ProjectItemFieldValue
+
+	err := p.ListItemFieldValues(ctx, fieldNames, func(item ProjectItemValues) bool {
+		if item.NodeID != contentNodeID {
+			return true
+		}
+
+		values = item.Values
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// GetItemsFieldValuesByNodeIDs looks up the project items for a set of content node IDs in a single pass
+// over the project, returning them keyed by node ID. Items not in the project are omitted.
+func (p *Project) GetItemsFieldValuesByNodeIDs(ctx context.Context, contentNodeIDs, fieldNames []string) (map[string]ProjectItemValues, error) {
+	want := map[string]bool{}
+	for _, id := range contentNodeIDs {
+		want[id] = true
+	}
+
+	items := map[string]ProjectItemValues{}
+	err := p.ListItemFieldValues(ctx, fieldNames, func(item ProjectItemValues) bool {
+		if want[item.NodeID] {
+			items[item.NodeID] = item
+		}
+
+		return len(items) < len(want)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetItemsFieldValues returns every item in the project along with the values of the requested fields.
+func (p *Project) GetItemsFieldValues(ctx context.Context, fieldNames []string) ([]ProjectItemValues, error) {
+	var items []ProjectItemValues
+
+	err := p.ListItemFieldValues(ctx, fieldNames, func(item ProjectItemValues) bool {
+		items = append(items, item)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ListItemFieldValues pages through all items in the project calling cb with each item and the values
+// of the requested fields. Paging stops early when cb returns false.
+func (p *Project) ListItemFieldValues(ctx context.Context, fieldNames []string, cb func(ProjectItemValues) bool) error {
 	if p.ProjectDetails == nil {
-		return nil, errors.New("project details not loaded yet")
+		return errors.New("project details not loaded yet")
 	}
 
 	// Build dynamic fieldValueByName queries for each requested field
@@ -438,12 +561,15 @@ func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []
 						}
 						nodes {
 							id
+							type
 							content {
 								... on Issue {
 									id
+									url
 								}
 								... on PullRequest {
 									id
+									url
 								}
 							}
 							%s
@@ -470,8 +596,10 @@ func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []
 
 	type itemNode struct {
 		ID      string `json:"id"`
+		Type    string `json:"type"`
 		Content struct {
-			ID string `json:"id"`
+			ID  string `json:"id"`
+			URL string `json:"url"`
 		} `json:"content"`
 		// Dynamic field values will be parsed from raw JSON
 	}
@@ -501,28 +629,29 @@ func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []
 		}
 
 		var result queryResult
-		if err := p.GraphQLQueryUnmarshal(q, queryParams, &result); err != nil {
-			return nil, fmt.Errorf("querying project items for field values: %w", err)
+		if err := allowItemErrors(p.GraphQLQueryUnmarshal(ctx, q, queryParams, &result), "listing project items"); err != nil {
+			return fmt.Errorf("querying project items for field values: %w", err)
 		}
 
 		for _, rawNode := range result.Data.Organization.ProjectV2.Items.Nodes {
-			// Parse the node to get the content ID
 			var node itemNode
-			if err := json.Unmarshal(rawNode, &node); err != nil {
+			if err := json.Unmarshal(rawNode, &node); err != nil || node.ID == "" {
 				continue
 			}
 
-			if node.Content.ID != contentNodeID {
-				continue
-			}
-
-			// Found the item — parse the field values
+			// parse the field values
 			var rawMap map[string]json.RawMessage
 			if err := json.Unmarshal(rawNode, &rawMap); err != nil {
-				return nil, fmt.Errorf("parsing item fields: %w", err)
+				return fmt.Errorf("parsing item fields: %w", err)
 			}
 
-			result := map[string]ProjectItemFieldValue{}
+			item := ProjectItemValues{
+				ID:     node.ID,
+				Type:   node.Type,
+				NodeID: node.Content.ID,
+				URL:    node.Content.URL,
+				Values: map[string]ProjectItemFieldValue{},
+			}
 			for i, name := range fieldNames {
 				alias := fmt.Sprintf("f%d", i)
 				raw, ok := rawMap[alias]
@@ -537,17 +666,19 @@ func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []
 
 				switch fv.Typename {
 				case "ProjectV2ItemFieldTextValue":
-					result[name] = ProjectItemFieldValue{Type: ItemValueTypeText, Value: fv.Text}
+					item.Values[name] = ProjectItemFieldValue{Type: ItemValueTypeText, Value: fv.Text}
 				case "ProjectV2ItemFieldNumberValue":
-					result[name] = ProjectItemFieldValue{Type: ItemValueTypeNumber, Value: fv.Number}
+					item.Values[name] = ProjectItemFieldValue{Type: ItemValueTypeNumber, Value: fv.Number}
 				case "ProjectV2ItemFieldDateValue":
-					result[name] = ProjectItemFieldValue{Type: ItemValueTypeDate, Value: fv.Date}
+					item.Values[name] = ProjectItemFieldValue{Type: ItemValueTypeDate, Value: fv.Date}
 				case "ProjectV2ItemFieldSingleSelectValue":
-					result[name] = ProjectItemFieldValue{Type: ItemValueTypeSingleSelect, Value: fv.SingleSelectOptionID}
+					item.Values[name] = ProjectItemFieldValue{Type: ItemValueTypeSingleSelect, Value: fv.SingleSelectOptionID}
 				}
 			}
 
-			return result, nil
+			if !cb(item) {
+				return nil
+			}
 		}
 
 		if !result.Data.Organization.ProjectV2.Items.PageInfo.HasNextPage {
@@ -556,6 +687,5 @@ func (p *Project) GetItemFieldValuesByNodeID(contentNodeID string, fieldNames []
 		cursor = result.Data.Organization.ProjectV2.Items.PageInfo.EndCursor
 	}
 
-	// Item not found in project
-	return nil, nil
+	return nil
 }
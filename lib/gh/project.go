@@ -1,6 +1,8 @@
 package gh
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 )
 
@@ -12,20 +14,12 @@ type Project struct {
 	*ProjectDetails
 }
 
-func NewProject(owner string, number int, token string) Project {
-	p := Project{
+func NewProject(owner string, number int, token Token) Project {
+	return Project{
 		Owner:  owner,
 		Number: number,
-		Token: Token{
-			Token: nil,
-		},
+		Token:  token,
 	}
-
-	if token != "" {
-		p.Token.Token = &token
-	}
-
-	return p
 }
 
 type ProjectDetails struct {
@@ -52,9 +46,10 @@ type ProjectDetailsResult struct {
 				ID     string `json:"id"`
 				Fields struct {
 					Nodes []struct {
-						ID      string `json:"id"`
-						Name    string `json:"name"`
-						Options []struct {
+						ID       string `json:"id"`
+						Name     string `json:"name"`
+						DataType string `json:"dataType"`
+						Options  []struct {
 							ID   string `json:"id"`
 							Name string `json:"name"`
 						} `json:"options"`
@@ -65,7 +60,7 @@ type ProjectDetailsResult struct {
 	} `json:"data"`
 }
 
-func (p *Project) LoadDetails() error {
+func (p *Project) LoadDetails(ctx context.Context) error {
 	q := `query=
         query($org: String!, $number: Int!) {
             organization(login: $org){
@@ -76,10 +71,12 @@ func (p *Project) LoadDetails() error {
                             ... on ProjectV2Field {
                                 id
                                 name
+                                dataType
                             }
                             ... on ProjectV2SingleSelectField {
                                 id
                                 name
+                                dataType
                                 options {
                                     id
                                     name
@@ -98,8 +95,14 @@ func (p *Project) LoadDetails() error {
 	}
 
 	var result ProjectDetailsResult
-	if err := p.GraphQLQueryUnmarshal(q, params, &result); err != nil {
-		return err
+	if err := p.GraphQLQueryUnmarshal(ctx, q, params, &result); err != nil {
+		if IsNotFound(err) {
+			return fmt.Errorf("project %d not found for owner %s: %w", p.Number, p.Owner, err)
+		}
+		return fmt.Errorf("loading project %d for owner %s: %w", p.Number, p.Owner, err)
+	}
+	if result.Data.Organization.ProjectV2.ID == "" {
+		return fmt.Errorf("project %d not found for owner %s", p.Number, p.Owner)
 	}
 
 	project := ProjectDetails{
@@ -112,6 +115,12 @@ func (p *Project) LoadDetails() error {
 	}
 
 	for _, f := range result.Data.Organization.ProjectV2.Fields.Nodes {
+		// system fields such as Repository, Labels or Parent issue are filled in by GitHub and can't be set,
+		// leaving them out keeps fields computed with the same name from failing every update
+		if !isSettableFieldType(f.DataType) {
+			continue
+		}
+
 		field := struct {
 			ID      string
 			Name    string
@@ -141,9 +150,14 @@ func (p *Project) LoadDetails() error {
 				project.SingleSelectOptionNames[f.Name][s.ID] = s.Name
 			}
 		} else {
-			// Default to text for regular fields — number/date are indistinguishable
-			// from the current query but UpdateItem handles them by type
-			project.FieldTypes[f.Name] = ItemValueTypeText
+			switch f.DataType {
+			case "NUMBER":
+				project.FieldTypes[f.Name] = ItemValueTypeNumber
+			case "DATE":
+				project.FieldTypes[f.Name] = ItemValueTypeDate
+			default:
+				project.FieldTypes[f.Name] = ItemValueTypeText
+			}
 		}
 
 		project.Fields = append(project.Fields, field)
@@ -162,3 +176,14 @@ func (p *Project) LoadDetails() error {
 	p.ProjectDetails = &project
 	return nil
 }
+
+// isSettableFieldType returns true for the ProjectV2FieldType of a field whose values can be set, empty when
+// it wasn't queried
+func isSettableFieldType(dataType string) bool {
+	switch dataType {
+	case "", "TEXT", "NUMBER", "DATE", "SINGLE_SELECT", "ITERATION":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,245 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// defaults that keep a batched mutation well inside GitHub's limits on the size and complexity of a request
+const (
+	DefaultBatchSize      = 20 // items per request
+	DefaultBatchMutations = 50 // aliased mutations per request
+)
+
+// ItemUpdate is a set of field updates for one project item
+type ItemUpdate struct {
+	ItemID string
+	Fields []ProjectItemField
+}
+
+// ItemBatcher packs adds and field updates for many items into aliased mutations of at most Size items and
+// Mutations calls each. A failed request is retried in halves down to single items so one bad item doesn't
+// fail the rest of its batch.
+type ItemBatcher struct {
+	Project   *Project
+	Size      int
+	Mutations int
+
+	query func(ctx context.Context, query string, params [][]string) (*string, error) // Project.GraphQLQuery when nil
+}
+
+// NewItemBatcher returns a batcher for the project, sizes of 0 or less use the defaults
+func (p *Project) NewItemBatcher(size, mutations int) *ItemBatcher {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if mutations <= 0 {
+		mutations = DefaultBatchMutations
+	}
+
+	return &ItemBatcher{Project: p, Size: size, Mutations: mutations}
+}
+
+// batchOp is one item's part of a batched mutation
+type batchOp struct {
+	key   string // node id for adds, item id for updates
+	calls int
+	build func(m *mutation, alias string) error // adds the item's calls, aliased alias or alias_*
+}
+
+// AddItems adds content nodes to the project, returning the item ids of those added and the errors of
+// those that failed by node id
+func (b *ItemBatcher) AddItems(ctx context.Context, nodeIDs []string) (map[string]string, map[string]error) {
+	ops := make([]batchOp, 0, len(nodeIDs))
+	seen := map[string]bool{}
+	for _, id := range nodeIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		ops = append(ops, batchOp{
+			key:   id,
+			calls: 1,
+			build: func(m *mutation, alias string) error {
+				m.addItem(alias, id)
+				return nil
+			},
+		})
+	}
+
+	ids := map[string]string{}
+	errs := map[string]error{}
+	data := b.run(ctx, "adding project items", ops, errs)
+	for id, raw := range data {
+		var r struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil || r.Item.ID == "" {
+			errs[id] = fmt.Errorf("adding project item: no item id in the response for %s", id)
+			continue
+		}
+		ids[id] = r.Item.ID
+	}
+
+	return ids, errs
+}
+
+// UpdateItems sets the fields of project items, returning the errors of those that failed by item id
+func (b *ItemBatcher) UpdateItems(ctx context.Context, updates []ItemUpdate) map[string]error {
+	errs := map[string]error{}
+
+	ops := make([]batchOp, 0, len(updates))
+	for _, u := range updates {
+		if len(u.Fields) == 0 {
+			continue
+		}
+
+		op := batchOp{
+			key:   u.ItemID,
+			calls: len(u.Fields),
+			build: func(m *mutation, alias string) error {
+				m.variable(alias+"_item", "ID!", u.ItemID)
+				for i, f := range u.Fields {
+					fa := alias + "_f" + strconv.Itoa(i)
+					if err := m.setField(fa, fa, "$"+alias+"_item", f); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+
+		// invalid fields fail the item rather than the batch it is in
+		if err := op.build(newMutation(""), "i"); err != nil {
+			errs[u.ItemID] = fmt.Errorf("updating project item %s: %w", u.ItemID, err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	b.run(ctx, "updating project items", ops, errs)
+
+	return errs
+}
+
+// run sends the ops in batches, returning the response data of each op that succeeded by key and adding
+// the errors of those that failed to errs
+func (b *ItemBatcher) run(ctx context.Context, what string, ops []batchOp, errs map[string]error) map[string]json.RawMessage {
+	data := map[string]json.RawMessage{}
+
+	var batch []batchOp
+	calls := 0
+	for _, op := range ops {
+		if len(batch) > 0 && (len(batch) >= b.Size || calls+op.calls > b.Mutations) {
+			b.send(ctx, what, batch, data, errs)
+			batch, calls = nil, 0
+		}
+		batch = append(batch, op)
+		calls += op.calls
+	}
+	if len(batch) > 0 {
+		b.send(ctx, what, batch, data, errs)
+	}
+
+	return data
+}
+
+// send runs one batch as a single mutation, mapping the errors of each alias back to its op. When the
+// request fails as a whole it is split in half and each half sent again.
+func (b *ItemBatcher) send(ctx context.Context, what string, batch []batchOp, data map[string]json.RawMessage, errs map[string]error) {
+	m := newMutation(b.Project.ID)
+	for i, op := range batch {
+		if err := op.build(m, "i"+strconv.Itoa(i)); err != nil {
+			// ops are validated before they are batched so this is a bug, fail the batch rather than
+			// send a partial mutation
+			for _, o := range batch {
+				errs[o.key] = fmt.Errorf("%s: building mutation: %w", what, err)
+			}
+			return
+		}
+	}
+
+	query := b.query
+	if query == nil {
+		query = b.Project.GraphQLQuery
+	}
+	out, err := query(ctx, m.query(), m.params)
+	aliasErrs, ok := aliasErrors(err, len(batch))
+
+	var r struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if ok && out != nil {
+		if uerr := json.Unmarshal([]byte(*out), &r); uerr != nil {
+			ok, err = false, fmt.Errorf("decoding response: %w", uerr)
+		}
+	}
+
+	if !ok || out == nil {
+		// splitting won't help once cancelled
+		if len(batch) == 1 || ctx.Err() != nil {
+			for _, op := range batch {
+				errs[op.key] = fmt.Errorf("%s: %w", what, err)
+			}
+			return
+		}
+
+		clog.Log.Warnf("%s: batch of %d failed, retrying in smaller batches: %s", what, len(batch), err)
+		half := len(batch) / 2
+		b.send(ctx, what, batch[:half], data, errs)
+		b.send(ctx, what, batch[half:], data, errs)
+		return
+	}
+
+	for i, op := range batch {
+		if ge, failed := aliasErrs[i]; failed {
+			errs[op.key] = fmt.Errorf("%s: %w", what, ge)
+			continue
+		}
+		data[op.key] = r.Data["i"+strconv.Itoa(i)]
+	}
+}
+
+// aliasErrors groups the errors of a batched mutation by the index of the op whose alias they are for,
+// false when the request failed as a whole or has errors that can't be attributed to an op
+func aliasErrors(err error, n int) (map[int]*GraphQLErrors, bool) {
+	if err == nil {
+		return nil, true
+	}
+
+	var ge *GraphQLErrors
+	if !errors.As(err, &ge) || !ge.Partial {
+		return nil, false
+	}
+
+	byOp := map[int]*GraphQLErrors{}
+	for _, e := range ge.Errors {
+		if len(e.Path) == 0 {
+			return nil, false
+		}
+
+		// aliases are i<n> or i<n>_f<m>
+		alias, _ := e.Path[0].(string)
+		alias, _, _ = strings.Cut(alias, "_")
+		i, cerr := strconv.Atoi(strings.TrimPrefix(alias, "i"))
+		if !strings.HasPrefix(alias, "i") || cerr != nil || i < 0 || i >= n {
+			return nil, false
+		}
+
+		if byOp[i] == nil {
+			byOp[i] = &GraphQLErrors{}
+		}
+		byOp[i].Errors = append(byOp[i].Errors, e)
+	}
+
+	return byOp, true
+}
@@ -0,0 +1,296 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAliasErrors(t *testing.T) {
+	t.Parallel()
+
+	pathErr := func(path ...any) GraphQLError {
+		return GraphQLError{Type: "NOT_FOUND", Message: "not found", Path: path}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		n    int
+		want map[int]int // op index -> number of errors
+		ok   bool
+	}{
+		{
+			name: "no error",
+			n:    2,
+			ok:   true,
+		},
+		{
+			name: "not graphql errors",
+			err:  errors.New("gh graphql failed: exit status 1"),
+			n:    2,
+		},
+		{
+			name: "not partial",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i0")}},
+			n:    2,
+		},
+		{
+			name: "no path",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr()}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "unknown alias",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("project")}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "alias not a number",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("ix_f0")}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "index out of range",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i2")}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "negative index",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i-1")}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "one unattributable fails all",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i0"), pathErr()}, Partial: true},
+			n:    2,
+		},
+		{
+			name: "by op",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i0"), pathErr("i1_f3", "projectV2Item")}, Partial: true},
+			n:    2,
+			want: map[int]int{0: 1, 1: 1},
+			ok:   true,
+		},
+		{
+			name: "several for one op",
+			err:  &GraphQLErrors{Errors: []GraphQLError{pathErr("i1_f0"), pathErr("i1_f1"), pathErr("i1_f1")}, Partial: true},
+			n:    2,
+			want: map[int]int{1: 3},
+			ok:   true,
+		},
+		{
+			name: "wrapped",
+			err:  errWrap(&GraphQLErrors{Errors: []GraphQLError{pathErr("i0")}, Partial: true}),
+			n:    1,
+			want: map[int]int{0: 1},
+			ok:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := aliasErrors(tt.err, tt.n)
+			if ok != tt.ok {
+				t.Fatalf("aliasErrors() ok = %t, want %t", ok, tt.ok)
+			}
+
+			counts := map[int]int{}
+			for i, ge := range got {
+				counts[i] = len(ge.Errors)
+			}
+			if len(counts) == 0 {
+				counts = nil
+			}
+			if !reflect.DeepEqual(counts, tt.want) {
+				t.Errorf("aliasErrors() errors by op = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func errWrap(err error) error {
+	return errors.Join(errors.New("updating project items"), err)
+}
+
+// fakeProject answers batched mutations, recording the items of each request. Items named bad fail any
+// request they are in as a whole, those named missing fail only their own alias.
+type fakeProject struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (f *fakeProject) query(_ context.Context, _ string, params [][]string) (*string, error) {
+	var items []string
+	for _, p := range params {
+		name, value, _ := strings.Cut(p[1], "=")
+		if strings.HasSuffix(name, "_content") || strings.HasSuffix(name, "_item") {
+			items = append(items, value)
+		}
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, items)
+	f.mu.Unlock()
+
+	data := map[string]any{}
+	ge := &GraphQLErrors{Partial: true}
+	for i, item := range items {
+		alias := "i" + strconv.Itoa(i)
+		switch item {
+		case "bad":
+			return nil, errors.New("gh graphql failed: exit status 1: something went wrong")
+		case "missing":
+			data[alias] = nil
+			ge.Errors = append(ge.Errors, GraphQLError{Type: "NOT_FOUND", Message: "not found", Path: []any{alias}})
+		default:
+			data[alias] = map[string]any{"item": map[string]any{"id": "item-" + item}}
+		}
+	}
+
+	b, err := json.Marshal(map[string]any{"data": data})
+	if err != nil {
+		return nil, err
+	}
+	out := string(b)
+
+	if len(ge.Errors) > 0 {
+		return &out, ge
+	}
+	return &out, nil
+}
+
+func newFakeBatcher(size, mutations int) (*ItemBatcher, *fakeProject) {
+	f := &fakeProject{}
+	p := &Project{ProjectDetails: &ProjectDetails{ID: "PVT_1"}}
+	b := p.NewItemBatcher(size, mutations)
+	b.query = f.query
+
+	return b, f
+}
+
+func textFields(n int) []ProjectItemField {
+	fields := make([]ProjectItemField, n)
+	for i := range fields {
+		fields[i] = ProjectItemField{Name: "Text", FieldID: "F" + strconv.Itoa(i), Type: ItemValueTypeText, Value: "v"}
+	}
+
+	return fields
+}
+
+func TestItemBatcherAddItems(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		size      int
+		nodeIDs   []string
+		wantCalls [][]string
+		wantIDs   map[string]string
+		wantErrs  []string
+	}{
+		{
+			name:      "batches of size",
+			size:      2,
+			nodeIDs:   []string{"a", "b", "c", "d", "e"},
+			wantCalls: [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+			wantIDs:   map[string]string{"a": "item-a", "b": "item-b", "c": "item-c", "d": "item-d", "e": "item-e"},
+		},
+		{
+			name:      "duplicates added once",
+			size:      5,
+			nodeIDs:   []string{"a", "b", "a", "b", "c"},
+			wantCalls: [][]string{{"a", "b", "c"}},
+			wantIDs:   map[string]string{"a": "item-a", "b": "item-b", "c": "item-c"},
+		},
+		{
+			name:      "failed batch split",
+			size:      4,
+			nodeIDs:   []string{"a", "b", "bad", "c"},
+			wantCalls: [][]string{{"a", "b", "bad", "c"}, {"a", "b"}, {"bad", "c"}, {"bad"}, {"c"}},
+			wantIDs:   map[string]string{"a": "item-a", "b": "item-b", "c": "item-c"},
+			wantErrs:  []string{"bad"},
+		},
+		{
+			name:      "alias errors fail their item",
+			size:      3,
+			nodeIDs:   []string{"a", "missing", "c"},
+			wantCalls: [][]string{{"a", "missing", "c"}},
+			wantIDs:   map[string]string{"a": "item-a", "c": "item-c"},
+			wantErrs:  []string{"missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, f := newFakeBatcher(tt.size, 0)
+			ids, errs := b.AddItems(t.Context(), tt.nodeIDs)
+
+			if !reflect.DeepEqual(f.calls, tt.wantCalls) {
+				t.Errorf("AddItems() requests = %v, want %v", f.calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("AddItems() ids = %v, want %v", ids, tt.wantIDs)
+			}
+
+			var failed []string
+			for id := range errs {
+				failed = append(failed, id)
+			}
+			if !reflect.DeepEqual(failed, tt.wantErrs) {
+				t.Errorf("AddItems() failed = %v (%v), want %v", failed, errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestItemBatcherUpdateItems(t *testing.T) {
+	t.Parallel()
+
+	b, f := newFakeBatcher(10, 4)
+	errs := b.UpdateItems(t.Context(), []ItemUpdate{
+		{ItemID: "a", Fields: textFields(2)},
+		{ItemID: "b", Fields: textFields(2)},
+		{ItemID: "c", Fields: textFields(3)},
+		{ItemID: "none"},
+		{ItemID: "invalid", Fields: []ProjectItemField{{Name: "No ID", Type: ItemValueTypeText, Value: "v"}}},
+		{ItemID: "d", Fields: textFields(1)},
+	})
+
+	// a request stops short of Mutations calls, items without fields aren't sent and invalid ones fail
+	// without failing their batch
+	want := [][]string{{"a", "b"}, {"c", "d"}}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("UpdateItems() requests = %v, want %v", f.calls, want)
+	}
+	if len(errs) != 1 || errs["invalid"] == nil {
+		t.Errorf("UpdateItems() errors = %v, want only invalid", errs)
+	}
+}
+
+func TestItemBatcherCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	b, f := newFakeBatcher(4, 0)
+	_, errs := b.AddItems(ctx, []string{"a", "bad", "c"})
+
+	// splitting a failed batch won't help once cancelled
+	if len(f.calls) != 1 {
+		t.Errorf("AddItems() sent %d requests once cancelled, want 1", len(f.calls))
+	}
+	if len(errs) != 3 {
+		t.Errorf("AddItems() failed %d items, want 3", len(errs))
+	}
+}
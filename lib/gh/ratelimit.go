@@ -33,15 +33,23 @@ type RateLimits struct {
 	Other map[string]Rate
 }
 
-func GetRateLimit(ctx context.Context, token string) (*RateLimits, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/rate_limit", http.NoBody)
+// GetRateLimit returns the rate limits of the token, requested through the same transport as other
+// calls so it is traced, recorded and replayed.
+func GetRateLimit(ctx context.Context, t Token) (*RateLimits, error) {
+	token, err := t.AccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, APIURL()+"rate_limit", http.NoBody)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	resp, err := http.DefaultClient.Do(req)
+	// authorized with this token rather than through the pool so it reports the token's own limits
+	resp, err := unauthenticatedClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,15 +1,95 @@
 package gh
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/clog"
 	"github.com/katbyte/ghp-sync/lib/pointer"
+	"golang.org/x/oauth2"
 )
 
 type Token struct {
-	Token *string
+	Token  *string
+	Source oauth2.TokenSource // ie github app installation tokens, used instead of Token when set
+	Pool   *TokenPool         // spreads requests over several tokens, used instead of Token and Source when set
+}
+
+// NewToken returns a Token for a personal access token, an empty token makes unauthenticated requests
+func NewToken(token string) Token {
+	if token == "" {
+		return Token{}
+	}
+
+	return Token{Token: &token}
+}
+
+// AccessToken returns the current token, minting a new one from Source when it has expired
+func (t Token) AccessToken() (string, error) {
+	token, _, err := t.accessToken(ResourceCore)
+	return token, err
+}
+
+// accessToken returns the token to use for a rate limit resource, along with the pooled token it came
+// from when there is a pool
+func (t Token) accessToken(resource string) (string, *pooledToken, error) {
+	source := t.Source
+	var pt *pooledToken
+	if t.Pool != nil {
+		pt = t.Pool.pick(resource)
+		source = pt.source
+	}
+
+	if source != nil {
+		ot, err := source.Token()
+		if err != nil {
+			return "", nil, fmt.Errorf("getting github token: %w", err)
+		}
+		return ot.AccessToken, pt, nil
+	}
+
+	if t.Token == nil {
+		return "", nil, nil
+	}
+
+	return *t.Token, nil, nil
+}
+
+// hasToken returns true if requests will be authenticated
+func (t Token) hasToken() bool {
+	return t.Pool != nil || t.Source != nil || t.Token != nil
+}
+
+// authTransport returns a RoundTripper authenticating requests with the token
+func (t Token) authTransport() http.RoundTripper {
+	base := transport()
+
+	switch {
+	case t.Pool != nil:
+		return t.Pool.Transport(base)
+	case t.Source != nil:
+		return &oauth2.Transport{Source: t.Source, Base: base}
+	case t.Token != nil:
+		return &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: *t.Token}), Base: base}
+	}
+
+	return base
+}
+
+// httpClient returns an http client authenticating requests with the token and retrying them with the
+// retry policy
+func (t Token) httpClient() *http.Client {
+	return &http.Client{Transport: &retryTransport{pool: t.Pool, base: t.authTransport()}}
+}
+
+// unauthenticatedClient returns an http client retrying requests with the retry policy for requests that
+// set their own Authorization header
+func unauthenticatedClient() *http.Client {
+	return &http.Client{Transport: &retryTransport{base: transport()}}
 }
 
 type Repo struct {
@@ -18,7 +98,7 @@ type Repo struct {
 	Token
 }
 
-func NewRepo(repo, token string) (*Repo, error) {
+func NewRepo(repo string, token Token) (*Repo, error) {
 	parts := strings.Split(repo, "/")
 
 	if len(parts) != 2 {
@@ -28,20 +108,12 @@ func NewRepo(repo, token string) (*Repo, error) {
 	return pointer.To(NewRepoOwnerName(parts[0], parts[1], token)), nil
 }
 
-func NewRepoOwnerName(owner, name, token string) Repo {
-	r := Repo{
+func NewRepoOwnerName(owner, name string, token Token) Repo {
+	return Repo{
 		Owner: owner,
 		Name:  name,
-		Token: Token{
-			Token: nil,
-		},
+		Token: token,
 	}
-
-	if token != "" {
-		r.Token.Token = &token
-	}
-
-	return r
 }
 
 type PRApproval struct {
@@ -55,7 +127,7 @@ type PRApproval struct {
 	}
 }
 
-func (r Repo) PRReviewDecision(pr int) (*string, error) {
+func (r Repo) PRReviewDecision(ctx context.Context, pr int) (*string, error) {
 	q := `query=
         query($owner: String!, $repo: String!, $pr: Int!) {
             repository(name: $repo, owner: $owner) {
@@ -83,9 +155,41 @@ func (r Repo) PRReviewDecision(pr int) (*string, error) {
 	}
 
 	var approved PRApproval
-	if err := r.GraphQLQueryUnmarshal(q, p, &approved); err != nil {
+	if err := r.GraphQLQueryUnmarshal(ctx, q, p, &approved); err != nil {
 		return nil, err
 	}
 
 	return &approved.Data.Repository.PullRequest.ReviewDecision, nil
 }
+
+// ListOrgRepos returns all repositories in an org sorted by name.
+func (t Token) ListOrgRepos(ctx context.Context, org string) ([]*github.Repository, error) {
+	client := t.NewClient()
+
+	opts := &github.RepositoryListByOrgOptions{
+		Type: "all",
+		Sort: "full_name",
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	var allRepos []*github.Repository
+	for {
+		clog.Log.Debugf("Listing all repos for %s (Page %d)...", org, opts.Page)
+		repos, resp, err := client.Repositories.ListByOrg(ctx, org, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list repos for %s (Page %d): %w", org, opts.Page, err)
+		}
+
+		allRepos = append(allRepos, repos...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allRepos, nil
+}
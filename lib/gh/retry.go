@@ -0,0 +1,282 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy is how failed requests are retried, shared by the REST and GraphQL clients and the gh CLI.
+type RetryPolicy struct {
+	MaxAttempts  int           // including the first
+	MinWait      time.Duration // backoff before the first retry, doubling each retry
+	MaxWait      time.Duration // cap on a single backoff, rate limit resets can be longer
+	MaxTotalWait time.Duration // give up rather than wait longer than this in total for a request
+}
+
+// DefaultRetryPolicy waits out an hour long rate limit reset with some to spare
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  8,
+	MinWait:      time.Second,
+	MaxWait:      time.Minute,
+	MaxTotalWait: 70 * time.Minute,
+}
+
+var retryPolicy = DefaultRetryPolicy
+
+// SetRetryPolicy sets the retry policy for all requests
+func SetRetryPolicy(p RetryPolicy) {
+	retryPolicy = p
+}
+
+// GraphQL error types that decide whether a query is retried
+const (
+	GraphQLErrorRateLimited = "RATE_LIMITED"
+	GraphQLErrorNotFound    = "NOT_FOUND"
+	GraphQLErrorForbidden   = "FORBIDDEN"
+)
+
+// retryable describes a failed attempt that can be retried
+type retryable struct {
+	reason string
+	wait   time.Duration // the least time to wait, ie until a rate limit resets, otherwise backoff is used
+}
+
+// backoff returns the exponential backoff with jitter before retry n
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.MinWait << (n - 1)
+	if d > p.MaxWait || d <= 0 {
+		d = p.MaxWait
+	}
+
+	// somewhere between half and all of it so concurrent runs don't retry in lockstep
+	return d/2 + rand.N(d/2+1) //nolint:gosec // jitter doesn't need a secure random number
+}
+
+// do calls attempt until it succeeds or fails with an error that can't be retried, waiting between
+// attempts. attempt returns a non nil retryable along with its error when it can be retried.
+func (p RetryPolicy) do(ctx context.Context, desc string, attempt func(n int) (*retryable, error)) error {
+	var waited time.Duration
+	for n := 1; ; n++ {
+		r, err := attempt(n)
+		if r == nil {
+			return err
+		}
+
+		if n >= p.MaxAttempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", desc, n, err)
+		}
+
+		wait := max(p.backoff(n), r.wait)
+		if waited+wait > p.MaxTotalWait {
+			return fmt.Errorf("%s: %s and waiting %s more would exceed the max retry wait of %s: %w", desc, r.reason, wait.Round(time.Second), p.MaxTotalWait, err)
+		}
+
+		clog.Log.Warnf("%s: %s, retrying in %s (attempt %d of %d)", desc, r.reason, wait.Round(time.Millisecond), n+1, p.MaxAttempts)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		waited += wait
+	}
+}
+
+// rateLimitWait returns how long a rate limited response says to wait, Retry-After for secondary limits
+// or until X-Ratelimit-Reset once the budget is used up
+func rateLimitWait(h http.Header) (time.Duration, bool) {
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if h.Get("X-Ratelimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0))+time.Second, 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// classifyResponse returns how a response can be retried, nil if it succeeded or can't be retried.
+// 200 GraphQL responses are checked for RATE_LIMITED errors.
+func classifyResponse(resp *http.Response, pool *TokenPool) *retryable {
+	resource := requestResource(resp.Request)
+
+	rateLimited := func(reason string) *retryable {
+		// with several tokens switch to one with budget left rather than waiting, or wait for the first
+		// to reset
+		if pool != nil {
+			if pool.hasBudget(resource) {
+				return &retryable{reason: reason + ", switching tokens"}
+			}
+			return &retryable{reason: reason + " on all tokens", wait: time.Until(pool.nextReset(resource)) + time.Second}
+		}
+
+		if wait, ok := rateLimitWait(resp.Header); ok {
+			return &retryable{reason: reason, wait: wait}
+		}
+
+		// GitHub asks for at least a minute when a secondary limit doesn't say how long
+		return &retryable{reason: reason, wait: time.Minute}
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusOK && resource == ResourceGraphQL:
+		if graphQLErrorType(peekBody(resp)) == GraphQLErrorRateLimited {
+			return rateLimited("graphql rate limited")
+		}
+	case isRateLimitResponse(resp):
+		return rateLimited("rate limited")
+	case code >= http.StatusInternalServerError && code != http.StatusNotImplemented:
+		return &retryable{reason: "server error " + resp.Status}
+	}
+
+	return nil
+}
+
+// peekBody returns the start of a response body without consuming it
+func peekBody(resp *http.Response) []byte {
+	if resp.Body == nil {
+		return nil
+	}
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+
+	return b
+}
+
+// graphQLErrorType returns the type of the first error in a GraphQL response that has one
+func graphQLErrorType(body []byte) string {
+	// the body may be cut short, so look for the errors rather than fully decoding it
+	if !bytes.Contains(body, []byte(`"errors"`)) {
+		return ""
+	}
+
+	var r struct {
+		Errors []struct {
+			Type string `json:"type"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &r); err == nil {
+		for _, e := range r.Errors {
+			if e.Type != "" {
+				return e.Type
+			}
+		}
+		return ""
+	}
+
+	for _, t := range []string{GraphQLErrorRateLimited, GraphQLErrorNotFound, GraphQLErrorForbidden} {
+		if bytes.Contains(body, []byte(`"type":"`+t+`"`)) {
+			return t
+		}
+	}
+
+	return ""
+}
+
+func isRateLimitError(msg string) bool {
+	m := strings.ToLower(msg)
+	return strings.Contains(m, "rate limit") ||
+		strings.Contains(m, "api rate limit exceeded") ||
+		strings.Contains(m, "secondary rate limit") ||
+		strings.Contains(m, "abuse detection")
+}
+
+// retryTransport retries requests with the retry policy
+type retryTransport struct {
+	pool   *TokenPool
+	base   http.RoundTripper
+	policy *RetryPolicy // retryPolicy when nil
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := retryPolicy
+	if t.policy != nil {
+		policy = *t.policy
+	}
+
+	var resp *http.Response
+	err := policy.do(req.Context(), req.Method+" "+req.URL.Path, func(n int) (*retryable, error) {
+		r := req
+		if n > 1 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("%s %s can't be retried as its body can't be replayed", req.Method, req.URL.Path)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		var err error
+		start := time.Now()
+		resp, err = t.base.RoundTrip(r)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		logAPICall(r.Context(), requestResource(r), r.Method+" "+r.URL.Path, n, start, status, err)
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			return &retryable{reason: "request failed"}, err
+		}
+
+		rt := classifyResponse(resp, t.pool)
+		if rt == nil {
+			return nil, nil
+		}
+
+		// read the rest so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close() //nolint:errcheck,gosec // best-effort close of the response body
+
+		return rt, errors.New(resp.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// logAPICall logs the timing of a request attempt with the fields of the job and item it was made for,
+// status is 0 when there was no response. They are logged at INFO for json lines.
+func logAPICall(ctx context.Context, api, call string, attempt int, start time.Time, status int, err error) {
+	l := clog.From(ctx).WithFields(logrus.Fields{
+		"api":         api,
+		"call":        call,
+		"attempt":     attempt,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+	if status != 0 {
+		l = l.WithField("status", status)
+	}
+	if err != nil {
+		l = l.WithError(err)
+	}
+
+	l.Log(clog.EventLevel(), "github api call")
+}
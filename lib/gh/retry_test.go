@@ -0,0 +1,493 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetries retries without waiting long enough to slow the tests
+var fastRetries = RetryPolicy{
+	MaxAttempts:  4,
+	MinWait:      time.Millisecond,
+	MaxWait:      time.Millisecond,
+	MaxTotalWait: time.Second,
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		fails    int           // attempts that fail before one succeeds
+		wait     time.Duration // asked for by each failed attempt
+		want     int           // attempts made
+		wantErr  string
+		minTotal time.Duration // the least time the retries take
+	}{
+		{
+			name:   "succeeds first time",
+			policy: fastRetries,
+			want:   1,
+		},
+		{
+			name:   "retried until success",
+			policy: fastRetries,
+			fails:  2,
+			want:   3,
+		},
+		{
+			name:    "gives up after max attempts",
+			policy:  fastRetries,
+			fails:   10,
+			want:    4,
+			wantErr: "giving up after 4 attempts: failed",
+		},
+		{
+			name:     "waits as long as asked",
+			policy:   fastRetries,
+			fails:    2,
+			wait:     50 * time.Millisecond,
+			want:     3,
+			minTotal: 100 * time.Millisecond,
+		},
+		{
+			name:    "max total wait caps the waiting",
+			policy:  RetryPolicy{MaxAttempts: 10, MinWait: time.Millisecond, MaxWait: time.Millisecond, MaxTotalWait: 120 * time.Millisecond},
+			fails:   10,
+			wait:    50 * time.Millisecond,
+			want:    3,
+			wantErr: "exceed the max retry wait of 120ms: failed",
+		},
+		{
+			name:    "longer than max total wait isn't waited",
+			policy:  fastRetries,
+			fails:   1,
+			wait:    time.Hour,
+			want:    1,
+			wantErr: "waiting 1h0m0s more would exceed the max retry wait",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := 0
+			start := time.Now()
+			err := tt.policy.do(t.Context(), "test", func(n int) (*retryable, error) {
+				attempts++
+				if n != attempts {
+					t.Errorf("attempt numbered %d, want %d", n, attempts)
+				}
+				if attempts <= tt.fails {
+					return &retryable{reason: "test failure", wait: tt.wait}, errFailed
+				}
+				return nil, nil
+			})
+
+			if attempts != tt.want {
+				t.Errorf("do() made %d attempts, want %d", attempts, tt.want)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("do() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("do() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantErr != "" && !errors.Is(err, errFailed) {
+				t.Errorf("do() error = %v doesn't wrap the attempt's error", err)
+			}
+			if took := time.Since(start); took < tt.minTotal {
+				t.Errorf("do() took %s, want at least %s", took, tt.minTotal)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoNotRetryable(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("not found")
+	attempts := 0
+	err := fastRetries.do(t.Context(), "test", func(int) (*retryable, error) {
+		attempts++
+		return nil, errFailed
+	})
+
+	if attempts != 1 || !errors.Is(err, errFailed) {
+		t.Errorf("do() = %v after %d attempts, want the error after 1", err, attempts)
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	p := RetryPolicy{MaxAttempts: 3, MinWait: time.Hour, MaxWait: time.Hour, MaxTotalWait: 3 * time.Hour}
+	start := time.Now()
+	err := p.do(ctx, "test", func(int) (*retryable, error) {
+		return &retryable{reason: "test failure"}, errors.New("failed")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want context.Canceled", err)
+	}
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("do() waited %s after being cancelled", took)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(30 * time.Second).Unix()
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		min, max time.Duration
+		ok       bool
+	}{
+		{
+			name:   "retry after",
+			header: map[string]string{"Retry-After": "7"},
+			min:    7 * time.Second,
+			max:    7 * time.Second,
+			ok:     true,
+		},
+		{
+			name:   "retry after wins over reset",
+			header: map[string]string{"Retry-After": "7", "X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": strconv.FormatInt(reset, 10)},
+			min:    7 * time.Second,
+			max:    7 * time.Second,
+			ok:     true,
+		},
+		{
+			name:   "until reset",
+			header: map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": strconv.FormatInt(reset, 10)},
+			min:    28 * time.Second,
+			max:    32 * time.Second,
+			ok:     true,
+		},
+		{
+			name:   "reset passed",
+			header: map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+			ok:     true,
+		},
+		{
+			name:   "budget left",
+			header: map[string]string{"X-Ratelimit-Remaining": "10", "X-Ratelimit-Reset": strconv.FormatInt(reset, 10)},
+		},
+		{
+			name: "no headers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for k, v := range tt.header {
+				h.Set(k, v)
+			}
+
+			wait, ok := rateLimitWait(h)
+			if ok != tt.ok || wait < tt.min || wait > tt.max {
+				t.Errorf("rateLimitWait() = %s, %t, want %s to %s, %t", wait, ok, tt.min, tt.max, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClassifyResponse(t *testing.T) {
+	t.Parallel()
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		header  map[string]string
+		body    string
+		retried bool
+		minWait time.Duration
+	}{
+		{
+			name:   "ok",
+			path:   "/repos/o/r",
+			status: http.StatusOK,
+			body:   `{}`,
+		},
+		{
+			name:   "not found",
+			path:   "/repos/o/r",
+			status: http.StatusNotFound,
+		},
+		{
+			name:    "server error",
+			path:    "/repos/o/r",
+			status:  http.StatusBadGateway,
+			retried: true,
+		},
+		{
+			name:   "not implemented",
+			path:   "/repos/o/r",
+			status: http.StatusNotImplemented,
+		},
+		{
+			name:    "secondary limit retry after",
+			path:    "/repos/o/r",
+			status:  http.StatusForbidden,
+			header:  map[string]string{"Retry-After": "90"},
+			retried: true,
+			minWait: 90 * time.Second,
+		},
+		{
+			name:    "primary limit reset",
+			path:    "/repos/o/r",
+			status:  http.StatusForbidden,
+			header:  map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": reset},
+			retried: true,
+			minWait: 59 * time.Minute,
+		},
+		{
+			name:    "secondary limit message",
+			path:    "/repos/o/r",
+			status:  http.StatusForbidden,
+			body:    `{"message":"You have exceeded a secondary rate limit"}`,
+			retried: true,
+			minWait: time.Minute,
+		},
+		{
+			name:   "forbidden",
+			path:   "/repos/o/r",
+			status: http.StatusForbidden,
+			body:   `{"message":"Resource not accessible by integration"}`,
+		},
+		{
+			name:    "graphql rate limited",
+			path:    "/graphql",
+			status:  http.StatusOK,
+			body:    `{"data":null,"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`,
+			retried: true,
+			minWait: time.Minute,
+		},
+		{
+			name:   "graphql not found",
+			path:   "/graphql",
+			status: http.StatusOK,
+			body:   `{"data":null,"errors":[{"type":"NOT_FOUND","message":"Could not resolve"}]}`,
+		},
+		{
+			name:   "rest body with rate limited isn't graphql",
+			path:   "/repos/o/r",
+			status: http.StatusOK,
+			body:   `{"errors":[{"type":"RATE_LIMITED"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "https://api.github.com"+tt.path, nil)
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     strconv.Itoa(tt.status) + " " + http.StatusText(tt.status),
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+				Request:    req,
+			}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+
+			r := classifyResponse(resp, nil)
+			if (r != nil) != tt.retried {
+				t.Fatalf("classifyResponse() = %+v, want retried %t", r, tt.retried)
+			}
+			if r != nil && r.wait < tt.minWait {
+				t.Errorf("classifyResponse() wait = %s, want at least %s", r.wait, tt.minWait)
+			}
+
+			// the body is still there to be read
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || string(body) != tt.body {
+				t.Errorf("body after classifyResponse() = %q, %v, want %q", body, err, tt.body)
+			}
+		})
+	}
+}
+
+// retryServer answers the first failures requests with fail, recording the body of every request
+func retryServer(t *testing.T, failures int, fail func(w http.ResponseWriter)) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var bodies []string
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		bodies = append(bodies, string(b))
+
+		if int(n.Add(1)) <= failures {
+			fail(w)
+			return
+		}
+		_, _ = io.WriteString(w, `{"data":{"viewer":{"login":"octocat"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &bodies
+}
+
+func TestRetryTransport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		failures int
+		fail     func(w http.ResponseWriter)
+		want     int // requests made
+		wantErr  bool
+	}{
+		{
+			name:     "server error",
+			failures: 2,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			want: 3,
+		},
+		{
+			name:     "graphql rate limited",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				_, _ = io.WriteString(w, `{"data":null,"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`)
+			},
+			want: 2,
+		},
+		{
+			name:     "rate limit reset too far off",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("X-Ratelimit-Remaining", "0")
+				w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			},
+			want:    1,
+			wantErr: true,
+		},
+		{
+			name:     "not retryable",
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, bodies := retryServer(t, tt.failures, tt.fail)
+			client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, policy: &fastRetries}}
+
+			const query = `{"query":"{ viewer { login } }"}`
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+"/graphql", strings.NewReader(query))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if len(*bodies) != tt.want {
+				t.Fatalf("server got %d requests, want %d", len(*bodies), tt.want)
+			}
+			// each retry is sent the whole body again
+			for i, b := range *bodies {
+				if b != query {
+					t.Errorf("request %d body = %q, want %q", i+1, b, query)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportBodyNotReplayable(t *testing.T) {
+	t.Parallel()
+
+	srv, bodies := retryServer(t, 5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, policy: &fastRetries}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+"/graphql", io.NopCloser(strings.NewReader("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = nil
+
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if err == nil || !strings.Contains(err.Error(), "body can't be replayed") {
+		t.Errorf("Do() error = %v, want the body can't be replayed", err)
+	}
+	if len(*bodies) != 1 {
+		t.Errorf("server got %d requests, want 1", len(*bodies))
+	}
+}
+
+func TestRetryTransportCancelled(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := retryServer(t, 5, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	slow := RetryPolicy{MaxAttempts: 5, MinWait: time.Hour, MaxWait: time.Hour, MaxTotalWait: 5 * time.Hour}
+	client := &http.Client{Transport: &retryTransport{base: http.DefaultTransport, policy: &slow}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/repos/o/r", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+}
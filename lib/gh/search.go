@@ -0,0 +1,279 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/katbyte/ghp-sync/lib/pointer"
+	"github.com/shurcooL/githubv4"
+)
+
+// SearchResultLimit is the most results the search API returns for a query, however many match it
+const SearchResultLimit = 1000
+
+type searchPullRequestsQuery struct {
+	Search struct {
+		IssueCount int
+		Nodes      []struct {
+			PullRequest pullRequestNode `graphql:"... on PullRequest"`
+		}
+		PageInfo pageInfo
+	} `graphql:"search(query: $query, type: ISSUE, first: 40, after: $cursor)"`
+}
+
+// SearchPullRequestsGQL returns the PRs matching a GitHub search query across any number of repos,
+// `is:pr` is added to the query if it isn't already there. Only PRs in one of states are returned unless
+// the query has its own state qualifier.
+func (t Token) SearchPullRequestsGQL(ctx context.Context, search string, states, reviewers []string, limit int, progress func(int)) (*[]PullRequest, error) {
+	client, err := t.NewGraphQLClient()
+	if err != nil {
+		return nil, fmt.Errorf("instantiating GraphQL client: %w", err)
+	}
+
+	rev := make(map[string]struct{})
+	for _, reviewer := range reviewers {
+		rev[reviewer] = struct{}{}
+	}
+
+	// search can only narrow to a single state, any others are filtered out as they are returned
+	search = searchQueryFor(search, "is:pr")
+	wantStates := map[string]bool{}
+	if !hasSearchQualifier(search, stateQualifiers...) {
+		for _, s := range states {
+			wantStates[strings.ToUpper(s)] = true
+		}
+		if len(states) == 1 {
+			search += " is:" + strings.ToLower(states[0])
+		}
+	}
+
+	query := searchPullRequestsQuery{}
+	variables := map[string]any{
+		"query":  githubv4.String(search),
+		"cursor": (*githubv4.String)(nil),
+	}
+
+	allPRs := make([]PullRequest, 0)
+	for page := 0; ; page++ {
+		if err := allowItemErrors(graphQLQuery(ctx, client, &query, variables), "searching"); err != nil {
+			return nil, err
+		}
+		if page == 0 {
+			warnSearchLimit(ctx, search, query.Search.IssueCount, limit)
+		}
+
+		for _, n := range query.Search.Nodes {
+			if n.PullRequest.ID == "" {
+				continue // not a pr
+			}
+			if len(wantStates) > 0 && !wantStates[n.PullRequest.State] {
+				continue
+			}
+			allPRs = append(allPRs, n.PullRequest.flatten(rev))
+		}
+
+		if progress != nil {
+			progress(len(allPRs))
+		}
+
+		if !query.Search.PageInfo.HasNextPage || (limit > 0 && len(allPRs) >= limit) {
+			break
+		}
+		variables["cursor"] = githubv4.String(query.Search.PageInfo.EndCursor)
+	}
+
+	return &allPRs, nil
+}
+
+type issueNode struct {
+	ID        string
+	Number    int
+	Title     string
+	URL       string
+	State     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  *time.Time
+
+	AuthorAssociation string
+
+	Repository struct {
+		NameWithOwner string
+	}
+
+	Author struct {
+		Login string
+	}
+
+	Assignees struct {
+		Nodes []struct {
+			Login string
+		}
+	} `graphql:"assignees(first: 10)"`
+
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 100)"`
+
+	Milestone struct {
+		Title string
+	}
+
+	Comments struct {
+		TotalCount int
+	}
+
+	Parent struct {
+		Number     int
+		Repository struct {
+			NameWithOwner string
+		}
+	}
+
+	SubIssuesSummary struct {
+		Total            int
+		Completed        int
+		PercentCompleted int
+	}
+}
+
+type searchIssuesQuery struct {
+	Search struct {
+		IssueCount int
+		Nodes      []struct {
+			Issue issueNode `graphql:"... on Issue"`
+		}
+		PageInfo pageInfo
+	} `graphql:"search(query: $query, type: ISSUE, first: 50, after: $cursor)"`
+}
+
+// SearchIssuesGQL returns the issues matching a GitHub search query across any number of repos,
+// `is:issue` is added to the query if it isn't already there. The issues are returned in the REST
+// shape so they can be handled the same as those from GetAllIssues.
+func (t Token) SearchIssuesGQL(ctx context.Context, search string, limit int, progress func(int)) (*[]github.Issue, error) {
+	client, err := t.NewGraphQLClient()
+	if err != nil {
+		return nil, fmt.Errorf("instantiating GraphQL client: %w", err)
+	}
+
+	search = searchQueryFor(search, "is:issue")
+	query := searchIssuesQuery{}
+	variables := map[string]any{
+		"query":  githubv4.String(search),
+		"cursor": (*githubv4.String)(nil),
+	}
+
+	allIssues := make([]github.Issue, 0)
+	for page := 0; ; page++ {
+		if err := allowItemErrors(graphQLQuery(ctx, client, &query, variables), "searching"); err != nil {
+			return nil, err
+		}
+		if page == 0 {
+			warnSearchLimit(ctx, search, query.Search.IssueCount, limit)
+		}
+
+		for _, n := range query.Search.Nodes {
+			if n.Issue.ID == "" {
+				continue // not an issue
+			}
+			allIssues = append(allIssues, n.Issue.toGitHub())
+		}
+
+		if progress != nil {
+			progress(len(allIssues))
+		}
+
+		if !query.Search.PageInfo.HasNextPage || (limit > 0 && len(allIssues) >= limit) {
+			break
+		}
+		variables["cursor"] = githubv4.String(query.Search.PageInfo.EndCursor)
+	}
+
+	return &allIssues, nil
+}
+
+func (n issueNode) toGitHub() github.Issue {
+	i := github.Issue{
+		NodeID:    pointer.To(n.ID),
+		Number:    pointer.To(n.Number),
+		Title:     pointer.To(n.Title),
+		HTMLURL:   pointer.To(n.URL),
+		State:     pointer.To(strings.ToLower(n.State)),
+		CreatedAt: &github.Timestamp{Time: n.CreatedAt},
+		UpdatedAt: &github.Timestamp{Time: n.UpdatedAt},
+		User:      &github.User{Login: pointer.To(n.Author.Login)},
+		Comments:  pointer.To(n.Comments.TotalCount),
+
+		AuthorAssociation: pointer.To(n.AuthorAssociation),
+		Repository: &github.Repository{
+			FullName: pointer.To(n.Repository.NameWithOwner),
+		},
+	}
+
+	if n.Parent.Number != 0 {
+		i.ParentIssueURL = pointer.To(fmt.Sprintf(APIURL()+"repos/%s/issues/%d", n.Parent.Repository.NameWithOwner, n.Parent.Number))
+	}
+	if n.SubIssuesSummary.Total > 0 {
+		i.SubIssuesSummary = &github.SubIssuesSummary{
+			Total:            pointer.To(n.SubIssuesSummary.Total),
+			Completed:        pointer.To(n.SubIssuesSummary.Completed),
+			PercentCompleted: pointer.To(n.SubIssuesSummary.PercentCompleted),
+		}
+	}
+	if n.ClosedAt != nil {
+		i.ClosedAt = &github.Timestamp{Time: *n.ClosedAt}
+	}
+	if n.Milestone.Title != "" {
+		i.Milestone = &github.Milestone{Title: pointer.To(n.Milestone.Title)}
+	}
+	for _, a := range n.Assignees.Nodes {
+		i.Assignees = append(i.Assignees, &github.User{Login: pointer.To(a.Login)})
+	}
+	for _, l := range n.Labels.Nodes {
+		i.Labels = append(i.Labels, &github.Label{Name: pointer.To(l.Name)})
+	}
+
+	return i
+}
+
+// stateQualifiers are the search qualifiers that pick the state of PRs
+var stateQualifiers = []string{"is:open", "is:closed", "is:merged", "is:unmerged", "state:open", "state:closed"}
+
+// searchQueryFor adds the is: qualifier to a search query if it isn't already present
+func searchQueryFor(search, qualifier string) string {
+	if hasSearchQualifier(search, qualifier) {
+		return search
+	}
+
+	return search + " " + qualifier
+}
+
+// hasSearchQualifier returns true if the search query has any of the qualifiers as a whole term, so
+// `is:pr` isn't found in `is:pr-review` and negated qualifiers such as `-is:pr` don't count
+func hasSearchQualifier(search string, qualifiers ...string) bool {
+	for _, term := range strings.Fields(search) {
+		for _, q := range qualifiers {
+			if strings.EqualFold(term, q) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// warnSearchLimit warns when a search matches more results than the search API returns, as the rest
+// are silently left out
+func warnSearchLimit(ctx context.Context, search string, matched, limit int) {
+	if matched <= SearchResultLimit || (limit > 0 && limit <= SearchResultLimit) {
+		return
+	}
+
+	clog.From(ctx).Warnf("search %q matched %d results but only the first %d can be returned, narrow it ie with repo: or created: qualifiers", search, matched, SearchResultLimit)
+}
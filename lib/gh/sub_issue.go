@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// ListSubIssues returns the sub-issues of an issue.
+func (r Repo) ListSubIssues(ctx context.Context, number int) ([]github.Issue, error) {
+	client := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	var all []github.Issue
+	for {
+		clog.Log.Debugf("Listing sub-issues for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		subIssues, resp, err := client.SubIssue.ListByIssue(ctx, r.Owner, r.Name, int64(number), opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list sub-issues for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		for _, s := range subIssues {
+			if s != nil {
+				all = append(all, github.Issue(*s))
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return all, nil
+}
@@ -0,0 +1,80 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/clog"
+)
+
+// GetTeamMembers returns the logins of all members of an org team, including the members of any nested
+// child teams.
+func (t Token) GetTeamMembers(ctx context.Context, org, slug string) ([]string, error) {
+	client := t.NewClient()
+
+	seen := map[string]bool{}
+	var members []string
+
+	teams := []string{slug}
+	visited := map[string]bool{}
+	for len(teams) > 0 {
+		team := teams[0]
+		teams = teams[1:]
+		if visited[team] {
+			continue
+		}
+		visited[team] = true
+
+		opts := &github.TeamListTeamMembersOptions{
+			ListOptions: github.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		for {
+			clog.Log.Debugf("Listing members of team %s/%s (Page %d)...", org, team, opts.Page)
+			users, resp, err := client.Teams.ListTeamMembersBySlug(ctx, org, team, opts)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list members of team %s/%s (Page %d): %w", org, team, opts.Page, err)
+			}
+
+			for _, u := range users {
+				if login := u.GetLogin(); login != "" && !seen[login] {
+					seen[login] = true
+					members = append(members, login)
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+
+		// the members endpoint should already include child team members, walk the child teams
+		// as well so nested membership doesn't rely on that
+		listOpts := &github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		}
+		for {
+			clog.Log.Debugf("Listing child teams of %s/%s (Page %d)...", org, team, listOpts.Page)
+			children, resp, err := client.Teams.ListChildTeamsByParentSlug(ctx, org, team, listOpts)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list child teams of %s/%s (Page %d): %w", org, team, listOpts.Page, err)
+			}
+
+			for _, c := range children {
+				teams = append(teams, c.GetSlug())
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+	}
+
+	return members, nil
+}
@@ -0,0 +1,223 @@
+package gh
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+)
+
+// Who an issue/PR is currently waiting on.
+const (
+	CourtMaintainer = "maintainer"
+	CourtAuthor     = "author"
+)
+
+// TimelineOptions configure how timeline events are interpreted.
+type TimelineOptions struct {
+	WaitingLabels    []string // labels that put the ball in the author's court, ie waiting-response
+	BlockedMilestone string   // milestone that pauses the clock while set, ie Blocked
+}
+
+// TimelineStats summarises who a PR has been waiting on over its lifetime.
+type TimelineStats struct {
+	BallInCourt  string    // CourtMaintainer or CourtAuthor
+	WaitingSince time.Time // when the ball last moved to BallInCourt
+
+	MaintainerWaitingDays int // total days spent waiting on maintainers
+	AuthorWaitingDays     int // total days spent waiting on the author
+
+	LastMaintainerReview time.Time // zero if never reviewed by a maintainer
+	LastAuthorActivity   time.Time // creation if the author has done nothing since
+	LastCommunityComment time.Time // last comment or review by someone other than the author or a maintainer
+}
+
+// DaysInCourt returns the number of days the ball has been in the current court.
+func (s TimelineStats) DaysInCourt() int {
+	return days(time.Since(s.WaitingSince))
+}
+
+// DaysSinceMaintainerReview returns the days since the last maintainer review, -1 if never reviewed.
+func (s TimelineStats) DaysSinceMaintainerReview() int {
+	if s.LastMaintainerReview.IsZero() {
+		return -1
+	}
+
+	return days(time.Since(s.LastMaintainerReview))
+}
+
+// DaysSinceCommunityComment returns the days since the last community comment or review, -1 if there are none.
+func (s TimelineStats) DaysSinceCommunityComment() int {
+	if s.LastCommunityComment.IsZero() {
+		return -1
+	}
+
+	return days(time.Since(s.LastCommunityComment))
+}
+
+// DaysSinceAuthorActivity returns the days since the author last commented, pushed or responded.
+func (s TimelineStats) DaysSinceAuthorActivity() int {
+	return days(time.Since(s.LastAuthorActivity))
+}
+
+// AnalyzeTimeline walks the events of a PR from GetAllIssueEvents oldest to newest tracking who the PR
+// is waiting on (the ball-in-court): it starts with the maintainers, moves to the author when a
+// maintainer comments, requests changes or adds a waiting label, and back to the maintainers when the
+// author comments, pushes, marks it ready for review or a waiting label is removed. Comments and reviews
+// only count as a maintainer's when their author association is (see IsMaintainerAssociation), those by
+// the rest of the community are only tracked in LastCommunityComment. Only maintainers can label, push
+// to or otherwise change a PR that isn't theirs, and bots are ignored. Time stops at closedAt if it is set.
+func AnalyzeTimeline(author string, createdAt, closedAt time.Time, events []TimelineEvent, opts TimelineOptions) TimelineStats {
+	waitingLabels := map[string]bool{}
+	for _, l := range opts.WaitingLabels {
+		waitingLabels[strings.ToLower(l)] = true
+	}
+
+	sorted := append([]TimelineEvent{}, events...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return eventTime(sorted[a].Timeline).Before(eventTime(sorted[b].Timeline))
+	})
+
+	s := TimelineStats{
+		BallInCourt:        CourtMaintainer,
+		WaitingSince:       createdAt,
+		LastAuthorActivity: createdAt,
+	}
+
+	// accrue adds the time since the last event to whoever the ball was with, unless blocked
+	var maintainerWaiting, authorWaiting time.Duration
+	last, blocked := createdAt, false
+	accrue := func(at time.Time) {
+		if !blocked {
+			if s.BallInCourt == CourtMaintainer {
+				maintainerWaiting += at.Sub(last)
+			} else {
+				authorWaiting += at.Sub(last)
+			}
+		}
+		last = at
+	}
+	moveTo := func(court string, at time.Time) {
+		if court == s.BallInCourt {
+			return
+		}
+
+		accrue(at)
+		s.BallInCourt = court
+		s.WaitingSince = at
+	}
+
+	for _, e := range sorted {
+		at := eventTime(e.Timeline)
+		if !closedAt.IsZero() && at.After(closedAt) {
+			break
+		}
+
+		actor := e.GetActor().GetLogin()
+		if actor == "" {
+			actor = e.GetUser().GetLogin()
+		}
+		if strings.HasSuffix(actor, "[bot]") {
+			continue
+		}
+		byAuthor := actor == author
+
+		switch e.GetEvent() {
+		case "commented":
+			switch {
+			case byAuthor:
+				s.LastAuthorActivity = at
+				moveTo(CourtMaintainer, at)
+			case IsMaintainerAssociation(e.AuthorAssociation):
+				moveTo(CourtAuthor, at)
+			default:
+				s.LastCommunityComment = at
+			}
+
+		case "reviewed":
+			if byAuthor {
+				s.LastAuthorActivity = at
+				moveTo(CourtMaintainer, at)
+				continue
+			}
+			if !IsMaintainerAssociation(e.AuthorAssociation) {
+				s.LastCommunityComment = at
+				continue
+			}
+
+			s.LastMaintainerReview = at
+			if strings.EqualFold(e.GetState(), "approved") {
+				moveTo(CourtMaintainer, at) // waiting on a merge
+			} else {
+				moveTo(CourtAuthor, at)
+			}
+
+		case "committed":
+			// commit events have no actor, pushes to the pr are assumed to come from the author
+			s.LastAuthorActivity = at
+			moveTo(CourtMaintainer, at)
+
+		case "head_ref_force_pushed", "ready_for_review", "reopened":
+			if byAuthor {
+				s.LastAuthorActivity = at
+			}
+			moveTo(CourtMaintainer, at)
+
+		case "convert_to_draft":
+			moveTo(CourtAuthor, at)
+
+		case "labeled", "unlabeled":
+			if !waitingLabels[strings.ToLower(e.GetLabel().GetName())] {
+				continue
+			}
+			if e.GetEvent() == "labeled" {
+				moveTo(CourtAuthor, at)
+			} else {
+				moveTo(CourtMaintainer, at)
+			}
+
+		case "milestoned", "demilestoned":
+			if opts.BlockedMilestone == "" || !strings.EqualFold(e.GetMilestone().GetTitle(), opts.BlockedMilestone) {
+				continue
+			}
+
+			// pause the clock while blocked, then restart it with the maintainers
+			accrue(at)
+			blocked = e.GetEvent() == "milestoned"
+			if !blocked {
+				s.BallInCourt, s.WaitingSince = CourtMaintainer, at
+			}
+		}
+	}
+
+	end := time.Now()
+	if !closedAt.IsZero() {
+		end = closedAt
+	}
+	accrue(end)
+
+	s.MaintainerWaitingDays = days(maintainerWaiting)
+	s.AuthorWaitingDays = days(authorWaiting)
+
+	return s
+}
+
+// eventTime returns when an event happened, reviews only have a submitted at time and commits the dates
+// of the commit, where the committer's is when it was last rebased or amended. Zero if it has none.
+func eventTime(e github.Timeline) time.Time {
+	switch {
+	case e.CreatedAt != nil:
+		return e.CreatedAt.Time
+	case e.SubmittedAt != nil:
+		return e.SubmittedAt.Time
+	case e.Committer != nil && e.Committer.Date != nil:
+		return e.Committer.Date.Time
+	}
+
+	return e.GetAuthor().GetDate().Time
+}
+
+func days(d time.Duration) int {
+	return int(d / (time.Hour * 24))
+}
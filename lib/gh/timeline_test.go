@@ -0,0 +1,218 @@
+package gh
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v89/github"
+	"github.com/katbyte/ghp-sync/lib/pointer"
+)
+
+var created = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func day(n int) time.Time {
+	return created.AddDate(0, 0, n)
+}
+
+func commented(login, association string, at time.Time) TimelineEvent {
+	return TimelineEvent{
+		Timeline: github.Timeline{
+			Event:     pointer.To("commented"),
+			Actor:     &github.User{Login: pointer.To(login)},
+			CreatedAt: &github.Timestamp{Time: at},
+		},
+		AuthorAssociation: association,
+	}
+}
+
+func reviewed(login, association, state string, at time.Time) TimelineEvent {
+	return TimelineEvent{
+		Timeline: github.Timeline{
+			Event:       pointer.To("reviewed"),
+			User:        &github.User{Login: pointer.To(login)},
+			State:       pointer.To(state),
+			SubmittedAt: &github.Timestamp{Time: at},
+		},
+		AuthorAssociation: association,
+	}
+}
+
+func committed(author, committer *time.Time) TimelineEvent {
+	e := TimelineEvent{Timeline: github.Timeline{
+		Event: pointer.To("committed"),
+		SHA:   pointer.To("abc123"),
+	}}
+	if author != nil {
+		e.Author = &github.CommitAuthor{Name: pointer.To("Author"), Date: &github.Timestamp{Time: *author}}
+	}
+	if committer != nil {
+		e.Committer = &github.CommitAuthor{Name: pointer.To("Author"), Date: &github.Timestamp{Time: *committer}}
+	}
+
+	return e
+}
+
+func TestEventTime(t *testing.T) {
+	t.Parallel()
+
+	authored, rebased := day(3), day(4)
+
+	cases := []struct {
+		name  string
+		event TimelineEvent
+		want  time.Time
+	}{
+		{"created", commented("a", "MEMBER", day(1)), day(1)},
+		{"submitted", reviewed("a", "MEMBER", "approved", day(2)), day(2)},
+		{"committed", committed(&authored, &rebased), rebased},
+		{"committed without a committer", committed(&authored, nil), authored},
+		{"no date", committed(nil, nil), time.Time{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := eventTime(tc.event.Timeline); !got.Equal(tc.want) {
+				t.Errorf("eventTime() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTimelineEventJSON(t *testing.T) {
+	t.Parallel()
+
+	var e TimelineEvent
+	err := json.Unmarshal([]byte(`{"event":"commented","actor":{"login":"a"},"author_association":"COLLABORATOR","created_at":"2026-01-02T00:00:00Z"}`), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.GetEvent() != "commented" || e.GetActor().GetLogin() != "a" || !eventTime(e.Timeline).Equal(day(1)) {
+		t.Errorf("timeline fields not decoded: %+v", e.Timeline)
+	}
+	if e.AuthorAssociation != "COLLABORATOR" {
+		t.Errorf("AuthorAssociation = %q, want COLLABORATOR", e.AuthorAssociation)
+	}
+}
+
+func TestAnalyzeTimeline(t *testing.T) {
+	t.Parallel()
+
+	pushed := day(5)
+
+	cases := []struct {
+		name   string
+		events []TimelineEvent
+
+		court                string
+		waitingSince         time.Time
+		maintainerDays       int
+		authorDays           int
+		lastMaintainerReview time.Time
+		lastAuthorActivity   time.Time
+		lastCommunityComment time.Time
+	}{
+		{
+			name:               "no events",
+			court:              CourtMaintainer,
+			waitingSince:       created,
+			maintainerDays:     10,
+			lastAuthorActivity: created,
+		},
+		{
+			// out of order as the timeline api lists commits by when they were pushed
+			name: "author pushes after a maintainer comment",
+			events: []TimelineEvent{
+				committed(nil, &pushed),
+				commented("maintainer", "MEMBER", day(2)),
+			},
+			court:              CourtMaintainer,
+			waitingSince:       pushed,
+			maintainerDays:     7,
+			authorDays:         3,
+			lastAuthorActivity: pushed,
+		},
+		{
+			name: "maintainer requests changes",
+			events: []TimelineEvent{
+				reviewed("maintainer", "COLLABORATOR", "changes_requested", day(4)),
+			},
+			court:                CourtAuthor,
+			waitingSince:         day(4),
+			maintainerDays:       4,
+			authorDays:           6,
+			lastMaintainerReview: day(4),
+			lastAuthorActivity:   created,
+		},
+		{
+			name: "community comments and reviews don't move the ball",
+			events: []TimelineEvent{
+				commented("passerby", "CONTRIBUTOR", day(2)),
+				reviewed("passerby", "NONE", "changes_requested", day(3)),
+			},
+			court:                CourtMaintainer,
+			waitingSince:         created,
+			maintainerDays:       10,
+			lastAuthorActivity:   created,
+			lastCommunityComment: day(3),
+		},
+		{
+			name: "author replies to a maintainer",
+			events: []TimelineEvent{
+				commented("maintainer", "OWNER", day(1)),
+				commented("passerby", "FIRST_TIME_CONTRIBUTOR", day(2)),
+				commented("author", "CONTRIBUTOR", day(6)),
+			},
+			court:                CourtMaintainer,
+			waitingSince:         day(6),
+			maintainerDays:       5,
+			authorDays:           5,
+			lastAuthorActivity:   day(6),
+			lastCommunityComment: day(2),
+		},
+		{
+			name: "bots are ignored",
+			events: []TimelineEvent{
+				commented("ci[bot]", "NONE", day(1)),
+				reviewed("helper[bot]", "MEMBER", "changes_requested", day(2)),
+			},
+			court:              CourtMaintainer,
+			waitingSince:       created,
+			maintainerDays:     10,
+			lastAuthorActivity: created,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := AnalyzeTimeline("author", created, day(10), tc.events, TimelineOptions{})
+
+			if s.BallInCourt != tc.court {
+				t.Errorf("BallInCourt = %q, want %q", s.BallInCourt, tc.court)
+			}
+			if !s.WaitingSince.Equal(tc.waitingSince) {
+				t.Errorf("WaitingSince = %s, want %s", s.WaitingSince, tc.waitingSince)
+			}
+			if s.MaintainerWaitingDays != tc.maintainerDays {
+				t.Errorf("MaintainerWaitingDays = %d, want %d", s.MaintainerWaitingDays, tc.maintainerDays)
+			}
+			if s.AuthorWaitingDays != tc.authorDays {
+				t.Errorf("AuthorWaitingDays = %d, want %d", s.AuthorWaitingDays, tc.authorDays)
+			}
+			if !s.LastMaintainerReview.Equal(tc.lastMaintainerReview) {
+				t.Errorf("LastMaintainerReview = %s, want %s", s.LastMaintainerReview, tc.lastMaintainerReview)
+			}
+			if !s.LastAuthorActivity.Equal(tc.lastAuthorActivity) {
+				t.Errorf("LastAuthorActivity = %s, want %s", s.LastAuthorActivity, tc.lastAuthorActivity)
+			}
+			if !s.LastCommunityComment.Equal(tc.lastCommunityComment) {
+				t.Errorf("LastCommunityComment = %s, want %s", s.LastCommunityComment, tc.lastCommunityComment)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package gh
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"golang.org/x/oauth2"
+)
+
+// rate limit resources, tracked separately as each has its own budget
+const (
+	ResourceCore    = "core"
+	ResourceGraphQL = "graphql"
+	ResourceSearch  = "search"
+)
+
+// TokenPool spreads requests over several tokens, using the one with the most remaining budget for each
+// rate limit resource and moving on to the next when one is rate limited rather than waiting for it to
+// reset.
+type TokenPool struct {
+	mu     sync.Mutex
+	tokens []*pooledToken
+
+	rateLimits func(ctx context.Context, t Token) (*RateLimits, error) // GetRateLimit when nil
+}
+
+type pooledToken struct {
+	name    string // for logging, never the token itself
+	source  oauth2.TokenSource
+	budgets map[string]budget // by resource
+}
+
+// budget is a token's rate limit for a resource, a token without one hasn't been used yet
+type budget struct {
+	remaining int
+	reset     time.Time
+}
+
+// NewTokenPool returns a pool of the tokens, names identify them in logs and output
+func NewTokenPool(names []string, sources []oauth2.TokenSource) *TokenPool {
+	p := &TokenPool{}
+	for i, s := range sources {
+		p.tokens = append(p.tokens, &pooledToken{
+			name:    names[i],
+			source:  s,
+			budgets: map[string]budget{},
+		})
+	}
+
+	return p
+}
+
+// Tokens returns the pooled tokens individually along with their names
+func (p *TokenPool) Tokens() ([]string, []Token) {
+	names := make([]string, 0, len(p.tokens))
+	tokens := make([]Token, 0, len(p.tokens))
+	for _, t := range p.tokens {
+		names = append(names, t.name)
+		tokens = append(tokens, Token{Source: t.source})
+	}
+
+	return names, tokens
+}
+
+// available returns the remaining budget for a resource, unused tokens and those past their reset are
+// assumed to have their full budget
+func (b budget) available(now time.Time, used bool) int {
+	if !used || !now.Before(b.reset) {
+		return math.MaxInt
+	}
+
+	return b.remaining
+}
+
+// pick returns the token with the most remaining budget for a resource, or when all are exhausted the
+// one that resets first
+func (p *TokenPool) pick(resource string) *pooledToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *pooledToken
+	bestRemaining := 0
+	var earliest *pooledToken
+	for _, t := range p.tokens {
+		b, used := t.budgets[resource]
+		if r := b.available(now, used); r > bestRemaining {
+			best, bestRemaining = t, r
+		}
+		if earliest == nil || b.reset.Before(earliest.budgets[resource].reset) {
+			earliest = t
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	return earliest
+}
+
+// hasBudget returns true if any token has budget left for a resource
+func (p *TokenPool) hasBudget(resource string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range p.tokens {
+		b, used := t.budgets[resource]
+		if b.available(now, used) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextReset returns the earliest time a token's budget for the resource resets
+func (p *TokenPool) nextReset(resource string) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var next time.Time
+	for _, t := range p.tokens {
+		if r := t.budgets[resource].reset; next.IsZero() || r.Before(next) {
+			next = r
+		}
+	}
+
+	return next
+}
+
+// update records a token's budget from a response's rate limit headers
+func (p *TokenPool) update(t *pooledToken, resource string, h http.Header) {
+	if r := h.Get("X-Ratelimit-Resource"); r != "" {
+		resource = r
+	}
+
+	remaining, err := strconv.Atoi(h.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(h.Get("X-Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t.budgets[resource] = budget{remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// limited marks a token as out of budget for a resource until a time, ie for a secondary rate limit
+func (p *TokenPool) limited(t *pooledToken, resource string, until time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// keep a later reset when the token's budget is already used up
+	b := t.budgets[resource]
+	if b.remaining > 0 || until.After(b.reset) {
+		b.reset = until
+	}
+	b.remaining = 0
+	t.budgets[resource] = b
+
+	clog.Log.Warnf("%s is rate limited for %s until %s, switching tokens", t.name, resource, b.reset.Format(time.TimeOnly))
+}
+
+// refresh reads a token's budgets from the rate limit api, which doesn't count against them
+func (p *TokenPool) refresh(ctx context.Context, t *pooledToken) {
+	get := p.rateLimits
+	if get == nil {
+		get = GetRateLimit
+	}
+
+	rl, err := get(ctx, Token{Source: t.source})
+	if err != nil {
+		clog.Log.Debugf("unable to refresh the rate limits of %s: %s", t.name, err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for resource, r := range map[string]Rate{ResourceCore: rl.Core, ResourceGraphQL: rl.GraphQL, ResourceSearch: rl.Search} {
+		t.budgets[resource] = budget{remaining: r.Remaining, reset: time.Unix(int64(r.Reset), 0)}
+	}
+}
+
+// Transport returns a RoundTripper authenticating each request with the pool's best token for it
+func (p *TokenPool) Transport(base http.RoundTripper) http.RoundTripper {
+	return &poolTransport{pool: p, base: base}
+}
+
+type poolTransport struct {
+	pool *TokenPool
+	base http.RoundTripper
+}
+
+func (pt *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := requestResource(req)
+	t := pt.pool.pick(resource)
+
+	tok, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the request
+	req = req.Clone(req.Context())
+	tok.SetAuthHeader(req)
+
+	resp, err := pt.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	pt.pool.update(t, resource, resp.Header)
+	if isRateLimitResponse(resp) {
+		until := time.Now().Add(time.Minute)
+		if ra, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			until = time.Now().Add(time.Duration(ra) * time.Second)
+		}
+		pt.pool.limited(t, resource, until)
+	}
+
+	return resp, nil
+}
+
+// requestResource guesses the rate limit resource a request counts against from its path
+func requestResource(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ResourceCore
+	}
+
+	switch path := req.URL.Path; {
+	case strings.HasSuffix(path, "/graphql"):
+		return ResourceGraphQL
+	case strings.Contains(path, "/search/"):
+		return ResourceSearch
+	default:
+		return ResourceCore
+	}
+}
+
+// isRateLimitResponse returns true for primary (remaining 0) and secondary (Retry-After or the message)
+// rate limits, 403s are also returned for missing permissions
+func isRateLimitResponse(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("X-Ratelimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "" ||
+			isRateLimitError(string(peekBody(resp)))
+	}
+
+	return false
+}
@@ -0,0 +1,273 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func newTestPool(names ...string) *TokenPool {
+	sources := make([]oauth2.TokenSource, 0, len(names))
+	for _, n := range names {
+		sources = append(sources, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token-" + n}))
+	}
+
+	return NewTokenPool(names, sources)
+}
+
+// pooled returns a pool's token by name
+func pooled(t *testing.T, p *TokenPool, name string) *pooledToken {
+	t.Helper()
+
+	for _, pt := range p.tokens {
+		if pt.name == name {
+			return pt
+		}
+	}
+
+	t.Fatalf("no token %s in the pool", name)
+	return nil
+}
+
+func TestTokenPoolPick(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		budgets  map[string]map[string]budget // token -> resource -> budget
+		resource string
+		want     string
+	}{
+		{
+			name: "most remaining",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 100, reset: later}},
+				"b": {ResourceCore: {remaining: 500, reset: later}},
+				"c": {ResourceCore: {remaining: 200, reset: later}},
+			},
+			resource: ResourceCore,
+			want:     "b",
+		},
+		{
+			name: "unused has its full budget",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 4000, reset: later}},
+				"b": {},
+				"c": {ResourceCore: {remaining: 10, reset: later}},
+			},
+			resource: ResourceCore,
+			want:     "b",
+		},
+		{
+			name: "past its reset has its full budget",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 4000, reset: later}},
+				"b": {ResourceCore: {remaining: 4000, reset: later}},
+				"c": {ResourceCore: {remaining: 0, reset: now.Add(-time.Second)}},
+			},
+			resource: ResourceCore,
+			want:     "c",
+		},
+		{
+			name: "exhausted skipped",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 0, reset: later}},
+				"b": {ResourceCore: {remaining: 0, reset: later}},
+				"c": {ResourceCore: {remaining: 1, reset: later}},
+			},
+			resource: ResourceCore,
+			want:     "c",
+		},
+		{
+			name: "all exhausted picks the first to reset",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 0, reset: now.Add(2 * time.Hour)}},
+				"b": {ResourceCore: {remaining: 0, reset: now.Add(30 * time.Minute)}},
+				"c": {ResourceCore: {remaining: 0, reset: later}},
+			},
+			resource: ResourceCore,
+			want:     "b",
+		},
+		{
+			name: "resources have their own budgets",
+			budgets: map[string]map[string]budget{
+				"a": {ResourceCore: {remaining: 5000, reset: later}, ResourceGraphQL: {remaining: 0, reset: later}},
+				"b": {ResourceCore: {remaining: 0, reset: later}, ResourceGraphQL: {remaining: 10, reset: later}},
+				"c": {ResourceCore: {remaining: 10, reset: later}, ResourceGraphQL: {remaining: 0, reset: later}},
+			},
+			resource: ResourceGraphQL,
+			want:     "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newTestPool("a", "b", "c")
+			for name, budgets := range tt.budgets {
+				for resource, b := range budgets {
+					pooled(t, p, name).budgets[resource] = b
+				}
+			}
+
+			if got := p.pick(tt.resource); got.name != tt.want {
+				t.Errorf("pick(%s) = %s, want %s", tt.resource, got.name, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenPoolAllExhausted(t *testing.T) {
+	t.Parallel()
+
+	p := newTestPool("a", "b")
+	first := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	pooled(t, p, "a").budgets[ResourceSearch] = budget{remaining: 0, reset: first.Add(time.Minute)}
+	pooled(t, p, "b").budgets[ResourceSearch] = budget{remaining: 0, reset: first}
+
+	if p.hasBudget(ResourceSearch) {
+		t.Errorf("hasBudget() = true with all tokens exhausted")
+	}
+	if got := p.nextReset(ResourceSearch); !got.Equal(first) {
+		t.Errorf("nextReset() = %s, want %s", got, first)
+	}
+	if !p.hasBudget(ResourceCore) {
+		t.Errorf("hasBudget() = false for another resource")
+	}
+}
+
+func TestTokenPoolLimited(t *testing.T) {
+	t.Parallel()
+
+	p := newTestPool("a", "b")
+	a, b := pooled(t, p, "a"), pooled(t, p, "b")
+	a.budgets[ResourceCore] = budget{remaining: 5000, reset: time.Now().Add(time.Hour)}
+	b.budgets[ResourceCore] = budget{remaining: 100, reset: time.Now().Add(time.Hour)}
+
+	// a secondary limit skips the token with the most budget until it is over
+	p.limited(a, ResourceCore, time.Now().Add(time.Minute))
+	if got := p.pick(ResourceCore); got != b {
+		t.Errorf("pick() = %s after a was limited, want b", got.name)
+	}
+
+	// a shorter limit doesn't bring forward a reset already waited for
+	reset := time.Now().Add(45 * time.Minute)
+	b.budgets[ResourceCore] = budget{remaining: 0, reset: reset}
+	p.limited(b, ResourceCore, time.Now().Add(time.Minute))
+	if got := b.budgets[ResourceCore].reset; !got.Equal(reset) {
+		t.Errorf("reset after limited() = %s, want %s", got, reset)
+	}
+
+	// until the limit is over
+	a.budgets[ResourceCore] = budget{remaining: 0, reset: time.Now().Add(-time.Second)}
+	if got := p.pick(ResourceCore); got != a {
+		t.Errorf("pick() = %s once a's limit was over, want a", got.name)
+	}
+}
+
+func TestTokenPoolRefresh(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	p := newTestPool("a", "b")
+	p.rateLimits = func(_ context.Context, tok Token) (*RateLimits, error) {
+		token, err := tok.AccessToken()
+		if err != nil {
+			return nil, err
+		}
+
+		switch token {
+		case "token-a":
+			r := Rate{Remaining: 10, Reset: int(reset.Unix())}
+			return &RateLimits{Core: r, GraphQL: Rate{Remaining: 4000, Reset: int(reset.Unix())}, Search: r}, nil
+		default:
+			return nil, errors.New("rate_limit request failed: 500 Internal Server Error")
+		}
+	}
+
+	a, b := pooled(t, p, "a"), pooled(t, p, "b")
+	before := budget{remaining: 0, reset: reset}
+	b.budgets[ResourceGraphQL] = before
+
+	p.refresh(t.Context(), a)
+	want := map[string]budget{
+		ResourceCore:    {remaining: 10, reset: reset},
+		ResourceGraphQL: {remaining: 4000, reset: reset},
+		ResourceSearch:  {remaining: 10, reset: reset},
+	}
+	for resource, w := range want {
+		if got := a.budgets[resource]; got.remaining != w.remaining || !got.reset.Equal(w.reset) {
+			t.Errorf("budget for %s after refresh() = %+v, want %+v", resource, got, w)
+		}
+	}
+
+	// a failed refresh keeps what is known
+	p.refresh(t.Context(), b)
+	if got := b.budgets[ResourceGraphQL]; got != before {
+		t.Errorf("budget after a failed refresh() = %+v, want %+v", got, before)
+	}
+	if got := p.pick(ResourceGraphQL); got != a {
+		t.Errorf("pick() = %s after refresh(), want a", got.name)
+	}
+}
+
+func TestTokenPoolTransport(t *testing.T) {
+	t.Parallel()
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	// token-a is secondary rate limited, token-b has a little budget left
+	var mu sync.Mutex
+	seen := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("Authorization")]++
+		mu.Unlock()
+
+		w.Header().Set("X-Ratelimit-Reset", reset)
+		switch r.Header.Get("Authorization") {
+		case "Bearer token-a":
+			w.Header().Set("X-Ratelimit-Remaining", "4000")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Header().Set("X-Ratelimit-Remaining", "10")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	p := newTestPool("a", "b")
+	pooled(t, p, "b").budgets[ResourceCore] = budget{remaining: 20, reset: time.Now().Add(time.Hour)}
+	client := &http.Client{Transport: p.Transport(http.DefaultTransport)}
+
+	for range 3 {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/repos/o/r", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// a is used first as it is unused, then skipped once it is limited
+	if seen["Bearer token-a"] != 1 || seen["Bearer token-b"] != 2 {
+		t.Errorf("requests by token = %v, want 1 with a then 2 with b", seen)
+	}
+	if got := pooled(t, p, "b").budgets[ResourceCore].remaining; got != 10 {
+		t.Errorf("b's remaining budget = %d, want 10 from the response", got)
+	}
+}
@@ -0,0 +1,103 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// IssueRef identifies an issue related to another.
+type IssueRef struct {
+	NodeID string
+	Repo   string // owner/name
+	Number int
+	URL    string
+	Closed bool
+}
+
+// TrackedIssues are an issue's task list relationships, which predate sub-issues and are still used by
+// older epics: the issues whose task lists track it and those tracked by its own task list.
+type TrackedIssues struct {
+	TrackedBy []IssueRef
+	Tracks    []IssueRef
+}
+
+type issueRefNode struct {
+	ID         string
+	Number     int
+	URL        string
+	Closed     bool
+	Repository struct {
+		NameWithOwner string
+	}
+}
+
+func (n issueRefNode) ref() IssueRef {
+	return IssueRef{
+		NodeID: n.ID,
+		Repo:   n.Repository.NameWithOwner,
+		Number: n.Number,
+		URL:    n.URL,
+		Closed: n.Closed,
+	}
+}
+
+type trackedIssuesQuery struct {
+	Nodes []struct {
+		Issue struct {
+			ID              string
+			TrackedInIssues struct {
+				Nodes []issueRefNode
+			} `graphql:"trackedInIssues(first: 10)"`
+			TrackedIssues struct {
+				Nodes []issueRefNode
+			} `graphql:"trackedIssues(first: 100)"`
+		} `graphql:"... on Issue"`
+	} `graphql:"nodes(ids: $ids)"`
+}
+
+// trackedIssuesBatch is how many issues are looked up per query
+const trackedIssuesBatch = 50
+
+// GetTrackedIssues returns the task list relationships of issues by node ID, issues without any are
+// omitted.
+func (t Token) GetTrackedIssues(ctx context.Context, nodeIDs []string) (map[string]TrackedIssues, error) {
+	client, err := t.NewGraphQLClient()
+	if err != nil {
+		return nil, fmt.Errorf("instantiating GraphQL client: %w", err)
+	}
+
+	tracked := map[string]TrackedIssues{}
+	for start := 0; start < len(nodeIDs); start += trackedIssuesBatch {
+		ids := make([]githubv4.ID, 0, trackedIssuesBatch)
+		for _, id := range nodeIDs[start:min(start+trackedIssuesBatch, len(nodeIDs))] {
+			ids = append(ids, githubv4.ID(id))
+		}
+
+		var query trackedIssuesQuery
+		if err := allowItemErrors(graphQLQuery(ctx, client, &query, map[string]any{"ids": ids}), "getting tracked issues"); err != nil {
+			return nil, err
+		}
+
+		for _, n := range query.Nodes {
+			// not an issue, or one that errored
+			if n.Issue.ID == "" {
+				continue
+			}
+
+			var ti TrackedIssues
+			for _, r := range n.Issue.TrackedInIssues.Nodes {
+				ti.TrackedBy = append(ti.TrackedBy, r.ref())
+			}
+			for _, r := range n.Issue.TrackedIssues.Nodes {
+				ti.Tracks = append(ti.Tracks, r.ref())
+			}
+			if len(ti.TrackedBy) > 0 || len(ti.Tracks) > 0 {
+				tracked[n.Issue.ID] = ti
+			}
+		}
+	}
+
+	return tracked, nil
+}
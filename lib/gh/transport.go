@@ -0,0 +1,52 @@
+package gh
+
+import (
+	"net/http"
+
+	"github.com/katbyte/ghp-sync/lib/chttp"
+	"github.com/katbyte/ghp-sync/lib/clog"
+	"github.com/sirupsen/logrus"
+)
+
+// responseCache caches REST responses for conditional requests, nil when disabled
+var responseCache *chttp.Cache
+
+// SetCache enables caching REST responses, a nil cache disables it
+func SetCache(c *chttp.Cache) {
+	responseCache = c
+}
+
+// CacheStats returns the response cache counts, false when caching is disabled
+func CacheStats() (chttp.CacheStats, bool) {
+	if responseCache == nil {
+		return chttp.CacheStats{}, false
+	}
+
+	return responseCache.Stats(), true
+}
+
+// fixtures records or replays requests, nil when neither
+var fixtures *chttp.Fixtures
+
+// SetFixtures records requests to, or replays them from, fixtures, including gh CLI calls. nil sends them
+// as usual.
+func SetFixtures(f *chttp.Fixtures) {
+	fixtures = f
+}
+
+// transport returns the RoundTripper api requests are sent with once authenticated. The cache is above the
+// trace logging so the traces show what is actually sent, ie conditional requests and their 304s.
+func transport() http.RoundTripper {
+	var rt http.RoundTripper = http.DefaultTransport
+	if fixtures != nil {
+		rt = fixtures.Transport(rt)
+	}
+	if clog.Log.IsLevelEnabled(logrus.TraceLevel) {
+		rt = chttp.NewTransport("GitHub", rt)
+	}
+	if responseCache != nil {
+		rt = responseCache.Transport(rt)
+	}
+
+	return rt
+}
@@ -14,8 +14,12 @@ func ParseGitHubURL(gitHubURL string) (owner, name, typ string, number int, err
 		return "", "", "", 0, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	// Check if the URL is from GitHub
-	if !strings.Contains(parsedURL.Host, "github.com") {
+	// Check if the URL is from GitHub, or the GitHub Enterprise Server host
+	if IsEnterprise() {
+		if !strings.EqualFold(parsedURL.Host, Host()) {
+			return "", "", "", 0, fmt.Errorf("URL is not a %s URL", Host())
+		}
+	} else if !strings.Contains(parsedURL.Host, "github.com") {
 		return "", "", "", 0, errors.New("URL is not a GitHub URL")
 	}
 
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	c "github.com/gookit/color"
 	"github.com/katbyte/ghp-sync/cli"
@@ -18,7 +21,16 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := cmd.Execute(); err != nil {
+	// the first ctrl-c cancels the run so it can report its progress, a second exits straight away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = cmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		clog.Log.Errorf("%s", c.Sprintf("<red>%s:</> %v", cmdName, err))
 
 		os.Exit(1)